@@ -1,9 +1,13 @@
 package config
 
 import (
+	"net/netip"
+	"time"
+
 	"github.com/Nha1410/go-zero-template/common/auth"
 	redisCache "github.com/Nha1410/go-zero-template/common/cache"
 	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/common/lifecycle"
 	"github.com/zeromicro/go-zero/rest"
 	"github.com/zeromicro/go-zero/zrpc"
 )
@@ -22,6 +26,59 @@ type Config struct {
 		Password string
 		VHost    string
 	}
-	Zitadel auth.ZitadelConfig
-	UserRpc zrpc.RpcClientConf
+	Zitadel   auth.ZitadelConfig
+	UserRpc   zrpc.RpcClientConf
+	RateLimit RateLimitConfig
+	Errors    struct {
+		// ProblemJSON renders errors as RFC 7807 application/problem+json
+		ProblemJSON bool
+	}
+	Conditional struct {
+		// RequireIfMatch rejects writes without If-Match with 428 Precondition Required
+		RequireIfMatch bool
+	}
+	Idempotency struct {
+		Enabled bool
+		// TTL is how long a stored response can be replayed
+		TTL time.Duration
+		// LockTTL bounds how long a concurrent duplicate waits for the first request
+		LockTTL time.Duration
+	}
+	// Startup bounds how long dependencies are retried at boot
+	Startup lifecycle.StartupConfig
+	// Dependencies declares which external systems must be up to start
+	Dependencies struct {
+		Database lifecycle.Requirement
+		Redis    lifecycle.Requirement
+		RabbitMQ lifecycle.Requirement
+		UserRpc  lifecycle.Requirement
+	}
+	HealthCheck struct {
+		// Timeout bounds each dependency check
+		Timeout time.Duration
+		// CacheTTL is how long a readiness report is reused
+		CacheTTL time.Duration
+	}
+}
+
+// RateLimitConfig configures the gateway rate limiter
+type RateLimitConfig struct {
+	Enabled   bool
+	Algorithm string
+	// Client limits each client IP across the protected routes. It applies
+	// before authentication, so unauthenticated traffic is limited too
+	Client RateLimitRule
+	// Default applies to routes without an entry in Routes
+	Default RateLimitRule
+	// Routes maps "METHOD /path" (as registered, e.g. "DELETE /api/v1/users/:id") to its rule
+	Routes map[string]RateLimitRule
+	// TrustedProxies lists the proxies allowed to set X-Forwarded-For. Without
+	// any, clients are identified by the address of the connection
+	TrustedProxies []netip.Prefix
+}
+
+// RateLimitRule allows Limit requests per Window
+type RateLimitRule struct {
+	Limit  int
+	Window time.Duration
 }
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"net/netip"
+	"strconv"
+	"strings"
 	"time"
 
 	envConfig "github.com/Nha1410/go-zero-template/common/config"
+	"github.com/Nha1410/go-zero-template/common/lifecycle"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/service"
 	"github.com/zeromicro/go-zero/rest"
@@ -59,12 +63,122 @@ func LoadFromEnv() Config {
 	if len(scopes) > 0 {
 		c.Zitadel.Scopes = scopes
 	}
+	c.Zitadel.ValidationMode = envConfig.GetString("ZITADEL_VALIDATION_MODE", "userinfo")
+	c.Zitadel.Audience = envConfig.GetStringSlice("ZITADEL_AUDIENCE", nil)
+	c.Zitadel.ProjectID = envConfig.GetString("ZITADEL_PROJECT_ID", "")
+	c.Zitadel.ClockSkew = time.Duration(envConfig.GetInt("ZITADEL_CLOCK_SKEW", 30)) * time.Second
+	c.Zitadel.JWKSRefreshInterval = time.Duration(envConfig.GetInt("ZITADEL_JWKS_REFRESH_INTERVAL", 3600)) * time.Second
 
-	rpcHost := envConfig.GetString("USER_RPC_HOST", "localhost:9000")
-	c.UserRpc.Etcd.Hosts = []string{rpcHost}
-	c.UserRpc.Etcd.Key = envConfig.GetString("USER_RPC_KEY", "user.rpc")
+	// Prefer etcd discovery when configured, otherwise dial the service directly
+	etcdHosts := envConfig.GetStringSlice("USER_RPC_ETCD_HOSTS", nil)
+	if len(etcdHosts) > 0 {
+		c.UserRpc.Etcd.Hosts = etcdHosts
+		c.UserRpc.Etcd.Key = envConfig.GetString("USER_RPC_KEY", "user.rpc")
+	} else {
+		c.UserRpc.Endpoints = []string{envConfig.GetString("USER_RPC_HOST", "localhost:9000")}
+	}
 	c.UserRpc.Timeout = int64(envConfig.GetInt("USER_RPC_TIMEOUT", 5000))
 	c.UserRpc.NonBlock = envConfig.GetBool("USER_RPC_NON_BLOCK", true)
 
+	c.RateLimit.Enabled = envConfig.GetBool("RATE_LIMIT_ENABLED", true)
+	c.RateLimit.Algorithm = envConfig.GetString("RATE_LIMIT_ALGORITHM", "sliding_window")
+	c.RateLimit.Client.Limit = envConfig.GetInt("RATE_LIMIT_CLIENT_LIMIT", 300)
+	c.RateLimit.Client.Window = time.Duration(envConfig.GetInt("RATE_LIMIT_CLIENT_WINDOW", 60)) * time.Second
+	c.RateLimit.Default.Limit = envConfig.GetInt("RATE_LIMIT_LIMIT", 100)
+	c.RateLimit.Default.Window = time.Duration(envConfig.GetInt("RATE_LIMIT_WINDOW", 60)) * time.Second
+	c.RateLimit.Routes = parseRateLimitRoutes(envConfig.GetString("RATE_LIMIT_ROUTES", ""))
+	c.RateLimit.TrustedProxies = parseTrustedProxies(envConfig.GetStringSlice("RATE_LIMIT_TRUSTED_PROXIES", nil))
+
+	c.Errors.ProblemJSON = envConfig.GetBool("ERROR_PROBLEM_JSON", false)
+
+	c.Conditional.RequireIfMatch = envConfig.GetBool("REQUIRE_IF_MATCH", false)
+
+	c.Idempotency.Enabled = envConfig.GetBool("IDEMPOTENCY_ENABLED", true)
+	c.Idempotency.TTL = time.Duration(envConfig.GetInt("IDEMPOTENCY_TTL", 86400)) * time.Second
+	c.Idempotency.LockTTL = time.Duration(envConfig.GetInt("IDEMPOTENCY_LOCK_TTL", 10)) * time.Second
+
+	c.HealthCheck.Timeout = time.Duration(envConfig.GetInt("HEALTH_CHECK_TIMEOUT", 2)) * time.Second
+	c.HealthCheck.CacheTTL = time.Duration(envConfig.GetInt("HEALTH_CHECK_CACHE_TTL", 1)) * time.Second
+
+	c.Startup.Timeout = time.Duration(envConfig.GetInt("STARTUP_TIMEOUT", 60)) * time.Second
+	c.Startup.MaxBackoff = time.Duration(envConfig.GetInt("STARTUP_MAX_BACKOFF", 10)) * time.Second
+	// The gateway only talks to the user service and Redis by default
+	c.Dependencies.Database = getRequirement("API_DATABASE_REQUIREMENT", lifecycle.Disabled)
+	c.Dependencies.Redis = getRequirement("API_REDIS_REQUIREMENT", lifecycle.Optional)
+	c.Dependencies.RabbitMQ = getRequirement("API_RABBITMQ_REQUIREMENT", lifecycle.Disabled)
+	c.Dependencies.UserRpc = getRequirement("API_USER_RPC_REQUIREMENT", lifecycle.Required)
+
+	c.Shutdown.WaitTime = time.Duration(envConfig.GetInt("SHUTDOWN_TIMEOUT", 30)) * time.Second
+
 	return c
 }
+
+// getRequirement reads a dependency requirement, falling back to defaultValue
+// when the variable is unset or invalid
+func getRequirement(key string, defaultValue lifecycle.Requirement) lifecycle.Requirement {
+	value := envConfig.GetString(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	req, err := lifecycle.ParseRequirement(value)
+	if err != nil {
+		logx.Errorf("Ignoring %s: %v", key, err)
+		return defaultValue
+	}
+	return req
+}
+
+// parseRateLimitRoutes parses "METHOD /path=limit/window_seconds" entries separated by ";"
+func parseRateLimitRoutes(value string) map[string]RateLimitRule {
+	routes := make(map[string]RateLimitRule)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, rule, ok := strings.Cut(entry, "=")
+		limit, window, ok2 := strings.Cut(rule, "/")
+		limitValue, err := strconv.Atoi(strings.TrimSpace(limit))
+		windowValue, err2 := strconv.Atoi(strings.TrimSpace(window))
+		if !ok || !ok2 || err != nil || err2 != nil || limitValue <= 0 || windowValue <= 0 {
+			logx.Errorf("Ignoring invalid RATE_LIMIT_ROUTES entry: %q", entry)
+			continue
+		}
+
+		routes[strings.Join(strings.Fields(route), " ")] = RateLimitRule{
+			Limit:  limitValue,
+			Window: time.Duration(windowValue) * time.Second,
+		}
+	}
+	return routes
+}
+
+// parseTrustedProxies parses CIDR ranges or single addresses
+func parseTrustedProxies(values []string) []netip.Prefix {
+	var proxies []netip.Prefix
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				logx.Errorf("Ignoring invalid RATE_LIMIT_TRUSTED_PROXIES entry: %q", value)
+				continue
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			logx.Errorf("Ignoring invalid RATE_LIMIT_TRUSTED_PROXIES entry: %q", value)
+			continue
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+
+	"github.com/Nha1410/go-zero-template/api/internal/config"
+	"github.com/Nha1410/go-zero-template/api/internal/middleware"
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// ErrorHandler renders errors passed to httpx.ErrorCtx as the common/errors
+// envelope, or as RFC 7807 problem details when enabled or requested with
+// Accept. Server errors are logged and returned without details so internals
+// never reach the client
+func ErrorHandler(c config.Config) func(context.Context, error) (int, any) {
+	return func(ctx context.Context, err error) (int, any) {
+		var e *errors.Error
+		if !stderrors.As(err, &e) {
+			logx.WithContext(ctx).Errorf("Unhandled error: %v", err)
+			e = errors.ErrInternalError
+		} else if e.StatusCode >= http.StatusInternalServerError && e.Details != "" {
+			logx.WithContext(ctx).Errorf("%s: %s", e.Code, e.Details)
+			e = errors.NewError(e.StatusCode, e.Code, e.Message)
+		}
+
+		requestID := middleware.RequestIDFromContext(ctx)
+		if c.Errors.ProblemJSON || middleware.ProblemJSONFromContext(ctx) {
+			return e.StatusCode, e.Problem(requestID)
+		}
+		return e.StatusCode, e.Response(requestID)
+	}
+}
+
+// NotFoundHandler renders unknown routes with the gateway error envelope
+func NotFoundHandler(c config.Config) http.Handler {
+	return withErrorMiddlewares(c, func(w http.ResponseWriter, r *http.Request) {
+		httpx.ErrorCtx(r.Context(), w, errors.ErrNotFound.WithDetails("Route not found"))
+	})
+}
+
+// NotAllowedHandler renders unsupported methods with the gateway error envelope
+func NotAllowedHandler(c config.Config) http.Handler {
+	return withErrorMiddlewares(c, func(w http.ResponseWriter, r *http.Request) {
+		httpx.ErrorCtx(r.Context(), w, errors.NewError(http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed"))
+	})
+}
+
+// globalMiddlewares are applied to every route, including the fallback handlers
+func globalMiddlewares(c config.Config) []func(http.HandlerFunc) http.HandlerFunc {
+	return []func(http.HandlerFunc) http.HandlerFunc{
+		middleware.NewRequestIDMiddleware().Handle,
+		middleware.NewProblemJSONMiddleware(c.Errors.ProblemJSON).Handle,
+	}
+}
+
+func withErrorMiddlewares(c config.Config, h http.HandlerFunc) http.Handler {
+	ms := globalMiddlewares(c)
+	for i := len(ms) - 1; i >= 0; i-- {
+		h = ms[i](h)
+	}
+	return h
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Nha1410/go-zero-template/api/internal/config"
+	"github.com/Nha1410/go-zero-template/api/internal/middleware"
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func TestMain(m *testing.M) {
+	logx.Disable()
+	httpx.SetErrorHandlerCtx(ErrorHandler(config.Config{}))
+	os.Exit(m.Run())
+}
+
+// serveError runs a handler failing with err behind the gateway-wide middleware
+func serveError(c config.Config, err error, accept string) *httptest.ResponseRecorder {
+	h := withErrorMiddlewares(c, func(w http.ResponseWriter, r *http.Request) {
+		httpx.ErrorCtx(r.Context(), w, err)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestErrorHandler(t *testing.T) {
+	violation := errors.FieldViolation{Field: "email", Description: "must be a valid email"}
+
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+		wantDetails string
+		wantFields  int
+	}{
+		{
+			name:        "client error",
+			err:         errors.ErrNotFound.WithDetails("User not found"),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    "NOT_FOUND",
+			wantMessage: "Resource not found",
+			wantDetails: "User not found",
+		},
+		{
+			name:        "validation error",
+			err:         errors.ErrBadRequest.WithViolations(violation),
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "BAD_REQUEST",
+			wantMessage: "Invalid request",
+			wantFields:  1,
+		},
+		{
+			name:        "wrapped error",
+			err:         fmt.Errorf("update user: %w", errors.ErrPreconditionFailed),
+			wantStatus:  http.StatusPreconditionFailed,
+			wantCode:    "PRECONDITION_FAILED",
+			wantMessage: "Precondition failed",
+		},
+		{
+			name:        "server error details are hidden",
+			err:         errors.ErrServiceUnavailable.WithDetails("dial tcp 10.0.0.1:8080: connection refused"),
+			wantStatus:  http.StatusServiceUnavailable,
+			wantCode:    "SERVICE_UNAVAILABLE",
+			wantMessage: "Service unavailable",
+		},
+		{
+			name:        "unknown error",
+			err:         fmt.Errorf("pq: relation \"users\" does not exist"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    "INTERNAL_ERROR",
+			wantMessage: "Internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"/envelope", func(t *testing.T) {
+			w := serveError(config.Config{}, tt.err, "application/json")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get(httpx.ContentType); got != "application/json; charset=utf-8" {
+				t.Fatalf("Content-Type = %q, want application/json", got)
+			}
+
+			var body errors.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.StatusCode != tt.wantStatus || body.Code != tt.wantCode || body.Message != tt.wantMessage ||
+				body.Details != tt.wantDetails || len(body.Violations) != tt.wantFields {
+				t.Fatalf("body = %+v", body)
+			}
+			if body.RequestID == "" || body.RequestID != w.Header().Get(middleware.RequestIDHeader) {
+				t.Fatalf("request_id = %q, want the %s header %q",
+					body.RequestID, middleware.RequestIDHeader, w.Header().Get(middleware.RequestIDHeader))
+			}
+		})
+
+		t.Run(tt.name+"/problem", func(t *testing.T) {
+			w := serveError(config.Config{}, tt.err, "application/problem+json, application/json;q=0.5")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get(httpx.ContentType); got != errors.ProblemContentType {
+				t.Fatalf("Content-Type = %q, want %s", got, errors.ProblemContentType)
+			}
+
+			var body errors.ProblemDetails
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.Type != "about:blank" || body.Status != tt.wantStatus || body.Code != tt.wantCode ||
+				body.Title != tt.wantMessage || body.Detail != tt.wantDetails || len(body.Violations) != tt.wantFields {
+				t.Fatalf("body = %+v", body)
+			}
+			if body.RequestID == "" || body.RequestID != w.Header().Get(middleware.RequestIDHeader) {
+				t.Fatalf("request_id = %q, want the %s header", body.RequestID, middleware.RequestIDHeader)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerProblemJSONEnabled(t *testing.T) {
+	var c config.Config
+	c.Errors.ProblemJSON = true
+
+	for _, accept := range []string{"", "application/json", "*/*"} {
+		w := serveError(c, errors.ErrForbidden, accept)
+		if got := w.Header().Get(httpx.ContentType); got != errors.ProblemContentType {
+			t.Fatalf("Accept %q: Content-Type = %q, want %s", accept, got, errors.ProblemContentType)
+		}
+		var body errors.ProblemDetails
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Status != http.StatusForbidden {
+			t.Fatalf("Accept %q: body = %s, want problem details", accept, w.Body.String())
+		}
+	}
+}
+
+func TestFallbackHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "not found", handler: NotFoundHandler(config.Config{}), wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND"},
+		{name: "not allowed", handler: NotAllowedHandler(config.Config{}), wantStatus: http.StatusMethodNotAllowed, wantCode: "METHOD_NOT_ALLOWED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/unknown", nil)
+			r.Header.Set(middleware.RequestIDHeader, "request-1")
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, r)
+
+			var body errors.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if w.Code != tt.wantStatus || body.Code != tt.wantCode || body.RequestID != "request-1" {
+				t.Fatalf("response = %d %+v, want %d %s for request-1", w.Code, body, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Nha1410/go-zero-template/api/internal/svc"
+	"github.com/Nha1410/go-zero-template/common/health"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// LivenessHandler reports that the process is running. It never checks
+// dependencies, so an outage does not cause the orchestrator to restart the gateway
+func LivenessHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		httpx.OkJsonCtx(r.Context(), w, map[string]interface{}{
+			"code":    http.StatusOK,
+			"message": "OK",
+			"data": map[string]string{
+				"status": health.StatusUp,
+			},
+		})
+	}
+}
+
+// ReadinessHandler checks every enabled dependency and returns 503 with the
+// per-component report if a required one is down. Optional dependencies that
+// are down only mark the report degraded
+func ReadinessHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := svcCtx.Health.Check(r.Context())
+
+		statusCode := http.StatusOK
+		if !report.Ready() {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		httpx.WriteJsonCtx(r.Context(), w, statusCode, map[string]interface{}{
+			"code":    statusCode,
+			"message": http.StatusText(statusCode),
+			"data":    report,
+		})
+	}
+}
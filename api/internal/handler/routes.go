@@ -7,49 +7,93 @@ import (
 )
 
 func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
+	// Gateway-wide middleware
+	for _, m := range globalMiddlewares(serverCtx.Config) {
+		server.Use(m)
+	}
+
 	// Create middleware
 	authMiddleware := middleware.NewAuthMiddleware(serverCtx)
+	authorize := middleware.NewAuthorizeMiddleware(serverCtx)
+	rateLimit := middleware.NewRateLimitMiddleware(serverCtx)
+	idempotency := middleware.NewIdempotencyMiddleware(serverCtx)
+
+	// Authorization policies
+	adminOnly := authorize.RequireRoles(middleware.RoleAdmin)
+	adminOrOwner := authorize.Require(middleware.Policy{
+		AnyRole: []string{middleware.RoleAdmin},
+		Owner:   authorize.OwnsUser(),
+	})
 
 	// Public routes
-	server.AddRoute(
-		rest.Route{
+	server.AddRoutes([]rest.Route{
+		{
+			// Kept for existing probes; equivalent to /health/live
 			Method:  "GET",
 			Path:    "/health",
-			Handler: HealthCheckHandler(serverCtx),
+			Handler: LivenessHandler(serverCtx),
 		},
-	)
+		{
+			Method:  "GET",
+			Path:    "/health/live",
+			Handler: LivenessHandler(serverCtx),
+		},
+		{
+			Method:  "GET",
+			Path:    "/health/ready",
+			Handler: ReadinessHandler(serverCtx),
+		},
+	})
 
-	// Protected routes - require authentication
+	// Protected routes - are rate limited per client, require authentication,
+	// are rate limited per caller, enforce a per-route authorization policy and
+	// then replay mutating requests that repeat an Idempotency-Key
+	idempotent := idempotency.Handle
 	server.AddRoutes(
 		rest.WithMiddlewares(
-			[]rest.Middleware{authMiddleware.Handle},
-			[]rest.Route{
+			[]rest.Middleware{rateLimit.HandleClient, authMiddleware.Handle},
+			rateLimit.Wrap([]rest.Route{
 				{
 					Method:  "POST",
 					Path:    "/api/v1/users",
-					Handler: CreateUserHandler(serverCtx),
+					Handler: adminOnly(idempotent(CreateUserHandler(serverCtx))),
+				},
+				{
+					Method:  "GET",
+					Path:    "/api/v1/users/search",
+					Handler: adminOnly(SearchUsersHandler(serverCtx)),
 				},
 				{
 					Method:  "GET",
 					Path:    "/api/v1/users/:id",
-					Handler: GetUserHandler(serverCtx),
+					Handler: adminOrOwner(GetUserHandler(serverCtx)),
 				},
 				{
 					Method:  "GET",
 					Path:    "/api/v1/users",
-					Handler: GetUsersHandler(serverCtx),
+					Handler: adminOnly(GetUsersHandler(serverCtx)),
 				},
 				{
 					Method:  "PUT",
 					Path:    "/api/v1/users/:id",
-					Handler: UpdateUserHandler(serverCtx),
+					Handler: adminOrOwner(idempotent(UpdateUserHandler(serverCtx))),
+				},
+				{
+					Method:  "PATCH",
+					Path:    "/api/v1/users/:id",
+					Handler: adminOrOwner(idempotent(PatchUserHandler(serverCtx))),
 				},
 				{
 					Method:  "DELETE",
 					Path:    "/api/v1/users/:id",
-					Handler: DeleteUserHandler(serverCtx),
+					Handler: adminOnly(idempotent(DeleteUserHandler(serverCtx))),
+				},
+				{
+					Method:  "POST",
+					Path:    "/api/v1/users/:id/restore",
+					Handler: adminOnly(idempotent(RestoreUserHandler(serverCtx))),
 				},
-			}...,
+			})...,
 		),
 	)
 }
@@ -1,20 +1,24 @@
 package handler
 
 import (
+	"encoding/json"
+	"mime"
 	"net/http"
 	"strconv"
 
 	"github.com/Nha1410/go-zero-template/api/internal/logic"
 	"github.com/Nha1410/go-zero-template/api/internal/svc"
 	"github.com/Nha1410/go-zero-template/api/internal/types"
+	"github.com/Nha1410/go-zero-template/common/errors"
 	"github.com/zeromicro/go-zero/rest/httpx"
+	"github.com/zeromicro/go-zero/rest/pathvar"
 )
 
 func CreateUserHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req types.CreateUserRequest
 		if err := httpx.Parse(r, &req); err != nil {
-			httpx.ErrorCtx(r.Context(), w, err)
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails(err.Error()))
 			return
 		}
 
@@ -23,6 +27,7 @@ func CreateUserHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 		if err != nil {
 			httpx.ErrorCtx(r.Context(), w, err)
 		} else {
+			setUserETag(w, resp)
 			httpx.OkJsonCtx(r.Context(), w, resp)
 		}
 	}
@@ -32,13 +37,13 @@ func GetUserHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req types.GetUserRequest
 		if err := httpx.Parse(r, &req); err != nil {
-			httpx.ErrorCtx(r.Context(), w, err)
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails(err.Error()))
 			return
 		}
 
 		id, err := strconv.ParseInt(req.Id, 10, 64)
 		if err != nil {
-			httpx.ErrorCtx(r.Context(), w, err)
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails("Invalid user ID"))
 			return
 		}
 		req.IdInt = id
@@ -47,9 +52,15 @@ func GetUserHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 		resp, err := l.GetUser(&req)
 		if err != nil {
 			httpx.ErrorCtx(r.Context(), w, err)
-		} else {
-			httpx.OkJsonCtx(r.Context(), w, resp)
+			return
+		}
+
+		etag := setUserETag(w, resp)
+		if req.IfNoneMatch != "" && logic.ETagMatches(req.IfNoneMatch, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
 		}
+		httpx.OkJsonCtx(r.Context(), w, resp)
 	}
 }
 
@@ -57,7 +68,7 @@ func GetUsersHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req types.GetUsersRequest
 		if err := httpx.Parse(r, &req); err != nil {
-			httpx.ErrorCtx(r.Context(), w, err)
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails(err.Error()))
 			return
 		}
 
@@ -71,17 +82,35 @@ func GetUsersHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	}
 }
 
+func SearchUsersHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.SearchUsersRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails(err.Error()))
+			return
+		}
+
+		l := logic.NewSearchUsersLogic(r.Context(), svcCtx)
+		resp, err := l.SearchUsers(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
+
 func UpdateUserHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req types.UpdateUserRequest
 		if err := httpx.Parse(r, &req); err != nil {
-			httpx.ErrorCtx(r.Context(), w, err)
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails(err.Error()))
 			return
 		}
 
-		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		id, err := strconv.ParseInt(pathvar.Vars(r)["id"], 10, 64)
 		if err != nil {
-			httpx.ErrorCtx(r.Context(), w, err)
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails("Invalid user ID"))
 			return
 		}
 		req.Id = id
@@ -91,6 +120,46 @@ func UpdateUserHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 		if err != nil {
 			httpx.ErrorCtx(r.Context(), w, err)
 		} else {
+			setUserETag(w, resp)
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
+
+// mergePatchContentType is the media type of RFC 7396 merge patches
+const mergePatchContentType = "application/merge-patch+json"
+
+func PatchUserHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.PatchUserRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails(err.Error()))
+			return
+		}
+
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != mergePatchContentType {
+			httpx.ErrorCtx(r.Context(), w, errors.NewError(http.StatusUnsupportedMediaType,
+				"UNSUPPORTED_MEDIA_TYPE", "Unsupported media type").WithDetails("Content-Type must be "+mergePatchContentType))
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req.Patch); err != nil || req.Patch == nil {
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails("Body must be a JSON object"))
+			return
+		}
+
+		id, err := strconv.ParseInt(pathvar.Vars(r)["id"], 10, 64)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails("Invalid user ID"))
+			return
+		}
+		req.Id = id
+
+		l := logic.NewPatchUserLogic(r.Context(), svcCtx)
+		resp, err := l.PatchUser(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			setUserETag(w, resp)
 			httpx.OkJsonCtx(r.Context(), w, resp)
 		}
 	}
@@ -100,13 +169,13 @@ func DeleteUserHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req types.DeleteUserRequest
 		if err := httpx.Parse(r, &req); err != nil {
-			httpx.ErrorCtx(r.Context(), w, err)
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails(err.Error()))
 			return
 		}
 
-		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		id, err := strconv.ParseInt(pathvar.Vars(r)["id"], 10, 64)
 		if err != nil {
-			httpx.ErrorCtx(r.Context(), w, err)
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails("Invalid user ID"))
 			return
 		}
 		req.Id = id
@@ -120,3 +189,40 @@ func DeleteUserHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 		}
 	}
 }
+
+func RestoreUserHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.RestoreUserRequest
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails(err.Error()))
+			return
+		}
+
+		id, err := strconv.ParseInt(pathvar.Vars(r)["id"], 10, 64)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails("Invalid user ID"))
+			return
+		}
+		req.Id = id
+
+		l := logic.NewRestoreUserLogic(r.Context(), svcCtx)
+		resp, err := l.RestoreUser(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			setUserETag(w, resp)
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
+
+// setUserETag sets the ETag header from the user in resp and returns it
+func setUserETag(w http.ResponseWriter, resp *types.BaseResponse) string {
+	user, ok := resp.Data.(types.User)
+	if !ok {
+		return ""
+	}
+	etag := logic.ETag(user.Version)
+	w.Header().Set("ETag", etag)
+	return etag
+}
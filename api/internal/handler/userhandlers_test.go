@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nha1410/go-zero-template/api/internal/config"
+	"github.com/Nha1410/go-zero-template/api/internal/svc"
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/service/user/userclient"
+	"github.com/zeromicro/go-zero/rest/pathvar"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// fakeUserRpc serves a single user at version 3 and records the write requests
+type fakeUserRpc struct {
+	userclient.User
+	updated *userclient.UpdateUserReq
+	deleted *userclient.DeleteUserReq
+	patched *userclient.PatchUserReq
+}
+
+const currentVersion = 3
+
+var errUserModified = errors.ToGRPCError(errors.ErrPreconditionFailed.WithDetails("User has been modified"))
+
+func (f *fakeUserRpc) GetUser(ctx context.Context, in *userclient.GetUserReq, opts ...grpc.CallOption) (*userclient.GetUserResp, error) {
+	return &userclient.GetUserResp{Id: in.Id, Email: "jane@example.com", Name: "Jane", Version: currentVersion}, nil
+}
+
+func (f *fakeUserRpc) UpdateUser(ctx context.Context, in *userclient.UpdateUserReq, opts ...grpc.CallOption) (*userclient.GetUserResp, error) {
+	f.updated = in
+	if in.ExpectedVersion != 0 && in.ExpectedVersion != currentVersion {
+		return nil, errUserModified
+	}
+	return &userclient.GetUserResp{Id: in.Id, Email: in.Email, Name: in.Name, CreatedAt: "2024-05-01T00:00:00Z", Version: currentVersion + 1}, nil
+}
+
+func (f *fakeUserRpc) PatchUser(ctx context.Context, in *userclient.PatchUserReq, opts ...grpc.CallOption) (*userclient.GetUserResp, error) {
+	f.patched = in
+	return &userclient.GetUserResp{Id: in.Id, Email: in.User.Email, Name: in.User.Name, Version: currentVersion + 1}, nil
+}
+
+func (f *fakeUserRpc) DeleteUser(ctx context.Context, in *userclient.DeleteUserReq, opts ...grpc.CallOption) (*userclient.DeleteUserResp, error) {
+	f.deleted = in
+	if in.ExpectedVersion != 0 && in.ExpectedVersion != currentVersion {
+		return nil, errUserModified
+	}
+	return &userclient.DeleteUserResp{}, nil
+}
+
+func newUserRequest(method, body string, header map[string]string) *http.Request {
+	r := httptest.NewRequest(method, "/api/v1/users/1", strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	return pathvar.WithVars(r, map[string]string{"id": "1"})
+}
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body errors.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %q: %v", w.Body.String(), err)
+	}
+	return body.Code
+}
+
+func TestGetUserIfNoneMatch(t *testing.T) {
+	handler := GetUserHandler(&svc.ServiceContext{UserRpc: &fakeUserRpc{}})
+
+	tests := []struct {
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{ifNoneMatch: "", wantStatus: http.StatusOK},
+		{ifNoneMatch: `"3"`, wantStatus: http.StatusNotModified},
+		{ifNoneMatch: `W/"3"`, wantStatus: http.StatusNotModified},
+		{ifNoneMatch: `"1", "3"`, wantStatus: http.StatusNotModified},
+		{ifNoneMatch: `*`, wantStatus: http.StatusNotModified},
+		{ifNoneMatch: `"2"`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ifNoneMatch, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler(w, newUserRequest(http.MethodGet, "", map[string]string{"If-None-Match": tt.ifNoneMatch}))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := w.Header().Get("ETag"); got != `"3"` {
+				t.Fatalf(`ETag = %s, want "3"`, got)
+			}
+			if tt.wantStatus == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Fatalf("body = %q, want none", w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && !strings.Contains(w.Body.String(), "jane@example.com") {
+				t.Fatalf("body = %q, want the user", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestWriteIfMatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		ifMatch        string
+		requireIfMatch bool
+		wantStatus     int
+		wantCode       string
+		wantCalled     bool
+		wantVersion    int64
+	}{
+		{name: "update current", method: http.MethodPut, ifMatch: `"3"`, wantStatus: http.StatusOK, wantCalled: true, wantVersion: 3},
+		{name: "update stale", method: http.MethodPut, ifMatch: `"2"`, wantStatus: http.StatusPreconditionFailed, wantCode: "PRECONDITION_FAILED", wantCalled: true, wantVersion: 2},
+		{name: "update weak", method: http.MethodPut, ifMatch: `W/"3"`, wantStatus: http.StatusPreconditionFailed, wantCode: "PRECONDITION_FAILED"},
+		{name: "update any", method: http.MethodPut, ifMatch: `*`, wantStatus: http.StatusOK, wantCalled: true},
+		{name: "update unconditional", method: http.MethodPut, wantStatus: http.StatusOK, wantCalled: true},
+		{name: "update required", method: http.MethodPut, requireIfMatch: true, wantStatus: http.StatusPreconditionRequired, wantCode: "PRECONDITION_REQUIRED"},
+		{name: "update required and current", method: http.MethodPut, ifMatch: `"3"`, requireIfMatch: true, wantStatus: http.StatusOK, wantCalled: true, wantVersion: 3},
+		{name: "delete current", method: http.MethodDelete, ifMatch: `"3"`, wantStatus: http.StatusOK, wantCalled: true, wantVersion: 3},
+		{name: "delete stale", method: http.MethodDelete, ifMatch: `"2"`, wantStatus: http.StatusPreconditionFailed, wantCode: "PRECONDITION_FAILED", wantCalled: true, wantVersion: 2},
+		{name: "delete required", method: http.MethodDelete, requireIfMatch: true, wantStatus: http.StatusPreconditionRequired, wantCode: "PRECONDITION_REQUIRED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpc := &fakeUserRpc{}
+			var c config.Config
+			c.Conditional.RequireIfMatch = tt.requireIfMatch
+			svcCtx := &svc.ServiceContext{Config: c, UserRpc: rpc}
+
+			var handler http.HandlerFunc
+			var body string
+			if tt.method == http.MethodPut {
+				handler, body = UpdateUserHandler(svcCtx), `{"id": 1, "name": "Jane Doe"}`
+			} else {
+				handler = DeleteUserHandler(svcCtx)
+			}
+
+			w := httptest.NewRecorder()
+			handler(w, newUserRequest(tt.method, body, map[string]string{"If-Match": tt.ifMatch}))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				if got := errorCode(t, w); got != tt.wantCode {
+					t.Fatalf("code = %s, want %s", got, tt.wantCode)
+				}
+			}
+
+			var called bool
+			var version int64
+			if rpc.updated != nil {
+				called, version = true, rpc.updated.ExpectedVersion
+			} else if rpc.deleted != nil {
+				called, version = true, rpc.deleted.ExpectedVersion
+			}
+			if called != tt.wantCalled || version != tt.wantVersion {
+				t.Fatalf("RPC called = %v with expected version %d, want %v with %d",
+					called, version, tt.wantCalled, tt.wantVersion)
+			}
+
+			if tt.method == http.MethodPut && tt.wantStatus == http.StatusOK {
+				if got := w.Header().Get("ETag"); got != `"4"` {
+					t.Fatalf(`ETag = %s, want "4"`, got)
+				}
+				// The same user shape as GET, PATCH and restore
+				if !strings.Contains(w.Body.String(), `"created_at":"2024-05-01T00:00:00Z"`) {
+					t.Fatalf("body = %s, want the created_at of the user", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+		wantPatch   *userclient.PatchUserReq
+	}{
+		{
+			name:        "set a field",
+			contentType: "application/merge-patch+json",
+			body:        `{"name": "Jane Doe"}`,
+			wantStatus:  http.StatusOK,
+			wantPatch: &userclient.PatchUserReq{
+				Id:         1,
+				User:       &userclient.UserFields{Name: "Jane Doe"},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+			},
+		},
+		{
+			name:        "null clears a field",
+			contentType: "application/merge-patch+json; charset=utf-8",
+			body:        `{"name": null, "email": "jane@example.com"}`,
+			wantStatus:  http.StatusOK,
+			wantPatch: &userclient.PatchUserReq{
+				Id:         1,
+				User:       &userclient.UserFields{Email: "jane@example.com"},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email", "name"}},
+			},
+		},
+		{
+			name:        "plain JSON",
+			contentType: "application/json",
+			body:        `{"name": "Jane Doe"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    "UNSUPPORTED_MEDIA_TYPE",
+		},
+		{
+			name:       "missing content type",
+			body:       `{"name": "Jane Doe"}`,
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantCode:   "UNSUPPORTED_MEDIA_TYPE",
+		},
+		{
+			name:        "nested object",
+			contentType: "application/merge-patch+json",
+			body:        `{"name": {"first": "Jane"}}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "BAD_REQUEST",
+		},
+		{
+			name:        "unknown field",
+			contentType: "application/merge-patch+json",
+			body:        `{"nickname": "JD"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "BAD_REQUEST",
+		},
+		{
+			name:        "read-only field",
+			contentType: "application/merge-patch+json",
+			body:        `{"version": 7}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "BAD_REQUEST",
+		},
+		{
+			name:        "empty patch",
+			contentType: "application/merge-patch+json",
+			body:        `{}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "BAD_REQUEST",
+		},
+		{
+			name:        "not an object",
+			contentType: "application/merge-patch+json",
+			body:        `["name"]`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "BAD_REQUEST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpc := &fakeUserRpc{}
+			handler := PatchUserHandler(&svc.ServiceContext{UserRpc: rpc})
+
+			r := newUserRequest(http.MethodPatch, tt.body, nil)
+			r.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				if got := errorCode(t, w); got != tt.wantCode {
+					t.Fatalf("code = %s, want %s", got, tt.wantCode)
+				}
+			}
+
+			if tt.wantPatch == nil {
+				if rpc.patched != nil {
+					t.Fatalf("PatchUser called with %v, want no call", rpc.patched)
+				}
+				return
+			}
+			if !proto.Equal(rpc.patched, tt.wantPatch) {
+				t.Fatalf("PatchUser request = %v, want %v", rpc.patched, tt.wantPatch)
+			}
+			if got := w.Header().Get("ETag"); got != `"4"` {
+				t.Fatalf(`ETag = %s, want "4"`, got)
+			}
+		})
+	}
+}
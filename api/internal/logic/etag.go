@@ -0,0 +1,51 @@
+package logic
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Nha1410/go-zero-template/common/errors"
+)
+
+// ETag returns the strong entity tag of a user at version
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ETagMatches reports whether an If-None-Match header matches etag, using the
+// weak comparison RFC 9110 requires for it
+func ETagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIfMatch returns the user version an If-Match header requires, or 0
+// when it is empty or "*" and any current version is accepted. An empty
+// header fails with ErrPreconditionRequired when required. If-Match uses
+// strong comparison, so a weak or foreign tag can never match
+func parseIfMatch(header string, required bool) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" && required {
+		return 0, errors.ErrPreconditionRequired.WithDetails("If-Match is required")
+	}
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, errors.ErrBadRequest.WithDetails("If-Match must hold a single entity tag")
+	}
+	value, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		value, ok = strings.CutSuffix(value, `"`)
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if !ok || err != nil || version < 1 {
+		return 0, errors.ErrPreconditionFailed.WithDetails("If-Match does not match the current version")
+	}
+	return version, nil
+}
@@ -0,0 +1,75 @@
+package logic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Nha1410/go-zero-template/common/errors"
+)
+
+func TestETag(t *testing.T) {
+	if got := ETag(3); got != `"3"` {
+		t.Fatalf(`ETag(3) = %s, want "3"`, got)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: `"3"`, want: true},
+		{header: `W/"3"`, want: true},
+		{header: `"2"`, want: false},
+		{header: `W/"2"`, want: false},
+		{header: `"1", "2", "3"`, want: true},
+		{header: `"1",W/"3"`, want: true},
+		{header: `"1", "2"`, want: false},
+		{header: `*`, want: true},
+		{header: `3`, want: false},
+		{header: `"03"`, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := ETagMatches(tt.header, `"3"`); got != tt.want {
+			t.Errorf("ETagMatches(%s) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header      string
+		required    bool
+		wantVersion int64
+		wantStatus  int
+	}{
+		{header: "", wantVersion: 0},
+		{header: "*", wantVersion: 0},
+		{header: `"3"`, wantVersion: 3},
+		{header: ` "3" `, wantVersion: 3},
+		{header: `"3"`, required: true, wantVersion: 3},
+		{header: "*", required: true, wantVersion: 0},
+		{header: "", required: true, wantStatus: http.StatusPreconditionRequired},
+		// If-Match uses strong comparison, so weak tags never match
+		{header: `W/"3"`, wantStatus: http.StatusPreconditionFailed},
+		{header: `3`, wantStatus: http.StatusPreconditionFailed},
+		{header: `"abc"`, wantStatus: http.StatusPreconditionFailed},
+		{header: `"0"`, wantStatus: http.StatusPreconditionFailed},
+		{header: `"3`, wantStatus: http.StatusPreconditionFailed},
+		{header: `"2", "3"`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		version, err := parseIfMatch(tt.header, tt.required)
+		if tt.wantStatus != 0 {
+			if e := errors.FromError(err); err == nil || e.StatusCode != tt.wantStatus {
+				t.Errorf("parseIfMatch(%s, %v) error = %v, want status %d", tt.header, tt.required, err, tt.wantStatus)
+			}
+			continue
+		}
+		if err != nil || version != tt.wantVersion {
+			t.Errorf("parseIfMatch(%s, %v) = %d, %v, want %d", tt.header, tt.required, version, err, tt.wantVersion)
+		}
+	}
+}
@@ -2,12 +2,17 @@ package logic
 
 import (
 	"context"
+	"fmt"
+	"sort"
 
+	"github.com/Nha1410/go-zero-template/api/internal/middleware"
 	"github.com/Nha1410/go-zero-template/api/internal/svc"
 	"github.com/Nha1410/go-zero-template/api/internal/types"
 	"github.com/Nha1410/go-zero-template/common/errors"
 	"github.com/Nha1410/go-zero-template/common/validator"
+	"github.com/Nha1410/go-zero-template/service/user/userclient"
 	"github.com/zeromicro/go-zero/core/logx"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 type CreateUserLogic struct {
@@ -29,10 +34,30 @@ func (l *CreateUserLogic) CreateUser(req *types.CreateUserRequest) (*types.BaseR
 		return nil, errors.ErrBadRequest.WithDetails(err.Error())
 	}
 
+	resp, err := l.svcCtx.UserRpc.CreateUser(l.ctx, &userclient.CreateUserReq{
+		Email: req.Email,
+		Name:  req.Name,
+	})
+	if err != nil {
+		l.Errorf("Failed to create user: %v", err)
+		return nil, errors.FromGRPCError(err)
+	}
+	if resp == nil {
+		l.Error("User service returned no user for create")
+		return nil, errors.ErrInternalError
+	}
+
 	return &types.BaseResponse{
 		Code:    200,
 		Message: "User created successfully",
-		Data:    nil,
+		Data: types.User{
+			Id:        resp.Id,
+			Email:     resp.Email,
+			Name:      resp.Name,
+			CreatedAt: resp.CreatedAt,
+			UpdatedAt: resp.CreatedAt,
+			Version:   resp.Version,
+		},
 	}, nil
 }
 
@@ -51,10 +76,28 @@ func NewGetUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetUserLo
 }
 
 func (l *GetUserLogic) GetUser(req *types.GetUserRequest) (*types.BaseResponse, error) {
+	// Owners may read their own record, but only admins see deleted users
+	if req.IncludeDeleted && !middleware.HasRole(l.ctx, middleware.RoleAdmin) {
+		return nil, errors.ErrForbidden.WithDetails("include_deleted requires the admin role")
+	}
+
+	resp, err := l.svcCtx.UserRpc.GetUser(l.ctx, &userclient.GetUserReq{
+		Id:             req.IdInt,
+		IncludeDeleted: req.IncludeDeleted,
+	})
+	if err != nil {
+		l.Errorf("Failed to get user %d: %v", req.IdInt, err)
+		return nil, errors.FromGRPCError(err)
+	}
+	if resp == nil {
+		l.Errorf("User service returned no user for %d", req.IdInt)
+		return nil, errors.ErrInternalError
+	}
+
 	return &types.BaseResponse{
 		Code:    200,
 		Message: "Success",
-		Data:    nil,
+		Data:    toUser(resp),
 	}, nil
 }
 
@@ -73,10 +116,111 @@ func NewGetUsersLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetUsers
 }
 
 func (l *GetUsersLogic) GetUsers(req *types.GetUsersRequest) (*types.BaseResponse, error) {
+	resp, err := l.svcCtx.UserRpc.GetUsers(l.ctx, &userclient.GetUsersReq{
+		Cursor:         req.Cursor,
+		PageSize:       req.PageSize,
+		TotalMode:      toTotalMode(req.Total),
+		Email:          req.Email,
+		NameContains:   req.NameContains,
+		CreatedAfter:   req.CreatedAfter,
+		CreatedBefore:  req.CreatedBefore,
+		Q:              req.Q,
+		Sort:           req.Sort,
+		Order:          toSortOrder(req.Order),
+		IncludeDeleted: req.IncludeDeleted,
+	})
+	if err != nil {
+		l.Errorf("Failed to list users: %v", err)
+		return nil, errors.FromGRPCError(err)
+	}
+	if resp == nil {
+		l.Error("User service returned no user list")
+		return nil, errors.ErrInternalError
+	}
+
+	users := make([]types.User, 0, len(resp.Users))
+	for _, u := range resp.Users {
+		users = append(users, toUser(u))
+	}
+
+	return &types.BaseResponse{
+		Code:    200,
+		Message: "Success",
+		Data: types.UserList{
+			Users:      users,
+			Total:      resp.Total,
+			TotalExact: resp.TotalExact,
+			PageSize:   resp.PageSize,
+			NextCursor: resp.NextCursor,
+			PrevCursor: resp.PrevCursor,
+		},
+	}, nil
+}
+
+func toSortOrder(order string) userclient.SortOrder {
+	if order == "asc" {
+		return userclient.SortOrder_SORT_ORDER_ASC
+	}
+	return userclient.SortOrder_SORT_ORDER_DESC
+}
+
+func toTotalMode(total string) userclient.TotalMode {
+	switch total {
+	case "exact":
+		return userclient.TotalMode_TOTAL_MODE_EXACT
+	case "none":
+		return userclient.TotalMode_TOTAL_MODE_NONE
+	default:
+		return userclient.TotalMode_TOTAL_MODE_ESTIMATED
+	}
+}
+
+type SearchUsersLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSearchUsersLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SearchUsersLogic {
+	return &SearchUsersLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *SearchUsersLogic) SearchUsers(req *types.SearchUsersRequest) (*types.BaseResponse, error) {
+	resp, err := l.svcCtx.UserRpc.SearchUsers(l.ctx, &userclient.SearchUsersReq{
+		Query: req.Q,
+		Limit: req.Limit,
+	})
+	if err != nil {
+		l.Errorf("Failed to search users: %v", err)
+		return nil, errors.FromGRPCError(err)
+	}
+	if resp == nil {
+		l.Error("User service returned no search result")
+		return nil, errors.ErrInternalError
+	}
+
+	hits := make([]types.UserSearchHit, 0, len(resp.Hits))
+	for _, h := range resp.Hits {
+		if h.User == nil {
+			l.Error("User service returned a search hit without a user")
+			return nil, errors.ErrInternalError
+		}
+		hits = append(hits, types.UserSearchHit{
+			User:           toUser(h.User),
+			Score:          h.Score,
+			NameHighlight:  h.NameHighlight,
+			EmailHighlight: h.EmailHighlight,
+		})
+	}
+
 	return &types.BaseResponse{
 		Code:    200,
 		Message: "Success",
-		Data:    nil,
+		Data:    types.UserSearchResult{Hits: hits},
 	}, nil
 }
 
@@ -99,10 +243,104 @@ func (l *UpdateUserLogic) UpdateUser(req *types.UpdateUserRequest) (*types.BaseR
 		return nil, errors.ErrBadRequest.WithDetails(err.Error())
 	}
 
+	version, err := parseIfMatch(req.IfMatch, l.svcCtx.Config.Conditional.RequireIfMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := l.svcCtx.UserRpc.UpdateUser(l.ctx, &userclient.UpdateUserReq{
+		Id:              req.Id,
+		Email:           req.Email,
+		Name:            req.Name,
+		ExpectedVersion: version,
+	})
+	if err != nil {
+		l.Errorf("Failed to update user %d: %v", req.Id, err)
+		return nil, errors.FromGRPCError(err)
+	}
+	if resp == nil {
+		l.Errorf("User service returned no user for update of %d", req.Id)
+		return nil, errors.ErrInternalError
+	}
+
 	return &types.BaseResponse{
 		Code:    200,
 		Message: "User updated successfully",
-		Data:    nil,
+		Data:    toUser(resp),
+	}, nil
+}
+
+type PatchUserLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewPatchUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *PatchUserLogic {
+	return &PatchUserLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// PatchUser applies an RFC 7396 merge patch. Members that are present are
+// set, and null clears a field; the user service rejects the result if it is
+// not a valid user
+func (l *PatchUserLogic) PatchUser(req *types.PatchUserRequest) (*types.BaseResponse, error) {
+	version, err := parseIfMatch(req.IfMatch, l.svcCtx.Config.Conditional.RequireIfMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(req.Patch))
+	for key := range req.Patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		return nil, errors.ErrBadRequest.WithDetails("Merge patch must set at least one field")
+	}
+
+	fields := &userclient.UserFields{}
+	for _, key := range keys {
+		var value string
+		if raw := req.Patch[key]; raw != nil {
+			s, ok := raw.(string)
+			if !ok {
+				return nil, errors.ErrBadRequest.WithDetails(fmt.Sprintf("Field %q must be a string or null", key))
+			}
+			value = s
+		}
+		switch key {
+		case "email":
+			fields.Email = value
+		case "name":
+			fields.Name = value
+		default:
+			return nil, errors.ErrBadRequest.WithDetails(fmt.Sprintf("Field %q cannot be patched", key))
+		}
+	}
+
+	resp, err := l.svcCtx.UserRpc.PatchUser(l.ctx, &userclient.PatchUserReq{
+		Id:              req.Id,
+		User:            fields,
+		UpdateMask:      &fieldmaskpb.FieldMask{Paths: keys},
+		ExpectedVersion: version,
+	})
+	if err != nil {
+		l.Errorf("Failed to patch user %d: %v", req.Id, err)
+		return nil, errors.FromGRPCError(err)
+	}
+	if resp == nil {
+		l.Errorf("User service returned no user for patch of %d", req.Id)
+		return nil, errors.ErrInternalError
+	}
+
+	return &types.BaseResponse{
+		Code:    200,
+		Message: "User updated successfully",
+		Data:    toUser(resp),
 	}, nil
 }
 
@@ -121,8 +359,70 @@ func NewDeleteUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *Delete
 }
 
 func (l *DeleteUserLogic) DeleteUser(req *types.DeleteUserRequest) (*types.BaseResponse, error) {
+	version, err := parseIfMatch(req.IfMatch, l.svcCtx.Config.Conditional.RequireIfMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := l.svcCtx.UserRpc.DeleteUser(l.ctx, &userclient.DeleteUserReq{
+		Id:              req.Id,
+		ExpectedVersion: version,
+	}); err != nil {
+		l.Errorf("Failed to delete user %d: %v", req.Id, err)
+		return nil, errors.FromGRPCError(err)
+	}
+	l.Infof("User %d deleted by %s", req.Id, middleware.UserIDFromContext(l.ctx))
+
 	return &types.BaseResponse{
 		Code:    200,
 		Message: "User deleted successfully",
 	}, nil
 }
+
+type RestoreUserLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRestoreUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RestoreUserLogic {
+	return &RestoreUserLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *RestoreUserLogic) RestoreUser(req *types.RestoreUserRequest) (*types.BaseResponse, error) {
+	resp, err := l.svcCtx.UserRpc.RestoreUser(l.ctx, &userclient.RestoreUserReq{
+		Id: req.Id,
+	})
+	if err != nil {
+		l.Errorf("Failed to restore user %d: %v", req.Id, err)
+		return nil, errors.FromGRPCError(err)
+	}
+	if resp == nil {
+		l.Errorf("User service returned no user for restore of %d", req.Id)
+		return nil, errors.ErrInternalError
+	}
+	l.Infof("User %d restored by %s", req.Id, middleware.UserIDFromContext(l.ctx))
+
+	return &types.BaseResponse{
+		Code:    200,
+		Message: "User restored successfully",
+		Data:    toUser(resp),
+	}, nil
+}
+
+// toUser maps a user RPC response to the gateway user type
+func toUser(u *userclient.GetUserResp) types.User {
+	return types.User{
+		Id:        u.Id,
+		Email:     u.Email,
+		Name:      u.Name,
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+		DeletedAt: u.DeletedAt,
+		Version:   u.Version,
+	}
+}
@@ -0,0 +1,87 @@
+package logic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Nha1410/go-zero-template/api/internal/svc"
+	"github.com/Nha1410/go-zero-template/api/internal/types"
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/service/user/userclient"
+	"google.golang.org/grpc"
+)
+
+// emptyUserRpc answers every call with neither a response nor an error
+type emptyUserRpc struct {
+	userclient.User
+}
+
+func (emptyUserRpc) CreateUser(ctx context.Context, in *userclient.CreateUserReq, opts ...grpc.CallOption) (*userclient.CreateUserResp, error) {
+	return nil, nil
+}
+
+func (emptyUserRpc) GetUser(ctx context.Context, in *userclient.GetUserReq, opts ...grpc.CallOption) (*userclient.GetUserResp, error) {
+	return nil, nil
+}
+
+func (emptyUserRpc) GetUsers(ctx context.Context, in *userclient.GetUsersReq, opts ...grpc.CallOption) (*userclient.GetUsersResp, error) {
+	return nil, nil
+}
+
+func (emptyUserRpc) SearchUsers(ctx context.Context, in *userclient.SearchUsersReq, opts ...grpc.CallOption) (*userclient.SearchUsersResp, error) {
+	// A hit without its user is as incomplete as no response
+	return &userclient.SearchUsersResp{Hits: []*userclient.UserSearchHit{{Score: 1}}}, nil
+}
+
+func (emptyUserRpc) UpdateUser(ctx context.Context, in *userclient.UpdateUserReq, opts ...grpc.CallOption) (*userclient.GetUserResp, error) {
+	return nil, nil
+}
+
+func (emptyUserRpc) PatchUser(ctx context.Context, in *userclient.PatchUserReq, opts ...grpc.CallOption) (*userclient.GetUserResp, error) {
+	return nil, nil
+}
+
+func (emptyUserRpc) RestoreUser(ctx context.Context, in *userclient.RestoreUserReq, opts ...grpc.CallOption) (*userclient.GetUserResp, error) {
+	return nil, nil
+}
+
+func TestUserLogicEmptyResponse(t *testing.T) {
+	ctx := context.Background()
+	svcCtx := &svc.ServiceContext{UserRpc: emptyUserRpc{}}
+
+	tests := []struct {
+		name string
+		call func() (*types.BaseResponse, error)
+	}{
+		{name: "create", call: func() (*types.BaseResponse, error) {
+			return NewCreateUserLogic(ctx, svcCtx).CreateUser(&types.CreateUserRequest{Email: "jane@example.com", Name: "Jane"})
+		}},
+		{name: "get", call: func() (*types.BaseResponse, error) {
+			return NewGetUserLogic(ctx, svcCtx).GetUser(&types.GetUserRequest{IdInt: 1})
+		}},
+		{name: "list", call: func() (*types.BaseResponse, error) {
+			return NewGetUsersLogic(ctx, svcCtx).GetUsers(&types.GetUsersRequest{})
+		}},
+		{name: "search", call: func() (*types.BaseResponse, error) {
+			return NewSearchUsersLogic(ctx, svcCtx).SearchUsers(&types.SearchUsersRequest{Q: "jane"})
+		}},
+		{name: "update", call: func() (*types.BaseResponse, error) {
+			return NewUpdateUserLogic(ctx, svcCtx).UpdateUser(&types.UpdateUserRequest{Id: 1, Name: "Jane"})
+		}},
+		{name: "patch", call: func() (*types.BaseResponse, error) {
+			return NewPatchUserLogic(ctx, svcCtx).PatchUser(&types.PatchUserRequest{Id: 1, Patch: map[string]interface{}{"name": "Jane"}})
+		}},
+		{name: "restore", call: func() (*types.BaseResponse, error) {
+			return NewRestoreUserLogic(ctx, svcCtx).RestoreUser(&types.RestoreUserRequest{Id: 1})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.call()
+			if resp != nil || err != errors.ErrInternalError {
+				t.Fatalf("%s = %+v, %v, want ErrInternalError", tt.name, resp, err)
+			}
+		})
+	}
+}
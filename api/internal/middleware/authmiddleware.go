@@ -2,17 +2,22 @@ package middleware
 
 import (
 	"context"
+	stderrors "errors"
 	"net/http"
 
 	"github.com/Nha1410/go-zero-template/api/internal/svc"
 	"github.com/Nha1410/go-zero-template/common/auth"
 	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/common/interceptor"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/rest/httpx"
 )
 
 type contextKey string
 
+// wwwAuthenticateHeader carries the RFC 6750 bearer challenge of a 401
+const wwwAuthenticateHeader = "WWW-Authenticate"
+
 const (
 	userIDKey    contextKey = "user_id"
 	userEmailKey contextKey = "user_email"
@@ -33,22 +38,27 @@ func (m *AuthMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		token := auth.ExtractTokenFromRequest(r)
 		if token == "" {
+			w.Header().Set(wwwAuthenticateHeader, "Bearer")
 			httpx.ErrorCtx(r.Context(), w, errors.ErrUnauthorized)
 			return
 		}
 
 		userInfo, err := m.svcCtx.Zitadel.ValidateToken(r.Context(), token)
+		if stderrors.Is(err, auth.ErrIdPUnavailable) {
+			// The token was not judged, so the client should retry rather
+			// than sign in again
+			logx.WithContext(r.Context()).Errorf("Token validation unavailable: %v", err)
+			httpx.ErrorCtx(r.Context(), w, errors.ErrServiceUnavailable)
+			return
+		}
 		if err != nil {
-			logx.Errorf("Token validation failed: %v", err)
+			logx.WithContext(r.Context()).Errorf("Token validation failed: %v", err)
+			w.Header().Set(wwwAuthenticateHeader, `Bearer error="invalid_token", error_description="Invalid or expired token"`)
 			httpx.ErrorCtx(r.Context(), w, errors.ErrUnauthorized.WithDetails("Invalid or expired token"))
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), userIDKey, userInfo.Sub)
-		ctx = context.WithValue(ctx, userEmailKey, userInfo.Email)
-		ctx = context.WithValue(ctx, userInfoKey, userInfo)
-
-		next(w, r.WithContext(ctx))
+		next(w, r.WithContext(withUser(r.Context(), userInfo)))
 	}
 }
 
@@ -58,12 +68,40 @@ func (m *AuthMiddleware) OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
 		if token != "" {
 			userInfo, err := m.svcCtx.Zitadel.ValidateToken(r.Context(), token)
 			if err == nil {
-				ctx := context.WithValue(r.Context(), userIDKey, userInfo.Sub)
-				ctx = context.WithValue(ctx, userEmailKey, userInfo.Email)
-				ctx = context.WithValue(ctx, userInfoKey, userInfo)
-				r = r.WithContext(ctx)
+				r = r.WithContext(withUser(r.Context(), userInfo))
 			}
 		}
 		next(w, r)
 	}
 }
+
+// withUser stores the verified caller in ctx, including the identity
+// forwarded to downstream RPCs
+func withUser(ctx context.Context, userInfo *auth.UserInfo) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, userInfo.Sub)
+	ctx = context.WithValue(ctx, userEmailKey, userInfo.Email)
+	ctx = context.WithValue(ctx, userInfoKey, userInfo)
+	return interceptor.ContextWithCaller(ctx, interceptor.Caller{
+		UserID: userInfo.Sub,
+		Email:  userInfo.Email,
+		Roles:  userInfo.Roles,
+	})
+}
+
+// UserFromContext returns the authenticated caller stored by AuthMiddleware
+func UserFromContext(ctx context.Context) (*auth.UserInfo, bool) {
+	userInfo, ok := ctx.Value(userInfoKey).(*auth.UserInfo)
+	return userInfo, ok && userInfo != nil
+}
+
+// UserIDFromContext returns the authenticated caller's subject, or "" if unauthenticated
+func UserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
+
+// UserEmailFromContext returns the authenticated caller's email, or "" if unknown
+func UserEmailFromContext(ctx context.Context) string {
+	email, _ := ctx.Value(userEmailKey).(string)
+	return email
+}
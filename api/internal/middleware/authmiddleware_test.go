@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/api/internal/config"
+	"github.com/Nha1410/go-zero-template/api/internal/svc"
+	"github.com/Nha1410/go-zero-template/common/auth"
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func TestMain(m *testing.M) {
+	logx.Disable()
+	// The gateway installs handler.ErrorHandler, which imports this package
+	httpx.SetErrorHandlerCtx(func(ctx context.Context, err error) (int, any) {
+		e := errors.FromError(err)
+		return e.StatusCode, e.Response(RequestIDFromContext(ctx))
+	})
+	os.Exit(m.Run())
+}
+
+const testKid = "key-1"
+
+// newTestIssuer serves a discovery document and a JWKS with the public half
+// of the returned key
+func newTestIssuer(t *testing.T) (*httptest.Server, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   server.URL,
+			"jwks_uri": server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": testKid,
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, key
+}
+
+func newTestToken(t *testing.T, issuer string, key *rsa.PrivateKey, exp time.Time) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   issuer,
+		"aud":   "client-id",
+		"sub":   "user-1",
+		"email": "user@example.com",
+		"exp":   exp.Unix(),
+	})
+	token.Header["kid"] = testKid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newJWTServiceContext(t *testing.T, issuer string) *svc.ServiceContext {
+	t.Helper()
+	zitadel, err := auth.NewZitadelClient(auth.ZitadelConfig{
+		Issuer:         issuer,
+		ClientID:       "client-id",
+		ValidationMode: auth.ValidationModeJWT,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &svc.ServiceContext{Config: config.Config{}, Zitadel: zitadel}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	issuer, key := newTestIssuer(t)
+	m := NewAuthMiddleware(newJWTServiceContext(t, issuer.URL))
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantChallenge string
+	}{
+		{
+			name:          "missing token",
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: "Bearer",
+		},
+		{
+			name:          "malformed token",
+			authorization: "Bearer not-a-jwt",
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer error="invalid_token", error_description="Invalid or expired token"`,
+		},
+		{
+			name:          "expired token",
+			authorization: "Bearer " + newTestToken(t, issuer.URL, key, time.Now().Add(-time.Hour)),
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer error="invalid_token", error_description="Invalid or expired token"`,
+		},
+		{
+			name:          "valid token",
+			authorization: "Bearer " + newTestToken(t, issuer.URL, key, time.Now().Add(time.Hour)),
+			wantStatus:    http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var caller string
+			handler := m.Handle(func(w http.ResponseWriter, r *http.Request) {
+				caller = UserIDFromContext(r.Context())
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Fatalf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+			if tt.wantStatus != http.StatusOK {
+				var body errors.ErrorResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("decode body: %v", err)
+				}
+				if body.Code != errors.ErrUnauthorized.Code {
+					t.Fatalf("code = %q, want %q", body.Code, errors.ErrUnauthorized.Code)
+				}
+				return
+			}
+			if caller != "user-1" {
+				t.Fatalf("caller = %q, want user-1", caller)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareIdPUnavailable(t *testing.T) {
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer idp.Close()
+
+	zitadel, err := auth.NewZitadelClient(auth.ZitadelConfig{
+		Issuer:         idp.URL,
+		ClientID:       "client-id",
+		ClientSecret:   "client-secret",
+		ValidationMode: auth.ValidationModeIntrospection,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewAuthMiddleware(&svc.ServiceContext{Zitadel: zitadel})
+
+	called := false
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+	r.Header.Set("Authorization", "Bearer opaque-token")
+	w := httptest.NewRecorder()
+	m.Handle(func(w http.ResponseWriter, r *http.Request) { called = true })(w, r)
+
+	if called {
+		t.Fatal("handler called although the token could not be validated")
+	}
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got != "" {
+		t.Fatalf("WWW-Authenticate = %q, want none", got)
+	}
+}
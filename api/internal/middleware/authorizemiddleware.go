@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Nha1410/go-zero-template/api/internal/svc"
+	"github.com/Nha1410/go-zero-template/common/auth"
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/service/user/userclient"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest"
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"github.com/zeromicro/go-zero/rest/pathvar"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Well-known roles
+const (
+	RoleAdmin = "admin"
+)
+
+// OwnershipCheck reports whether the caller owns the resource addressed by the request
+type OwnershipCheck func(r *http.Request, user *auth.UserInfo) (bool, error)
+
+// Policy declares what a caller needs to access a route
+type Policy struct {
+	// AnyRole grants access when the caller has at least one of these roles
+	AnyRole []string
+	// AllScopes must all have been granted to the caller's token
+	AllScopes []string
+	// Owner grants access when the caller owns the target resource, even
+	// without one of AnyRole
+	Owner OwnershipCheck
+}
+
+type AuthorizeMiddleware struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewAuthorizeMiddleware(svcCtx *svc.ServiceContext) *AuthorizeMiddleware {
+	return &AuthorizeMiddleware{
+		svcCtx: svcCtx,
+	}
+}
+
+// Require returns a middleware enforcing policy. It must run after AuthMiddleware.Handle
+func (m *AuthorizeMiddleware) Require(policy Policy) rest.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			userInfo, ok := UserFromContext(r.Context())
+			if !ok {
+				httpx.ErrorCtx(r.Context(), w, errors.ErrUnauthorized)
+				return
+			}
+
+			if err := authorize(r, userInfo, policy); err != nil {
+				logx.WithContext(r.Context()).Infof("Access denied for %s on %s %s: %v", userInfo.Sub, r.Method, r.URL.Path, err)
+				httpx.ErrorCtx(r.Context(), w, err)
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// RequireRoles is a shorthand for a policy requiring any of roles
+func (m *AuthorizeMiddleware) RequireRoles(roles ...string) rest.Middleware {
+	return m.Require(Policy{AnyRole: roles})
+}
+
+// OwnsUser returns an OwnershipCheck that matches the caller's verified email
+// against the user addressed by the :id path parameter
+func (m *AuthorizeMiddleware) OwnsUser() OwnershipCheck {
+	return func(r *http.Request, user *auth.UserInfo) (bool, error) {
+		if user.Email == "" || !user.EmailVerified {
+			return false, nil
+		}
+
+		id, err := strconv.ParseInt(pathvar.Vars(r)["id"], 10, 64)
+		if err != nil {
+			return false, nil
+		}
+
+		target, err := m.svcCtx.UserRpc.GetUser(r.Context(), &userclient.GetUserReq{Id: id})
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to load user %d: %w", id, err)
+		}
+
+		return strings.EqualFold(target.Email, user.Email), nil
+	}
+}
+
+// authorize evaluates policy for the caller and returns an error describing a denial
+func authorize(r *http.Request, user *auth.UserInfo, policy Policy) error {
+	for _, scope := range policy.AllScopes {
+		if !contains(user.Scopes, scope) {
+			return errors.ErrForbidden.WithDetails(fmt.Sprintf("Missing required scope: %s", scope))
+		}
+	}
+
+	if len(policy.AnyRole) == 0 && policy.Owner == nil {
+		return nil
+	}
+
+	for _, role := range policy.AnyRole {
+		if contains(user.Roles, role) {
+			return nil
+		}
+	}
+
+	if policy.Owner != nil {
+		owns, err := policy.Owner(r, user)
+		if err != nil {
+			logx.WithContext(r.Context()).Errorf("Ownership check failed: %v", err)
+			return errors.ErrInternalError
+		}
+		if owns {
+			return nil
+		}
+	}
+
+	reason := fmt.Sprintf("Requires one of roles: %s", strings.Join(policy.AnyRole, ", "))
+	switch {
+	case len(policy.AnyRole) == 0:
+		reason = "Requires ownership of the resource"
+	case policy.Owner != nil:
+		reason += " or ownership of the resource"
+	}
+	return errors.ErrForbidden.WithDetails(reason)
+}
+
+// HasRole reports whether the authenticated caller has role, for checks that
+// depend on request parameters rather than the route
+func HasRole(ctx context.Context, role string) bool {
+	userInfo, ok := UserFromContext(ctx)
+	return ok && contains(userInfo.Roles, role)
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nha1410/go-zero-template/api/internal/svc"
+	"github.com/Nha1410/go-zero-template/common/auth"
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/service/user/userclient"
+	"github.com/zeromicro/go-zero/rest/pathvar"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeUserRpc serves GetUser from a map of emails by ID
+type fakeUserRpc struct {
+	userclient.User
+	emails map[int64]string
+}
+
+func (f *fakeUserRpc) GetUser(ctx context.Context, in *userclient.GetUserReq, opts ...grpc.CallOption) (*userclient.GetUserResp, error) {
+	email, ok := f.emails[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &userclient.GetUserResp{Id: in.Id, Email: email}, nil
+}
+
+func TestAuthorizeMiddleware(t *testing.T) {
+	authorize := NewAuthorizeMiddleware(&svc.ServiceContext{
+		UserRpc: &fakeUserRpc{emails: map[int64]string{
+			1: "owner@example.com",
+			2: "other@example.com",
+		}},
+	})
+	adminOnly := authorize.RequireRoles(RoleAdmin)
+	adminOrOwner := authorize.Require(Policy{
+		AnyRole: []string{RoleAdmin},
+		Owner:   authorize.OwnsUser(),
+	})
+	withScope := authorize.Require(Policy{AllScopes: []string{"users:write"}})
+
+	admin := &auth.UserInfo{Sub: "admin-1", Email: "admin@example.com", EmailVerified: true, Roles: []string{RoleAdmin}}
+	owner := &auth.UserInfo{Sub: "user-1", Email: "Owner@example.com", EmailVerified: true}
+	unverified := &auth.UserInfo{Sub: "user-1", Email: "owner@example.com"}
+
+	tests := []struct {
+		name       string
+		middleware func(http.HandlerFunc) http.HandlerFunc
+		user       *auth.UserInfo
+		id         string
+		wantStatus int
+		wantDetail string
+	}{
+		{
+			name:       "admin on admin route",
+			middleware: adminOnly,
+			user:       admin,
+			id:         "2",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "admin on another user",
+			middleware: adminOrOwner,
+			user:       admin,
+			id:         "2",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "owner",
+			middleware: adminOrOwner,
+			user:       owner,
+			id:         "1",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "non-owner",
+			middleware: adminOrOwner,
+			user:       owner,
+			id:         "2",
+			wantStatus: http.StatusForbidden,
+			wantDetail: "Requires one of roles: admin or ownership of the resource",
+		},
+		{
+			name:       "owner with unverified email",
+			middleware: adminOrOwner,
+			user:       unverified,
+			id:         "1",
+			wantStatus: http.StatusForbidden,
+			wantDetail: "Requires one of roles: admin or ownership of the resource",
+		},
+		{
+			name:       "owner of a missing user",
+			middleware: adminOrOwner,
+			user:       owner,
+			id:         "3",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "non-admin on admin route",
+			middleware: adminOnly,
+			user:       owner,
+			id:         "1",
+			wantStatus: http.StatusForbidden,
+			wantDetail: "Requires one of roles: admin",
+		},
+		{
+			name:       "missing scope",
+			middleware: withScope,
+			user:       admin,
+			wantStatus: http.StatusForbidden,
+			wantDetail: "Missing required scope: users:write",
+		},
+		{
+			name:       "granted scope",
+			middleware: withScope,
+			user:       &auth.UserInfo{Sub: "client-1", Scopes: []string{"openid", "users:write"}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing claims",
+			middleware: adminOrOwner,
+			id:         "1",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := tt.middleware(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+tt.id, nil)
+			r = pathvar.WithVars(r, map[string]string{"id": tt.id})
+			if tt.user != nil {
+				r = r.WithContext(withUser(r.Context(), tt.user))
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if called != (tt.wantStatus == http.StatusOK) {
+				t.Fatalf("handler called = %v, want %v", called, tt.wantStatus == http.StatusOK)
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+
+			var body errors.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if tt.wantStatus == http.StatusForbidden && body.Code != errors.ErrForbidden.Code {
+				t.Fatalf("code = %q, want %q", body.Code, errors.ErrForbidden.Code)
+			}
+			if tt.wantDetail != "" && body.Details != tt.wantDetail {
+				t.Fatalf("details = %q, want %q", body.Details, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestAuthorizeOwnershipCheckFailure(t *testing.T) {
+	authorize := NewAuthorizeMiddleware(&svc.ServiceContext{UserRpc: &failingUserRpc{}})
+	handler := authorize.Require(Policy{Owner: authorize.OwnsUser()})(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler called although ownership could not be checked")
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+	r = pathvar.WithVars(r, map[string]string{"id": "1"})
+	r = r.WithContext(withUser(r.Context(), &auth.UserInfo{Sub: "user-1", Email: "owner@example.com", EmailVerified: true}))
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+type failingUserRpc struct {
+	userclient.User
+}
+
+func (f *failingUserRpc) GetUser(ctx context.Context, in *userclient.GetUserReq, opts ...grpc.CallOption) (*userclient.GetUserResp, error) {
+	return nil, status.Error(codes.Unavailable, "connection refused")
+}
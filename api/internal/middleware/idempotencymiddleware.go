@@ -0,0 +1,274 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/api/internal/svc"
+	"github.com/Nha1410/go-zero-template/common/cache"
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	idempotencyKeyPrefix      = "idempotency"
+	idempotencyMaxKeyLength   = 255
+	idempotencyPollInterval   = 50 * time.Millisecond
+)
+
+var (
+	errIdempotencyKeyReused = errors.NewError(http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED",
+		"Idempotency-Key was already used with a different request")
+	errIdempotencyInProgress = errors.NewError(http.StatusConflict, "IDEMPOTENCY_IN_PROGRESS",
+		"A request with this Idempotency-Key is still in progress")
+)
+
+// replayedHeaders are the response headers stored with a record. Headers
+// describing the request itself, such as X-Request-ID and RateLimit-*, are
+// set again by the middleware in front of this one
+var replayedHeaders = []string{
+	"Content-Type",
+	"Content-Language",
+	"ETag",
+	"Last-Modified",
+	"Location",
+}
+
+// idempotencyRecord is the stored outcome of a request
+type idempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	StatusCode  int         `json:"status_code"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+type IdempotencyMiddleware struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewIdempotencyMiddleware(svcCtx *svc.ServiceContext) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		svcCtx: svcCtx,
+	}
+}
+
+// Handle replays the stored response for a repeated Idempotency-Key on
+// mutating requests. It should run after authentication, so keys are scoped
+// to the caller, and after rate limiting and authorization, so replays are
+// limited and denied like any other request
+func (m *IdempotencyMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(idempotencyKeyHeader)
+		if !m.svcCtx.Config.Idempotency.Enabled || idempotencyKey == "" || !isMutating(r.Method) {
+			next(w, r)
+			return
+		}
+		if len(idempotencyKey) > idempotencyMaxKeyLength {
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails("Idempotency-Key is too long"))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, errors.ErrBadRequest.WithDetails("Failed to read request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(r, body)
+		key := idempotencyKeyPrefix + ":" + callerKey(r, m.svcCtx.Config.RateLimit.TrustedProxies) + ":" + idempotencyKey
+		logger := logx.WithContext(r.Context())
+
+		if record, err := m.load(key); err == nil {
+			replay(w, r, record, fingerprint)
+			return
+		} else if !stderrors.Is(err, cache.ErrKeyNotFound) {
+			logger.Errorf("Idempotency store unavailable, processing request: %v", err)
+			next(w, r)
+			return
+		}
+
+		lockKey := key + ":lock"
+		token, err := lockToken()
+		if err != nil {
+			logger.Errorf("Failed to generate idempotency lock token: %v", err)
+			next(w, r)
+			return
+		}
+
+		acquired, err := m.svcCtx.Redis.SetNX(lockKey, token, m.svcCtx.Config.Idempotency.LockTTL)
+		if err != nil {
+			logger.Errorf("Idempotency store unavailable, processing request: %v", err)
+			next(w, r)
+			return
+		}
+		if !acquired {
+			// A concurrent duplicate holds the lock; wait for its response
+			if record, ok := m.waitForRecord(r, key); ok {
+				replay(w, r, record, fingerprint)
+				return
+			}
+			httpx.ErrorCtx(r.Context(), w, errIdempotencyInProgress)
+			return
+		}
+		defer func() {
+			if _, err := m.svcCtx.Redis.DeleteIfEquals(lockKey, token); err != nil {
+				logger.Errorf("Failed to release idempotency lock: %v", err)
+			}
+		}()
+
+		// The first request may have finished between the lookup and the lock
+		if record, err := m.load(key); err == nil {
+			replay(w, r, record, fingerprint)
+			return
+		}
+
+		rec := newResponseRecorder(w)
+		next(rec, r)
+
+		if !storableStatus(rec.statusCode) {
+			return
+		}
+
+		record := idempotencyRecord{
+			Fingerprint: fingerprint,
+			StatusCode:  rec.statusCode,
+			Header:      make(http.Header),
+			Body:        rec.body.Bytes(),
+		}
+		for _, name := range replayedHeaders {
+			if values := rec.Header().Values(name); len(values) > 0 {
+				record.Header[name] = values
+			}
+		}
+		if err := m.svcCtx.Redis.SetJSON(key, record, m.svcCtx.Config.Idempotency.TTL); err != nil {
+			logger.Errorf("Failed to store idempotent response: %v", err)
+		}
+	}
+}
+
+func (m *IdempotencyMiddleware) load(key string) (*idempotencyRecord, error) {
+	var record idempotencyRecord
+	if err := m.svcCtx.Redis.GetJSON(key, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// waitForRecord polls for the record stored by a concurrent request until the lock TTL elapses
+func (m *IdempotencyMiddleware) waitForRecord(r *http.Request, key string) (*idempotencyRecord, bool) {
+	deadline := time.Now().Add(m.svcCtx.Config.Idempotency.LockTTL)
+	ticker := time.NewTicker(idempotencyPollInterval)
+	defer ticker.Stop()
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-r.Context().Done():
+			return nil, false
+		case <-ticker.C:
+		}
+
+		if record, err := m.load(key); err == nil {
+			return record, true
+		}
+	}
+	return nil, false
+}
+
+// replay writes a stored response, rejecting it if the request differs
+func replay(w http.ResponseWriter, r *http.Request, record *idempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		httpx.ErrorCtx(r.Context(), w, errIdempotencyKeyReused)
+		return
+	}
+
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(idempotencyReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	_, _ = w.Write(record.Body)
+}
+
+// storableStatus reports whether a response is stored for replay. Only
+// successes and client errors a retry would get again are stored; server
+// errors, timeouts, conflicts and rate limiting may go away on a retry
+func storableStatus(statusCode int) bool {
+	switch {
+	case statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices:
+		return true
+	case statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError:
+		switch statusCode {
+		case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooEarly, http.StatusTooManyRequests:
+			return false
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// requestFingerprint identifies the request a key was first used with. It
+// covers If-Match, since a response is only valid for the precondition it
+// was evaluated against
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write([]byte(r.Header.Get("If-Match")))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func lockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// responseRecorder captures the response while passing it through to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK,
+	}
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
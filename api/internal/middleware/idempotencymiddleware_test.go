@@ -0,0 +1,295 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/api/internal/config"
+	"github.com/Nha1410/go-zero-template/api/internal/svc"
+	"github.com/Nha1410/go-zero-template/common/auth"
+	"github.com/Nha1410/go-zero-template/common/cache"
+	"github.com/Nha1410/go-zero-template/common/errors"
+)
+
+func newIdempotencyMiddleware(redis *cache.RedisClient, lockTTL time.Duration) *IdempotencyMiddleware {
+	var c config.Config
+	c.Idempotency.Enabled = true
+	c.Idempotency.TTL = time.Hour
+	c.Idempotency.LockTTL = lockTTL
+	return NewIdempotencyMiddleware(&svc.ServiceContext{Config: c, Redis: redis})
+}
+
+func idempotentRequest(key, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(body))
+	r.Header.Set(idempotencyKeyHeader, key)
+	return r.WithContext(withUser(r.Context(), &auth.UserInfo{Sub: "user-1"}))
+}
+
+// countingHandler creates a user once per call and answers with the call number
+func countingHandler(calls *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/v1/users/1")
+		w.Header().Set("X-Request-ID", fmt.Sprintf("request-%d", n))
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"call": n, "body": string(body)})
+	}
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	redis, _ := newTestRedis(t)
+	m := newIdempotencyMiddleware(redis, time.Second)
+	var calls int32
+	handler := m.Handle(countingHandler(&calls))
+
+	first := httptest.NewRecorder()
+	handler(first, idempotentRequest("key-1", `{"email":"a@example.com"}`))
+	if first.Code != http.StatusCreated || first.Header().Get(idempotencyReplayedHeader) != "" {
+		t.Fatalf("first = %d with headers %v, want 201 not replayed", first.Code, first.Header())
+	}
+
+	second := httptest.NewRecorder()
+	handler(second, idempotentRequest("key-1", `{"email":"a@example.com"}`))
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("handler called %d times, want 1", got)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Fatalf("replay = %d %q, want 201 %q", second.Code, second.Body.String(), first.Body.String())
+	}
+	if second.Header().Get(idempotencyReplayedHeader) != "true" {
+		t.Fatalf("%s = %q, want true", idempotencyReplayedHeader, second.Header().Get(idempotencyReplayedHeader))
+	}
+	if second.Header().Get("Location") != "/api/v1/users/1" || second.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("replayed headers = %v, want Location and Content-Type", second.Header())
+	}
+	// Per-request headers are not replayed
+	if got := second.Header().Get("X-Request-ID"); got != "" {
+		t.Fatalf("X-Request-ID = %q, want none", got)
+	}
+
+	// Keys are scoped to the caller
+	other := idempotentRequest("key-1", `{"email":"a@example.com"}`)
+	other = other.WithContext(withUser(other.Context(), &auth.UserInfo{Sub: "user-2"}))
+	handler(httptest.NewRecorder(), other)
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("handler called %d times, want 2 for another caller", got)
+	}
+}
+
+func TestIdempotencyKeyReusedWithDifferentRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		ifMatch string
+	}{
+		{name: "body", body: `{"email":"b@example.com"}`, ifMatch: `"3"`},
+		{name: "precondition", body: `{"email":"a@example.com"}`, ifMatch: `"4"`},
+		{name: "no precondition", body: `{"email":"a@example.com"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redis, _ := newTestRedis(t)
+			m := newIdempotencyMiddleware(redis, time.Second)
+			var calls int32
+			handler := m.Handle(countingHandler(&calls))
+
+			first := idempotentRequest("key-1", `{"email":"a@example.com"}`)
+			first.Header.Set("If-Match", `"3"`)
+			handler(httptest.NewRecorder(), first)
+			reused := idempotentRequest("key-1", tt.body)
+			if tt.ifMatch != "" {
+				reused.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			handler(w, reused)
+
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+			}
+			var body errors.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.Code != errIdempotencyKeyReused.Code {
+				t.Fatalf("code = %q, want %q", body.Code, errIdempotencyKeyReused.Code)
+			}
+			if got := atomic.LoadInt32(&calls); got != 1 {
+				t.Fatalf("handler called %d times, want 1", got)
+			}
+		})
+	}
+}
+
+func TestIdempotencyConcurrentDuplicate(t *testing.T) {
+	redis, _ := newTestRedis(t)
+	m := newIdempotencyMiddleware(redis, 200*time.Millisecond)
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := m.Handle(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		handler(w, idempotentRequest("key-1", `{}`))
+		done <- w
+	}()
+	<-started
+
+	// The duplicate waits for the lock TTL and gives up
+	w := httptest.NewRecorder()
+	handler(w, idempotentRequest("key-1", `{}`))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("duplicate status = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want 201", first.Code)
+	}
+
+	// The 409 was not stored, so a retry replays the first response
+	w = httptest.NewRecorder()
+	handler(w, idempotentRequest("key-1", `{}`))
+	if w.Code != http.StatusCreated || w.Header().Get(idempotencyReplayedHeader) != "true" {
+		t.Fatalf("retry = %d replayed %q, want replayed 201", w.Code, w.Header().Get(idempotencyReplayedHeader))
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("handler called %d times, want 1", got)
+	}
+}
+
+func TestIdempotencyConcurrentDuplicateWaitsForResponse(t *testing.T) {
+	redis, _ := newTestRedis(t)
+	m := newIdempotencyMiddleware(redis, 5*time.Second)
+	var calls int32
+	started := make(chan struct{})
+	handler := m.Handle(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler(httptest.NewRecorder(), idempotentRequest("key-1", `{}`))
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	handler(w, idempotentRequest("key-1", `{}`))
+	if w.Code != http.StatusCreated || w.Header().Get(idempotencyReplayedHeader) != "true" {
+		t.Fatalf("duplicate = %d replayed %q, want replayed 201", w.Code, w.Header().Get(idempotencyReplayedHeader))
+	}
+	<-done
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("handler called %d times, want 1", got)
+	}
+}
+
+func TestIdempotencyRecordExpires(t *testing.T) {
+	redis, mr := newTestRedis(t)
+	m := newIdempotencyMiddleware(redis, time.Second)
+	var calls int32
+	handler := m.Handle(countingHandler(&calls))
+
+	handler(httptest.NewRecorder(), idempotentRequest("key-1", `{}`))
+	mr.FastForward(time.Hour)
+
+	w := httptest.NewRecorder()
+	handler(w, idempotentRequest("key-1", `{}`))
+	if w.Header().Get(idempotencyReplayedHeader) != "" {
+		t.Fatal("response replayed after the record expired")
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("handler called %d times, want 2", got)
+	}
+}
+
+func TestIdempotencyStoredStatuses(t *testing.T) {
+	tests := []struct {
+		status     int
+		wantStored bool
+	}{
+		{status: http.StatusOK, wantStored: true},
+		{status: http.StatusNoContent, wantStored: true},
+		{status: http.StatusBadRequest, wantStored: true},
+		{status: http.StatusNotFound, wantStored: true},
+		{status: http.StatusRequestTimeout},
+		{status: http.StatusConflict},
+		{status: http.StatusTooManyRequests},
+		{status: http.StatusInternalServerError},
+		{status: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			redis, _ := newTestRedis(t)
+			m := newIdempotencyMiddleware(redis, time.Second)
+			var calls int32
+			handler := m.Handle(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(tt.status)
+			})
+
+			for i := 0; i < 2; i++ {
+				w := httptest.NewRecorder()
+				handler(w, idempotentRequest("key-1", `{}`))
+				if w.Code != tt.status {
+					t.Fatalf("request %d status = %d, want %d", i+1, w.Code, tt.status)
+				}
+			}
+
+			wantCalls := int32(2)
+			if tt.wantStored {
+				wantCalls = 1
+			}
+			if got := atomic.LoadInt32(&calls); got != wantCalls {
+				t.Fatalf("handler called %d times, want %d", got, wantCalls)
+			}
+		})
+	}
+}
+
+func TestIdempotencyRateLimitedBeforeReplay(t *testing.T) {
+	redis, _ := newTestRedis(t)
+	limiter := NewRateLimitMiddleware(newRateLimitServiceContext(redis))
+	m := newIdempotencyMiddleware(redis, time.Second)
+	var calls int32
+	// Rate limiting runs in front of idempotency, as in the routes
+	handler := limiter.Handle(http.MethodPost, "/api/v1/users")(m.Handle(countingHandler(&calls)))
+
+	w := httptest.NewRecorder()
+	handler(w, idempotentRequest("key-1", `{}`))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want 201", w.Code)
+	}
+
+	// A replay still counts against the caller's limit
+	w = httptest.NewRecorder()
+	handler(w, idempotentRequest("key-1", `{}`))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("handler called %d times, want 1", got)
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+const problemJSONKey = contextKey("problem_json")
+
+type ProblemJSONMiddleware struct {
+	enabled bool
+}
+
+// NewProblemJSONMiddleware renders problem details for every request when
+// enabled, and otherwise only for requests that accept them
+func NewProblemJSONMiddleware(enabled bool) *ProblemJSONMiddleware {
+	return &ProblemJSONMiddleware{
+		enabled: enabled,
+	}
+}
+
+// Handle marks the request for problem details and labels JSON error
+// responses as application/problem+json. httpx always writes
+// application/json, so the header is rewritten when the status is sent
+func (m *ProblemJSONMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !m.enabled && !acceptsProblemJSON(r.Header.Get("Accept")) {
+			next(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), problemJSONKey, true)
+		next(&problemJSONWriter{ResponseWriter: w}, r.WithContext(ctx))
+	}
+}
+
+// ProblemJSONFromContext reports whether errors for the request are rendered
+// as problem details
+func ProblemJSONFromContext(ctx context.Context) bool {
+	problem, _ := ctx.Value(problemJSONKey).(bool)
+	return problem
+}
+
+// acceptsProblemJSON reports whether an Accept header names
+// application/problem+json with a non-zero quality
+func acceptsProblemJSON(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), errors.ProblemContentType) {
+			continue
+		}
+
+		accepted := true
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") && strings.Trim(strings.TrimSpace(value), "0.") == "" {
+				accepted = false
+			}
+		}
+		if accepted {
+			return true
+		}
+	}
+	return false
+}
+
+type problemJSONWriter struct {
+	http.ResponseWriter
+}
+
+func (w *problemJSONWriter) WriteHeader(statusCode int) {
+	if statusCode >= http.StatusBadRequest &&
+		strings.HasPrefix(w.Header().Get(httpx.ContentType), "application/json") {
+		w.Header().Set(httpx.ContentType, errors.ProblemContentType)
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func TestAcceptsProblemJSON(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "*/*", want: false},
+		{accept: "application/json", want: false},
+		{accept: "application/problem+json", want: true},
+		{accept: "Application/Problem+JSON", want: true},
+		{accept: "application/json, application/problem+json;q=0.9", want: true},
+		{accept: "application/problem+json; q=1.0", want: true},
+		{accept: "application/problem+json;q=0", want: false},
+		{accept: "application/problem+json;q=0.000, application/json", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := acceptsProblemJSON(tt.accept); got != tt.want {
+			t.Errorf("acceptsProblemJSON(%q) = %v, want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestProblemJSONMiddleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		enabled         bool
+		accept          string
+		status          int
+		contentType     string
+		wantProblem     bool
+		wantContentType string
+	}{
+		{
+			name:            "disabled",
+			status:          http.StatusNotFound,
+			contentType:     "application/json; charset=utf-8",
+			wantContentType: "application/json; charset=utf-8",
+		},
+		{
+			name:            "requested with Accept",
+			accept:          errors.ProblemContentType,
+			status:          http.StatusNotFound,
+			contentType:     "application/json; charset=utf-8",
+			wantProblem:     true,
+			wantContentType: errors.ProblemContentType,
+		},
+		{
+			name:            "enabled",
+			enabled:         true,
+			accept:          "application/json",
+			status:          http.StatusBadRequest,
+			contentType:     "application/json; charset=utf-8",
+			wantProblem:     true,
+			wantContentType: errors.ProblemContentType,
+		},
+		{
+			name:            "success is left alone",
+			enabled:         true,
+			status:          http.StatusOK,
+			contentType:     "application/json; charset=utf-8",
+			wantProblem:     true,
+			wantContentType: "application/json; charset=utf-8",
+		},
+		{
+			name:            "non-JSON error is left alone",
+			enabled:         true,
+			status:          http.StatusBadGateway,
+			contentType:     "text/plain",
+			wantProblem:     true,
+			wantContentType: "text/plain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problem bool
+			handler := NewProblemJSONMiddleware(tt.enabled).Handle(func(w http.ResponseWriter, r *http.Request) {
+				problem = ProblemJSONFromContext(r.Context())
+				w.Header().Set(httpx.ContentType, tt.contentType)
+				w.WriteHeader(tt.status)
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if problem != tt.wantProblem {
+				t.Fatalf("ProblemJSONFromContext() = %v, want %v", problem, tt.wantProblem)
+			}
+			if got := w.Header().Get(httpx.ContentType); got != tt.wantContentType {
+				t.Fatalf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+		})
+	}
+}
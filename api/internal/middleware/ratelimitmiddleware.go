@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/api/internal/config"
+	"github.com/Nha1410/go-zero-template/api/internal/svc"
+	"github.com/Nha1410/go-zero-template/common/cache"
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+const rateLimitKeyPrefix = "ratelimit"
+
+type RateLimitMiddleware struct {
+	svcCtx  *svc.ServiceContext
+	limiter *cache.RateLimiter
+}
+
+func NewRateLimitMiddleware(svcCtx *svc.ServiceContext) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		svcCtx:  svcCtx,
+		limiter: cache.NewRateLimiter(svcCtx.Redis, rateLimitKeyPrefix),
+	}
+}
+
+// Handle returns a middleware limiting the route registered as method and path.
+// Callers are identified by authenticated subject, then client IP, so it should
+// run after AuthMiddleware.Handle
+func (m *RateLimitMiddleware) Handle(method, path string) rest.Middleware {
+	conf := m.svcCtx.Config.RateLimit
+	route := method + " " + path
+	rule, ok := conf.Routes[route]
+	if !ok {
+		rule = conf.Default
+	}
+	return m.limit(rule, func(r *http.Request) string {
+		return route + ":" + callerKey(r, conf.TrustedProxies)
+	})
+}
+
+// HandleClient limits requests per client IP across all routes it wraps. It
+// runs before AuthMiddleware.Handle so requests without a valid token are
+// limited too
+func (m *RateLimitMiddleware) HandleClient(next http.HandlerFunc) http.HandlerFunc {
+	return m.limit(m.svcCtx.Config.RateLimit.Client, func(r *http.Request) string {
+		return "client:" + clientKey(r, m.svcCtx.Config.RateLimit.TrustedProxies)
+	})(next)
+}
+
+// limit returns a middleware allowing rule.Limit requests per rule.Window for
+// each key returned by keyOf
+func (m *RateLimitMiddleware) limit(rule config.RateLimitRule, keyOf func(r *http.Request) string) rest.Middleware {
+	conf := m.svcCtx.Config.RateLimit
+	limit := cache.RateLimit{
+		Algorithm: conf.Algorithm,
+		Limit:     rule.Limit,
+		Window:    rule.Window,
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		if !conf.Enabled || rule.Limit <= 0 {
+			return next
+		}
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			result, err := m.limiter.Allow(r.Context(), keyOf(r), limit)
+			if err != nil {
+				// Fail open so a Redis outage does not take the gateway down
+				logx.WithContext(r.Context()).Errorf("Rate limiter unavailable, allowing request: %v", err)
+				next(w, r)
+				return
+			}
+
+			setRateLimitHeaders(w, result)
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				httpx.ErrorCtx(r.Context(), w, errors.ErrTooManyRequests.WithDetails(
+					fmt.Sprintf("Rate limit of %d requests per %s exceeded", rule.Limit, rule.Window)))
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// Wrap applies the rate limit for each route to its handler
+func (m *RateLimitMiddleware) Wrap(routes []rest.Route) []rest.Route {
+	wrapped := make([]rest.Route, len(routes))
+	for i, route := range routes {
+		route.Handler = m.Handle(route.Method, route.Path)(route.Handler)
+		wrapped[i] = route
+	}
+	return wrapped
+}
+
+// callerKey identifies the caller for rate limiting, by authenticated subject
+// when there is one
+func callerKey(r *http.Request, trusted []netip.Prefix) string {
+	if sub := UserIDFromContext(r.Context()); sub != "" {
+		return "sub:" + sub
+	}
+	return clientKey(r, trusted)
+}
+
+// clientKey identifies the client by IP. Headers the client controls, such as
+// API keys, are not used before they have been validated
+func clientKey(r *http.Request, trusted []netip.Prefix) string {
+	return "ip:" + clientIP(r, trusted)
+}
+
+// clientIP returns the address of the connection or, when that is a trusted
+// proxy, the right-most X-Forwarded-For hop that is not. Hops left of it were
+// written by the client and cannot be believed
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	// RemoteAddr includes the port, which differs between connections
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(addr, trusted) {
+		return host
+	}
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// A malformed hop ends the chain we can believe
+			break
+		}
+		addr = hop.Unmap()
+		if !isTrustedProxy(addr, trusted) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func setRateLimitHeaders(w http.ResponseWriter, result *cache.RateLimitResult) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/api/internal/config"
+	"github.com/Nha1410/go-zero-template/api/internal/svc"
+	"github.com/Nha1410/go-zero-template/common/auth"
+	"github.com/Nha1410/go-zero-template/common/cache"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedis(t *testing.T) (*cache.RedisClient, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := cache.NewRedisClient(cache.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client, mr
+}
+
+func newRateLimitServiceContext(redis *cache.RedisClient) *svc.ServiceContext {
+	return &svc.ServiceContext{
+		Config: config.Config{RateLimit: config.RateLimitConfig{
+			Enabled:   true,
+			Algorithm: cache.AlgorithmFixedWindow,
+			Client:    config.RateLimitRule{Limit: 2, Window: time.Minute},
+			Default:   config.RateLimitRule{Limit: 1, Window: time.Minute},
+		}},
+		Redis: redis,
+	}
+}
+
+func TestRateLimitHandleClient(t *testing.T) {
+	redis, _ := newTestRedis(t)
+	m := NewRateLimitMiddleware(newRateLimitServiceContext(redis))
+	handler := m.HandleClient(func(w http.ResponseWriter, r *http.Request) {})
+
+	request := func(remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		r.RemoteAddr = remoteAddr
+		for name, values := range header {
+			r.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	// Unauthenticated requests are limited per IP, whatever the port
+	for i, wantRemaining := range []string{"1", "0"} {
+		w := request("192.0.2.1:"+strconv.Itoa(1000+i), nil)
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != wantRemaining {
+			t.Fatalf("request %d = %d with RateLimit-Remaining %q, want 200 with %s",
+				i+1, w.Code, w.Header().Get("RateLimit-Remaining"), wantRemaining)
+		}
+	}
+	w := request("192.0.2.1:5678", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") != "60" || w.Header().Get("RateLimit-Limit") != "2" {
+		t.Fatalf("headers = %v, want Retry-After 60 and RateLimit-Limit 2", w.Header())
+	}
+
+	// Headers the client controls do not reset its limit
+	for _, header := range []http.Header{
+		{"X-Forwarded-For": {"198.51.100.1"}},
+		{"X-Forwarded-For": {"198.51.100.2"}},
+		{"X-Real-Ip": {"198.51.100.3"}},
+		{"X-Api-Key": {"key-1"}},
+		{"X-Api-Key": {"key-2"}},
+	} {
+		if w := request("192.0.2.1:1234", header); w.Code != http.StatusTooManyRequests {
+			t.Fatalf("status with %v = %d, want %d", header, w.Code, http.StatusTooManyRequests)
+		}
+	}
+
+	// Other IPs have their own limits
+	if w := request("192.0.2.2:1234", nil); w.Code != http.StatusOK {
+		t.Fatalf("other IP status = %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitHandleClientBehindProxy(t *testing.T) {
+	redis, _ := newTestRedis(t)
+	svcCtx := newRateLimitServiceContext(redis)
+	svcCtx.Config.RateLimit.TrustedProxies = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	handler := NewRateLimitMiddleware(svcCtx).HandleClient(func(w http.ResponseWriter, r *http.Request) {})
+
+	request := func(forwardedFor string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	// The client the proxy saw is limited, whatever it prepends to the header
+	for i, forwardedFor := range []string{"192.0.2.1", "198.51.100.1, 192.0.2.1", "198.51.100.2, 192.0.2.1, 10.0.0.2"} {
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if got := request(forwardedFor); got != want {
+			t.Fatalf("status with X-Forwarded-For %q = %d, want %d", forwardedFor, got, want)
+		}
+	}
+	if got := request("192.0.2.2"); got != http.StatusOK {
+		t.Fatalf("other client status = %d, want 200", got)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/32")}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "direct", remoteAddr: "192.0.2.1:1234", want: "192.0.2.1"},
+		{name: "untrusted forwarder", remoteAddr: "192.0.2.1:1234", forwardedFor: []string{"198.51.100.1"}, want: "192.0.2.1"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "spoofed hops", remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"203.0.113.9, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "proxy chain", remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"198.51.100.1, 10.0.0.2"}, want: "198.51.100.1"},
+		{name: "repeated headers", remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"203.0.113.9", "198.51.100.1"}, want: "198.51.100.1"},
+		{name: "malformed hop", remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"198.51.100.1, junk, 10.0.0.2"}, want: "10.0.0.2"},
+		{name: "only proxies", remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"10.0.0.3, 10.0.0.2"}, want: "10.0.0.3"},
+		{name: "no header", remoteAddr: "10.0.0.1:1234", want: "10.0.0.1"},
+		{name: "IPv6", remoteAddr: "[2001:db8::1]:1234", forwardedFor: []string{"2001:db8:1::1, 2001:db9::1"}, want: "2001:db9::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIP(r, trusted); got != tt.want {
+				t.Fatalf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitHandlePerCaller(t *testing.T) {
+	redis, _ := newTestRedis(t)
+	m := NewRateLimitMiddleware(newRateLimitServiceContext(redis))
+	handler := m.Handle(http.MethodGet, "/api/v1/users")(func(w http.ResponseWriter, r *http.Request) {})
+
+	request := func(sub string) int {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r = r.WithContext(withUser(r.Context(), &auth.UserInfo{Sub: sub}))
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+
+	// Callers behind one IP are limited by subject
+	if got := request("user-1"); got != http.StatusOK {
+		t.Fatalf("user-1 status = %d, want 200", got)
+	}
+	if got := request("user-2"); got != http.StatusOK {
+		t.Fatalf("user-2 status = %d, want 200", got)
+	}
+	if got := request("user-1"); got != http.StatusTooManyRequests {
+		t.Fatalf("second user-1 status = %d, want 429", got)
+	}
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	redis, mr := newTestRedis(t)
+	m := NewRateLimitMiddleware(newRateLimitServiceContext(redis))
+	mr.Close()
+
+	called := 0
+	handler := m.HandleClient(func(w http.ResponseWriter, r *http.Request) { called++ })
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/api/v1/users", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200 while Redis is down", w.Code)
+		}
+	}
+	if called != 3 {
+		t.Fatalf("handler called %d times, want 3", called)
+	}
+}
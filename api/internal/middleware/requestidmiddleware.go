@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/Nha1410/go-zero-template/common/interceptor"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	requestIDKey       = contextKey("request_id")
+	maxRequestIDLength = 128
+)
+
+type RequestIDMiddleware struct{}
+
+func NewRequestIDMiddleware() *RequestIDMiddleware {
+	return &RequestIDMiddleware{}
+}
+
+// Handle propagates the caller's X-Request-ID or generates one, exposing it
+// in the context, the logs and the response headers
+func (m *RequestIDMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+		ctx = interceptor.ContextWithRequestID(ctx, requestID)
+		ctx = logx.ContextWithFields(ctx, logx.Field("request_id", requestID))
+		w.Header().Set(RequestIDHeader, requestID)
+
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// RequestIDFromContext returns the request ID set by RequestIDMiddleware
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// validRequestID accepts short printable ASCII IDs so headers cannot inject content
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,60 +1,194 @@
 package svc
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/Nha1410/go-zero-template/api/internal/config"
 	"github.com/Nha1410/go-zero-template/common/auth"
 	"github.com/Nha1410/go-zero-template/common/cache"
 	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/common/health"
+	"github.com/Nha1410/go-zero-template/common/interceptor"
+	"github.com/Nha1410/go-zero-template/common/lifecycle"
 	"github.com/Nha1410/go-zero-template/common/queue"
+	"github.com/Nha1410/go-zero-template/service/user/userclient"
 	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/zrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// disabledUserRpcTarget is never dialed; calls are rejected before connecting
+const disabledUserRpcTarget = "passthrough:///user-rpc-disabled"
+
 type ServiceContext struct {
-	Config   config.Config
-	DB       *sql.DB
+	Config config.Config
+	// DB is nil when the database dependency is disabled
+	DB *sql.DB
+	// Redis and RabbitMQ return ErrUnavailable while disconnected or disabled
 	Redis    *cache.RedisClient
 	RabbitMQ *queue.RabbitMQClient
-	Zitadel *auth.ZitadelClient
+	Zitadel  *auth.ZitadelClient
+	UserRpc  userclient.User
+	Health   *health.Checker
+
+	connector *lifecycle.Connector
+	closers   lifecycle.Closers
 }
 
-func NewServiceContext(c config.Config) *ServiceContext {
-	db, err := database.NewPostgresConnection(c.Database.Postgres)
-	if err != nil {
-		logx.Errorf("Failed to connect to database: %v", err)
-		panic(err)
+// NewServiceContext connects to every enabled dependency. Required ones are
+// retried with backoff until c.Startup.Timeout; optional ones that are down
+// leave the gateway degraded and reconnect in the background. On failure the
+// connections already opened are closed
+func NewServiceContext(c config.Config) (*ServiceContext, error) {
+	if c.RateLimit.Enabled {
+		if err := cache.ValidateRateLimitAlgorithm(c.RateLimit.Algorithm); err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_ALGORITHM: %w", err)
+		}
+	}
+
+	ctx, cancel := c.Startup.Context()
+	defer cancel()
+
+	svcCtx := &ServiceContext{
+		Config:    c,
+		Redis:     &cache.RedisClient{},
+		RabbitMQ:  &queue.RabbitMQClient{},
+		Health:    health.NewChecker(c.HealthCheck.Timeout, c.HealthCheck.CacheTTL),
+		connector: lifecycle.NewConnector(c.Startup),
+	}
+
+	if err := svcCtx.connectDatabase(ctx); err != nil {
+		return nil, svcCtx.closeOnError(err)
 	}
 
-	redisClient, err := cache.NewRedisClient(c.Redis)
+	enabled, err := svcCtx.connector.Connect(ctx, c.Dependencies.Redis, "Redis", func() error {
+		return svcCtx.Redis.Connect(c.Redis)
+	})
 	if err != nil {
-		logx.Errorf("Failed to connect to Redis: %v", err)
-		panic(err)
+		return nil, svcCtx.closeOnError(err)
+	}
+	if enabled {
+		svcCtx.closers.Add("Redis", svcCtx.Redis.Close)
+		lifecycle.RegisterCheck(svcCtx.Health, c.Dependencies.Redis, "redis", health.PingCheck(svcCtx.Redis))
 	}
 
-	rabbitmqClient, err := queue.NewRabbitMQClient(queue.RabbitMQConfig{
-		Host:     c.RabbitMQ.Host,
-		Port:     c.RabbitMQ.Port,
-		User:     c.RabbitMQ.User,
-		Password: c.RabbitMQ.Password,
-		VHost:    c.RabbitMQ.VHost,
+	enabled, err = svcCtx.connector.Connect(ctx, c.Dependencies.RabbitMQ, "RabbitMQ", func() error {
+		return svcCtx.RabbitMQ.Connect(queue.RabbitMQConfig{
+			Host:     c.RabbitMQ.Host,
+			Port:     c.RabbitMQ.Port,
+			User:     c.RabbitMQ.User,
+			Password: c.RabbitMQ.Password,
+			VHost:    c.RabbitMQ.VHost,
+		})
 	})
 	if err != nil {
-		logx.Errorf("Failed to connect to RabbitMQ: %v", err)
-		panic(err)
+		return nil, svcCtx.closeOnError(err)
+	}
+	if enabled {
+		svcCtx.closers.Add("RabbitMQ", svcCtx.RabbitMQ.Close)
+		lifecycle.RegisterCheck(svcCtx.Health, c.Dependencies.RabbitMQ, "rabbitmq", health.PingCheck(svcCtx.RabbitMQ))
 	}
 
-	zitadelClient, err := auth.NewZitadelClient(c.Zitadel)
+	svcCtx.Zitadel, err = auth.NewZitadelClient(c.Zitadel)
 	if err != nil {
-		logx.Errorf("Failed to initialize Zitadel client: %v", err)
-		panic(err)
+		return nil, svcCtx.closeOnError(err)
+	}
+
+	if err := svcCtx.connectUserRpc(ctx); err != nil {
+		return nil, svcCtx.closeOnError(err)
+	}
+
+	return svcCtx, nil
+}
+
+// connectDatabase opens the pool. An optional database that is down keeps the
+// unverified pool, which connects lazily once the database is back
+func (s *ServiceContext) connectDatabase(ctx context.Context) error {
+	req := s.Config.Dependencies.Database
+	switch req {
+	case lifecycle.Disabled:
+		logx.Info("PostgreSQL is disabled")
+		return nil
+	case lifecycle.Optional:
+		db, err := database.OpenPostgres(s.Config.Database.Postgres)
+		if err != nil {
+			return err
+		}
+		s.DB = db
+		if err := db.PingContext(ctx); err != nil {
+			logx.Errorf("Optional dependency PostgreSQL is unavailable, starting degraded: %v", err)
+		}
+	default:
+		err := lifecycle.Retry(ctx, s.Config.Startup, "PostgreSQL", func() (err error) {
+			s.DB, err = database.NewPostgresConnection(s.Config.Database.Postgres)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	s.closers.Add("PostgreSQL", func() error {
+		return database.ClosePostgresConnection(s.DB)
+	})
+	lifecycle.RegisterCheck(s.Health, req, "database", health.DBCheck(s.DB))
+	return nil
+}
+
+// connectUserRpc dials the user service. When optional the dial does not block
+// and gRPC reconnects on its own; when disabled every call fails with Unavailable
+func (s *ServiceContext) connectUserRpc(ctx context.Context) error {
+	req := s.Config.Dependencies.UserRpc
+	if req == lifecycle.Disabled {
+		logx.Info("User RPC is disabled")
+		cli, err := zrpc.NewClientWithTarget(disabledUserRpcTarget,
+			zrpc.WithNonBlock(),
+			zrpc.WithUnaryClientInterceptor(rejectDisabledUserRpc))
+		if err != nil {
+			return err
+		}
+		s.closers.Add("user RPC", cli.Conn().Close)
+		s.UserRpc = userclient.NewUser(cli)
+		return nil
 	}
 
-	return &ServiceContext{
-		Config:   c,
-		DB:       db,
-		Redis:    redisClient,
-		RabbitMQ: rabbitmqClient,
-		Zitadel:  zitadelClient,
+	conf := s.Config.UserRpc
+	conf.NonBlock = req == lifecycle.Optional
+
+	var cli zrpc.Client
+	err := lifecycle.Retry(ctx, s.Config.Startup, "user RPC", func() (err error) {
+		cli, err = zrpc.NewClient(conf, zrpc.WithUnaryClientInterceptor(interceptor.UnaryClientMetadataInterceptor))
+		return err
+	})
+	if err != nil {
+		return err
 	}
+
+	s.closers.Add("user RPC", cli.Conn().Close)
+	s.UserRpc = userclient.NewUser(cli)
+	lifecycle.RegisterCheck(s.Health, req, "user_rpc",
+		health.GRPCCheck(cli.Conn(), userclient.User_ServiceDesc.ServiceName))
+	return nil
+}
+
+func rejectDisabledUserRpc(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	return status.Error(codes.Unavailable, fmt.Sprintf("user service is disabled, cannot call %s", method))
+}
+
+// Close stops background reconnects, then releases all connections in the
+// reverse order they were opened. Call it only after the server has stopped
+// accepting requests
+func (s *ServiceContext) Close() error {
+	s.connector.Stop()
+	return s.closers.Close()
+}
+
+func (s *ServiceContext) closeOnError(err error) error {
+	_ = s.Close()
+	return err
 }
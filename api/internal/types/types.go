@@ -15,6 +15,28 @@ type User struct {
 	Name      string `json:"name"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
+	DeletedAt string `json:"deleted_at,omitempty"`
+	Version   int64  `json:"version"`
+}
+
+type UserList struct {
+	Users      []User `json:"users"`
+	Total      *int64 `json:"total,omitempty"`
+	TotalExact bool   `json:"total_exact"`
+	PageSize   int64  `json:"page_size"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type UserSearchHit struct {
+	User           User    `json:"user"`
+	Score          float64 `json:"score"`
+	NameHighlight  string  `json:"name_highlight"`
+	EmailHighlight string  `json:"email_highlight"`
+}
+
+type UserSearchResult struct {
+	Hits []UserSearchHit `json:"hits"`
 }
 
 type CreateUserRequest struct {
@@ -23,22 +45,50 @@ type CreateUserRequest struct {
 }
 
 type UpdateUserRequest struct {
-	Id    int64  `json:"id" validate:"required"`
-	Email string `json:"email,omitempty" validate:"omitempty,email"`
-	Name  string `json:"name,omitempty"`
+	Id      int64  `json:"id" validate:"required"`
+	Email   string `json:"email,optional" validate:"omitempty,email"`
+	Name    string `json:"name,optional"`
+	IfMatch string `header:"If-Match,optional"`
+}
+
+type PatchUserRequest struct {
+	Id      int64                  `path:"id"`
+	IfMatch string                 `header:"If-Match,optional"`
+	Patch   map[string]interface{} // Merge patch document decoded from the body
 }
 
 type GetUserRequest struct {
-	Id    string `path:"id"`
-	IdInt int64  // Parsed ID
+	Id             string `path:"id"`
+	IdInt          int64  `json:"-"` // Parsed ID, skipped by httpx.Parse
+	IncludeDeleted bool   `form:"include_deleted,optional"`
+	IfNoneMatch    string `header:"If-None-Match,optional"`
 }
 
 type GetUsersRequest struct {
-	Page     int64 `form:"page,default=1"`
-	PageSize int64 `form:"page_size,default=10"`
+	Cursor         string `form:"cursor,optional"`
+	PageSize       int64  `form:"page_size,default=10"`
+	Total          string `form:"total,default=estimated,options=none|estimated|exact"`
+	Email          string `form:"email,optional"`
+	NameContains   string `form:"name_contains,optional"`
+	CreatedAfter   string `form:"created_after,optional"`
+	CreatedBefore  string `form:"created_before,optional"`
+	Q              string `form:"q,optional"`
+	Sort           string `form:"sort,default=created_at,options=created_at|updated_at|name|email|id"`
+	Order          string `form:"order,default=desc,options=asc|desc"`
+	IncludeDeleted bool   `form:"include_deleted,optional"`
+}
+
+type SearchUsersRequest struct {
+	Q     string `form:"q"`
+	Limit int64  `form:"limit,default=20"`
 }
 
 type DeleteUserRequest struct {
+	Id      int64  `path:"id"`
+	IfMatch string `header:"If-Match,optional"`
+}
+
+type RestoreUserRequest struct {
 	Id int64 `path:"id"`
 }
 
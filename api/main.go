@@ -8,17 +8,33 @@ import (
 	"github.com/Nha1410/go-zero-template/api/internal/svc"
 	envConfig "github.com/Nha1410/go-zero-template/common/config"
 
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/proc"
 	"github.com/zeromicro/go-zero/rest"
+	"github.com/zeromicro/go-zero/rest/httpx"
 )
 
 func main() {
 	_ = envConfig.LoadEnv()
 	c := config.LoadFromEnv()
 
-	server := rest.MustNewServer(c.RestConf)
+	httpx.SetErrorHandlerCtx(handler.ErrorHandler(c))
+
+	server := rest.MustNewServer(c.RestConf,
+		rest.WithNotFoundHandler(handler.NotFoundHandler(c)),
+		rest.WithNotAllowedHandler(handler.NotAllowedHandler(c)),
+	)
+
+	ctx, err := svc.NewServiceContext(c)
+	logx.Must(err)
+	// Deferred first so it runs last, once the server has drained
+	defer ctx.Close()
 	defer server.Stop()
 
-	ctx := svc.NewServiceContext(c)
+	// On SIGTERM report not ready before the listener closes; Start returns
+	// after in-flight requests finish
+	proc.AddWrapUpListener(ctx.Health.MarkShuttingDown)
+
 	handler.RegisterHandlers(server, ctx)
 
 	fmt.Printf("Starting server at %s:%d...\n", c.Host, c.Port)
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// introspectionCacheLimit caps the number of cached introspection results
+	introspectionCacheLimit = 10000
+	// introspectionCacheExpire is the default entry lifetime; entries are
+	// normally stored with the token's remaining lifetime instead
+	introspectionCacheExpire = time.Hour
+)
+
+// introspectionResult is a cached active introspection response
+type introspectionResult struct {
+	userInfo *UserInfo
+	exp      time.Time
+}
+
+// introspect validates an opaque token with the RFC 7662 introspection
+// endpoint, authenticating with the client credentials. Active results are
+// cached until the token expires. Failures of the endpoint itself, including
+// rejected client credentials, wrap ErrIdPUnavailable
+func (z *ZitadelClient) introspect(ctx context.Context, token string) (*UserInfo, error) {
+	key := introspectionCacheKey(token)
+	// The cache evicts on a one second tick, so exp is checked as well
+	if cached, ok := z.introspectionCache.Get(key); ok && time.Now().Before(cached.(introspectionResult).exp) {
+		return cached.(introspectionResult).userInfo, nil
+	}
+
+	endpoint, err := z.introspectionEndpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "access_token")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(z.clientConfig.ClientID), url.QueryEscape(z.clientConfig.ClientSecret))
+
+	resp, err := z.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call introspection endpoint: %w: %w", ErrIdPUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection failed: %w: status code %d", ErrIdPUnavailable, resp.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode introspection response: %w: %w", ErrIdPUnavailable, err)
+	}
+
+	if active, _ := claims["active"].(bool); !active {
+		return nil, fmt.Errorf("invalid token: token is not active")
+	}
+
+	exp, ok := numericClaim(claims, "exp")
+	if !ok {
+		return nil, fmt.Errorf("invalid token: missing exp claim")
+	}
+	ttl := time.Until(exp)
+	if ttl <= 0 {
+		return nil, fmt.Errorf("invalid token: token expired at %s", exp.Format(time.RFC3339))
+	}
+
+	userInfo := z.userInfoFromClaims(claims)
+	if userInfo.Sub == "" {
+		return nil, fmt.Errorf("invalid token: missing sub claim")
+	}
+
+	z.introspectionCache.SetWithExpire(key, introspectionResult{userInfo: userInfo, exp: exp}, ttl)
+	return userInfo, nil
+}
+
+// introspectionEndpoint returns the endpoint advertised by discovery, falling
+// back to Zitadel's well-known path
+func (z *ZitadelClient) introspectionEndpoint(ctx context.Context) (string, error) {
+	d, err := z.keys.Discovery(ctx)
+	if err == nil && d.IntrospectionEndpoint != "" {
+		return d.IntrospectionEndpoint, nil
+	}
+	if z.issuer == "" {
+		return "", fmt.Errorf("no introspection endpoint available: %w", err)
+	}
+	return fmt.Sprintf("%s/oauth/v2/introspect", z.issuer), nil
+}
+
+// introspectionCacheKey hashes the token so raw credentials are not kept in memory as keys
+func introspectionCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
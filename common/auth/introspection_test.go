@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testClientSecret = "client-secret"
+
+// testIntrospector serves RFC 7662 introspection at Zitadel's well-known path
+// and counts the calls that passed client authentication
+type testIntrospector struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	calls    int
+	status   int
+	response map[string]interface{}
+}
+
+func newTestIntrospector(t *testing.T) *testIntrospector {
+	t.Helper()
+	p := &testIntrospector{status: http.StatusOK}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/v2/introspect", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != testAudience || secret != testClientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost || r.PostFormValue("token") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.calls++
+		if p.status != http.StatusOK {
+			w.WriteHeader(p.status)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(p.response)
+	})
+	// Discovery is not served, so the client falls back to the well-known path
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *testIntrospector) respond(status int, response map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status, p.response = status, response
+}
+
+func (p *testIntrospector) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+func newIntrospectionClient(t *testing.T, issuer, secret string) *ZitadelClient {
+	t.Helper()
+	z, err := NewZitadelClient(ZitadelConfig{
+		Issuer:         issuer,
+		ClientID:       testAudience,
+		ClientSecret:   secret,
+		ValidationMode: ValidationModeIntrospection,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+func activeResponse(exp time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"active": true,
+		"aud":    []interface{}{testAudience},
+		"sub":    "user-1",
+		"scope":  "openid users:write",
+		"exp":    exp.Unix(),
+		zitadelRolesClaim: map[string]interface{}{
+			"admin": map[string]interface{}{"org-1": "example.com"},
+		},
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	tests := []struct {
+		name            string
+		secret          string
+		status          int
+		response        map[string]interface{}
+		wantErr         string
+		wantUnavailable bool
+	}{
+		{
+			name:     "active",
+			secret:   testClientSecret,
+			status:   http.StatusOK,
+			response: activeResponse(time.Now().Add(time.Hour)),
+		},
+		{
+			name:     "inactive",
+			secret:   testClientSecret,
+			status:   http.StatusOK,
+			response: map[string]interface{}{"active": false},
+			wantErr:  "not active",
+		},
+		{
+			name:     "active without sub",
+			secret:   testClientSecret,
+			status:   http.StatusOK,
+			response: map[string]interface{}{"active": true, "exp": time.Now().Add(time.Hour).Unix()},
+			wantErr:  "missing sub",
+		},
+		{
+			name:     "active but expired",
+			secret:   testClientSecret,
+			status:   http.StatusOK,
+			response: activeResponse(time.Now().Add(-time.Minute)),
+			wantErr:  "token expired",
+		},
+		{
+			name:            "client authentication rejected",
+			secret:          "wrong-secret",
+			status:          http.StatusOK,
+			response:        activeResponse(time.Now().Add(time.Hour)),
+			wantErr:         "status code 401",
+			wantUnavailable: true,
+		},
+		{
+			name:            "server error",
+			secret:          testClientSecret,
+			status:          http.StatusInternalServerError,
+			wantErr:         "status code 500",
+			wantUnavailable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newTestIntrospector(t)
+			idp.respond(tt.status, tt.response)
+			z := newIntrospectionClient(t, idp.URL, tt.secret)
+
+			info, err := z.ValidateToken(context.Background(), "opaque-token")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ValidateToken() error = %v, want %q", err, tt.wantErr)
+				}
+				if got := errors.Is(err, ErrIdPUnavailable); got != tt.wantUnavailable {
+					t.Fatalf("errors.Is(err, ErrIdPUnavailable) = %v, want %v", got, tt.wantUnavailable)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if info.Sub != "user-1" {
+				t.Fatalf("Sub = %q, want user-1", info.Sub)
+			}
+			if len(info.Roles) != 1 || info.Roles[0] != "admin" {
+				t.Fatalf("Roles = %v, want [admin]", info.Roles)
+			}
+			if len(info.Scopes) != 2 || info.Scopes[1] != "users:write" {
+				t.Fatalf("Scopes = %v, want [openid users:write]", info.Scopes)
+			}
+		})
+	}
+}
+
+func TestIntrospectUnreachable(t *testing.T) {
+	idp := newTestIntrospector(t)
+	z := newIntrospectionClient(t, idp.URL, testClientSecret)
+	idp.Close()
+
+	_, err := z.ValidateToken(context.Background(), "opaque-token")
+	if !errors.Is(err, ErrIdPUnavailable) {
+		t.Fatalf("ValidateToken() error = %v, want ErrIdPUnavailable", err)
+	}
+}
+
+func TestIntrospectCacheBoundedByExp(t *testing.T) {
+	idp := newTestIntrospector(t)
+	z := newIntrospectionClient(t, idp.URL, testClientSecret)
+	ctx := context.Background()
+
+	// exp has whole second precision, so the token expires in one to two seconds
+	exp := time.Unix(time.Now().Add(2*time.Second).Unix(), 0)
+	idp.respond(http.StatusOK, activeResponse(exp))
+
+	for i := 0; i < 3; i++ {
+		if _, err := z.ValidateToken(ctx, "opaque-token"); err != nil {
+			t.Fatalf("ValidateToken() call %d error = %v", i+1, err)
+		}
+	}
+	if got := idp.callCount(); got != 1 {
+		t.Fatalf("introspection calls = %d, want 1 while cached", got)
+	}
+
+	// Other tokens are introspected separately
+	if _, err := z.ValidateToken(ctx, "other-token"); err != nil {
+		t.Fatalf("ValidateToken(other) error = %v", err)
+	}
+	if got := idp.callCount(); got != 2 {
+		t.Fatalf("introspection calls = %d, want 2", got)
+	}
+
+	// Once the token expires the IdP is asked again, and its answer stands
+	time.Sleep(time.Until(exp) + 10*time.Millisecond)
+	idp.respond(http.StatusOK, map[string]interface{}{"active": false})
+	if _, err := z.ValidateToken(ctx, "opaque-token"); err == nil || !strings.Contains(err.Error(), "not active") {
+		t.Fatalf("ValidateToken() after exp error = %v, want not active", err)
+	}
+	if got := idp.callCount(); got != 3 {
+		t.Fatalf("introspection calls = %d, want 3", got)
+	}
+}
+
+func TestIntrospectInactiveNotCached(t *testing.T) {
+	idp := newTestIntrospector(t)
+	z := newIntrospectionClient(t, idp.URL, testClientSecret)
+	ctx := context.Background()
+
+	idp.respond(http.StatusOK, map[string]interface{}{"active": false})
+	if _, err := z.ValidateToken(ctx, "opaque-token"); err == nil {
+		t.Fatal("ValidateToken() of an inactive token succeeded")
+	}
+	idp.respond(http.StatusOK, activeResponse(time.Now().Add(time.Hour)))
+	if _, err := z.ValidateToken(ctx, "opaque-token"); err != nil {
+		t.Fatalf("ValidateToken() once active error = %v", err)
+	}
+	if got := idp.callCount(); got != 2 {
+		t.Fatalf("introspection calls = %d, want 2", got)
+	}
+}
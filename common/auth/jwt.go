@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// zitadelRolesClaim is the claim Zitadel uses for project roles. Project
+// specific claims take the form urn:zitadel:iam:org:project:<id>:roles
+const (
+	zitadelRolesClaim       = "urn:zitadel:iam:org:project:roles"
+	zitadelRolesClaimPrefix = "urn:zitadel:iam:org:project:"
+	zitadelRolesClaimSuffix = ":roles"
+)
+
+// jwtSigningMethods are the asymmetric algorithms accepted for access tokens
+var jwtSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// validateJWT verifies the token signature against the issuer's JWKS and checks
+// the registered claims locally
+func (z *ZitadelClient) validateJWT(ctx context.Context, token string) (*UserInfo, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(jwtSigningMethods),
+		jwt.WithoutClaimsValidation(),
+	)
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, fmt.Errorf("token has no kid header")
+		}
+		return z.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if err := z.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return z.userInfoFromClaims(claims), nil
+}
+
+// validateClaims checks iss, aud, exp and nbf allowing for the configured clock skew
+func (z *ZitadelClient) validateClaims(claims jwt.MapClaims) error {
+	now := time.Now()
+
+	iss, _ := claims["iss"].(string)
+	if strings.TrimSuffix(iss, "/") != z.issuer {
+		return fmt.Errorf("invalid token: unexpected issuer %q", iss)
+	}
+
+	if !z.audienceMatches(claims) {
+		return fmt.Errorf("invalid token: audience mismatch")
+	}
+
+	exp, ok := numericClaim(claims, "exp")
+	if !ok {
+		return fmt.Errorf("invalid token: missing exp claim")
+	}
+	if now.After(exp.Add(z.clockSkew)) {
+		return fmt.Errorf("invalid token: token expired at %s", exp.Format(time.RFC3339))
+	}
+
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(z.clockSkew).Before(nbf) {
+		return fmt.Errorf("invalid token: token not valid before %s", nbf.Format(time.RFC3339))
+	}
+
+	if iat, ok := numericClaim(claims, "iat"); ok && now.Add(z.clockSkew).Before(iat) {
+		return fmt.Errorf("invalid token: token issued in the future")
+	}
+
+	return nil
+}
+
+func (z *ZitadelClient) audienceMatches(claims map[string]interface{}) bool {
+	return audienceIncludes(claims, z.audience)
+}
+
+// audienceIncludes reports whether the aud claim names any of want
+func audienceIncludes(claims map[string]interface{}, want []string) bool {
+	var aud []string
+	switch v := claims["aud"].(type) {
+	case string:
+		aud = []string{v}
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+	}
+
+	for _, w := range want {
+		for _, got := range aud {
+			if got == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// numericClaim reads a NumericDate claim as a time
+func numericClaim(claims jwt.MapClaims, name string) (time.Time, bool) {
+	switch v := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(v), 0), true
+	case int64:
+		return time.Unix(v, 0), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// userInfoFromClaims maps token or userinfo claims to UserInfo
+func (z *ZitadelClient) userInfoFromClaims(claims map[string]interface{}) *UserInfo {
+	str := func(name string) string {
+		s, _ := claims[name].(string)
+		return s
+	}
+
+	info := &UserInfo{
+		Sub:        str("sub"),
+		Email:      str("email"),
+		Name:       str("name"),
+		GivenName:  str("given_name"),
+		FamilyName: str("family_name"),
+		Picture:    str("picture"),
+		Roles:      z.rolesFromClaims(claims),
+	}
+	info.EmailVerified, _ = claims["email_verified"].(bool)
+	if scope := str("scope"); scope != "" {
+		info.Scopes = strings.Fields(scope)
+	}
+
+	return info
+}
+
+// rolesFromClaims collects role names from the Zitadel role claims of this
+// project. Each claim maps a role key to the organizations that granted it.
+// Tokens issued for other projects of the same instance carry their roles
+// under other claims, which are ignored
+func (z *ZitadelClient) rolesFromClaims(claims map[string]interface{}) []string {
+	var names []string
+	if z.projectID != "" {
+		names = append(names, zitadelRolesClaimPrefix+z.projectID+zitadelRolesClaimSuffix)
+	}
+	// The project-agnostic claim holds the roles of whichever project the
+	// token was requested for, so it is only read when that is this one
+	roleAudience := z.audience
+	if z.projectID != "" {
+		roleAudience = []string{z.projectID}
+	}
+	if audienceIncludes(claims, roleAudience) {
+		names = append(names, zitadelRolesClaim)
+	}
+
+	seen := make(map[string]struct{})
+	for _, name := range names {
+		grants, ok := claims[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for role := range grants {
+			seen[role] = struct{}{}
+		}
+	}
+
+	roles := make([]string, 0, len(seen))
+	for role := range seen {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
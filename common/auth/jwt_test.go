@@ -0,0 +1,409 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testAudience = "client-id"
+
+// testIdP serves a discovery document and the JWKS of its current keys
+type testIdP struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	keys         map[string]*rsa.PrivateKey
+	jwksRequests int
+}
+
+func newTestIdP(t *testing.T, kids ...string) *testIdP {
+	t.Helper()
+	p := &testIdP{keys: make(map[string]*rsa.PrivateKey)}
+	p.rotate(t, kids...)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(OIDCDiscovery{
+			Issuer:  p.URL,
+			JWKSURI: p.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", p.serveJWKS)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// rotate replaces the served keys with new keys under kids
+func (p *testIdP) rotate(t *testing.T, kids ...string) {
+	t.Helper()
+	keys := make(map[string]*rsa.PrivateKey, len(kids))
+	for _, kid := range kids {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[kid] = key
+	}
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+}
+
+func (p *testIdP) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.jwksRequests++
+
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	for kid, key := range p.keys {
+		doc.Keys = append(doc.Keys, jsonWebKey{
+			Kid: kid,
+			Kty: "RSA",
+			Alg: "RS256",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	_ = json.NewEncoder(w).Encode(doc)
+}
+
+func (p *testIdP) requests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.jwksRequests
+}
+
+func (p *testIdP) key(kid string) *rsa.PrivateKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keys[kid]
+}
+
+// claims returns valid claims for a token from p, with overrides applied
+func (p *testIdP) claims(overrides jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   p.URL,
+		"aud":   []string{testAudience},
+		"sub":   "user-1",
+		"email": "user@example.com",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"scope": "openid email",
+		zitadelRolesClaim: map[string]interface{}{
+			"admin": map[string]interface{}{"org-1": "example.com"},
+		},
+	}
+	for name, value := range overrides {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newJWTClient(t *testing.T, issuer string) *ZitadelClient {
+	t.Helper()
+	z, err := NewZitadelClient(ZitadelConfig{
+		Issuer:         issuer,
+		ClientID:       testAudience,
+		ValidationMode: ValidationModeJWT,
+		ClockSkew:      30 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+func TestValidateJWT(t *testing.T) {
+	idp := newTestIdP(t, "key-1")
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodRS256, "key-1", idp.claims(nil), idp.key("key-1"))
+			},
+		},
+		{
+			name: "expired within clock skew",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodRS256, "key-1",
+					idp.claims(jwt.MapClaims{"exp": now.Add(-10 * time.Second).Unix()}), idp.key("key-1"))
+			},
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodRS256, "key-1",
+					idp.claims(jwt.MapClaims{"iss": "https://evil.example.com"}), idp.key("key-1"))
+			},
+			wantErr: "unexpected issuer",
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodRS256, "key-1",
+					idp.claims(jwt.MapClaims{"aud": "other-client"}), idp.key("key-1"))
+			},
+			wantErr: "audience mismatch",
+		},
+		{
+			name: "expired",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodRS256, "key-1",
+					idp.claims(jwt.MapClaims{"exp": now.Add(-2 * time.Minute).Unix()}), idp.key("key-1"))
+			},
+			wantErr: "token expired",
+		},
+		{
+			name: "missing exp",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodRS256, "key-1",
+					idp.claims(jwt.MapClaims{"exp": nil}), idp.key("key-1"))
+			},
+			wantErr: "missing exp",
+		},
+		{
+			name: "not yet valid",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodRS256, "key-1",
+					idp.claims(jwt.MapClaims{"nbf": now.Add(2 * time.Minute).Unix()}), idp.key("key-1"))
+			},
+			wantErr: "not valid before",
+		},
+		{
+			name: "alg none",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodNone, "key-1", idp.claims(nil), jwt.UnsafeAllowNoneSignatureType)
+			},
+			wantErr: "signing method none is invalid",
+		},
+		{
+			name: "alg HS256",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodHS256, "key-1", idp.claims(nil), []byte("secret"))
+			},
+			wantErr: "signing method HS256 is invalid",
+		},
+		{
+			name: "signed by another key",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodRS256, "key-1", idp.claims(nil), otherKey)
+			},
+			wantErr: "verification error",
+		},
+		{
+			name: "missing kid",
+			token: func() string {
+				return signToken(t, jwt.SigningMethodRS256, "", idp.claims(nil), idp.key("key-1"))
+			},
+			wantErr: "no kid header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z := newJWTClient(t, idp.URL)
+			info, err := z.ValidateToken(context.Background(), "Bearer "+tt.token())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ValidateToken() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if info.Sub != "user-1" || info.Email != "user@example.com" {
+				t.Fatalf("ValidateToken() = %+v, want sub user-1 and email user@example.com", info)
+			}
+			if len(info.Roles) != 1 || info.Roles[0] != "admin" {
+				t.Fatalf("Roles = %v, want [admin]", info.Roles)
+			}
+			if len(info.Scopes) != 2 {
+				t.Fatalf("Scopes = %v, want [openid email]", info.Scopes)
+			}
+		})
+	}
+}
+
+func TestRolesFromClaims(t *testing.T) {
+	admin := map[string]interface{}{"admin": map[string]interface{}{"org-1": "example.com"}}
+	projectClaim := func(id string) string {
+		return zitadelRolesClaimPrefix + id + zitadelRolesClaimSuffix
+	}
+
+	tests := []struct {
+		name      string
+		projectID string
+		claims    map[string]interface{}
+		want      []string
+	}{
+		{
+			name:      "own project",
+			projectID: "project-1",
+			claims:    map[string]interface{}{projectClaim("project-1"): admin},
+			want:      []string{"admin"},
+		},
+		{
+			name:      "foreign project",
+			projectID: "project-1",
+			claims:    map[string]interface{}{projectClaim("project-2"): admin},
+		},
+		{
+			name:   "foreign project without a configured project",
+			claims: map[string]interface{}{"aud": []interface{}{testAudience}, projectClaim("project-2"): admin},
+		},
+		{
+			name:      "requested for own project",
+			projectID: "project-1",
+			claims:    map[string]interface{}{"aud": []interface{}{testAudience, "project-1"}, zitadelRolesClaim: admin},
+			want:      []string{"admin"},
+		},
+		{
+			name:      "requested for another project",
+			projectID: "project-1",
+			claims:    map[string]interface{}{"aud": []interface{}{testAudience, "project-2"}, zitadelRolesClaim: admin},
+		},
+		{
+			name:   "requested for the client",
+			claims: map[string]interface{}{"aud": testAudience, zitadelRolesClaim: admin},
+			want:   []string{"admin"},
+		},
+		{
+			name:   "requested without an audience",
+			claims: map[string]interface{}{zitadelRolesClaim: admin},
+		},
+		{
+			name:      "plain role list",
+			projectID: "project-1",
+			claims:    map[string]interface{}{"aud": "project-1", "roles": []interface{}{"admin"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			z, err := NewZitadelClient(ZitadelConfig{Issuer: "https://idp.example.com", ClientID: testAudience, ProjectID: tt.projectID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := z.rolesFromClaims(tt.claims); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("rolesFromClaims() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateJWTForeignProjectRoles(t *testing.T) {
+	idp := newTestIdP(t, "key-1")
+	z, err := NewZitadelClient(ZitadelConfig{
+		Issuer:         idp.URL,
+		ClientID:       testAudience,
+		ProjectID:      "project-1",
+		ValidationMode: ValidationModeJWT,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token accepted for this client grants no roles from another project
+	token := signToken(t, jwt.SigningMethodRS256, "key-1", idp.claims(jwt.MapClaims{
+		zitadelRolesClaim: nil,
+		zitadelRolesClaimPrefix + "project-2" + zitadelRolesClaimSuffix: map[string]interface{}{
+			"admin": map[string]interface{}{"org-1": "example.com"},
+		},
+	}), idp.key("key-1"))
+	info, err := z.ValidateToken(context.Background(), "Bearer "+token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if len(info.Roles) != 0 {
+		t.Fatalf("Roles = %v, want none", info.Roles)
+	}
+}
+
+func TestValidateJWTKeyRotation(t *testing.T) {
+	idp := newTestIdP(t, "key-1")
+	z := newJWTClient(t, idp.URL)
+	ctx := context.Background()
+
+	if _, err := z.ValidateToken(ctx, signToken(t, jwt.SigningMethodRS256, "key-1", idp.claims(nil), idp.key("key-1"))); err != nil {
+		t.Fatalf("ValidateToken(key-1) error = %v", err)
+	}
+	if got := idp.requests(); got != 1 {
+		t.Fatalf("JWKS requests = %d, want 1", got)
+	}
+
+	// A token signed with a rotated key forces a refresh, but not twice
+	// within minJWKSRefreshInterval, so unknown key IDs cannot hammer the IdP
+	retired := idp.key("key-1")
+	idp.rotate(t, "key-2")
+	rotated := signToken(t, jwt.SigningMethodRS256, "key-2", idp.claims(nil), idp.key("key-2"))
+	if _, err := z.ValidateToken(ctx, rotated); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Fatalf("ValidateToken(key-2) error = %v, want rate limited", err)
+	}
+	if got := idp.requests(); got != 1 {
+		t.Fatalf("JWKS requests = %d, want 1 while rate limited", got)
+	}
+
+	z.keys.mu.Lock()
+	z.keys.lastAttempt = time.Now().Add(-minJWKSRefreshInterval)
+	z.keys.mu.Unlock()
+
+	info, err := z.ValidateToken(ctx, rotated)
+	if err != nil {
+		t.Fatalf("ValidateToken(key-2) after the refresh interval error = %v", err)
+	}
+	if info.Sub != "user-1" {
+		t.Fatalf("Sub = %q, want user-1", info.Sub)
+	}
+	if got := idp.requests(); got != 2 {
+		t.Fatalf("JWKS requests = %d, want 2", got)
+	}
+
+	// The retired key is gone from the refreshed set
+	old := signToken(t, jwt.SigningMethodRS256, "key-1", idp.claims(nil), retired)
+	if _, err := z.ValidateToken(ctx, old); err == nil {
+		t.Fatal("ValidateToken(key-1) after rotation succeeded, want error")
+	}
+}
@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minJWKSRefreshInterval limits how often the JWKS can be refetched
+const minJWKSRefreshInterval = 30 * time.Second
+
+// OIDCDiscovery holds the fields we use from the issuer's discovery document
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	IntrospectionEndpoint string `json:"introspection_endpoint"`
+}
+
+// jsonWebKey is a single entry of a JWKS document
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet fetches and caches the issuer's signing keys, refreshing them
+// periodically and whenever a token references an unknown key ID
+type keySet struct {
+	issuer          string
+	httpClient      *http.Client
+	refreshInterval time.Duration
+
+	mu          sync.RWMutex
+	discovery   *OIDCDiscovery
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func newKeySet(issuer string, httpClient *http.Client, refreshInterval time.Duration) *keySet {
+	return &keySet{
+		issuer:          strings.TrimSuffix(issuer, "/"),
+		httpClient:      httpClient,
+		refreshInterval: refreshInterval,
+		keys:            make(map[string]crypto.PublicKey),
+	}
+}
+
+// Discovery returns the cached discovery document, fetching it on first use
+func (k *keySet) Discovery(ctx context.Context) (*OIDCDiscovery, error) {
+	k.mu.RLock()
+	d := k.discovery
+	k.mu.RUnlock()
+	if d != nil {
+		return d, nil
+	}
+
+	d, err := k.fetchDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.discovery = d
+	k.mu.Unlock()
+	return d, nil
+}
+
+// Key returns the public key for kid, refreshing the key set when it is stale
+// or does not contain kid
+func (k *keySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	stale := time.Since(k.fetchedAt) > k.refreshInterval
+	k.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	if err := k.refresh(ctx, !ok); err != nil {
+		// Keep serving a known key if the IdP is temporarily unreachable
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok = k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found in JWKS", kid)
+	}
+	return key, nil
+}
+
+// refresh refetches the JWKS at most once per minJWKSRefreshInterval. When force
+// is set (unknown kid) a rate-limited refresh is an error, so forged key IDs
+// cannot be used to hammer the IdP
+func (k *keySet) refresh(ctx context.Context, force bool) error {
+	k.mu.Lock()
+	if time.Since(k.lastAttempt) < minJWKSRefreshInterval {
+		k.mu.Unlock()
+		if force {
+			return fmt.Errorf("JWKS refresh rate limited")
+		}
+		return nil
+	}
+	k.lastAttempt = time.Now()
+	k.mu.Unlock()
+
+	d, err := k.Discovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys, err := k.fetchKeys(ctx, d.JWKSURI)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.fetchedAt = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+func (k *keySet) fetchDiscovery(ctx context.Context) (*OIDCDiscovery, error) {
+	discoveryURL := fmt.Sprintf("%s/.well-known/openid-configuration", k.issuer)
+
+	var d OIDCDiscovery
+	if err := k.getJSON(ctx, discoveryURL, &d); err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document: %w: %w", ErrIdPUnavailable, err)
+	}
+	if d.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document has no jwks_uri")
+	}
+	if d.Issuer != "" && strings.TrimSuffix(d.Issuer, "/") != k.issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match configured issuer %q", d.Issuer, k.issuer)
+	}
+
+	return &d, nil
+}
+
+func (k *keySet) fetchKeys(ctx context.Context, jwksURI string) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := k.getJSON(ctx, jwksURI, &doc); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w: %w", ErrIdPUnavailable, err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip keys we cannot use rather than failing the whole set
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no usable signing keys")
+	}
+
+	return keys, nil
+}
+
+func (k *keySet) getJSON(ctx context.Context, url string, dest interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(dest)
+}
+
+// publicKey converts the JWK into an RSA or ECDSA public key
+func (j jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+}
@@ -3,20 +3,52 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/zeromicro/go-zero/core/collection"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
+// Token validation modes
+const (
+	// ValidationModeUserInfo validates tokens by calling the userinfo endpoint
+	ValidationModeUserInfo = "userinfo"
+	// ValidationModeJWT validates JWT access tokens locally against the issuer's JWKS
+	ValidationModeJWT = "jwt"
+	// ValidationModeIntrospection validates tokens with the OAuth2 introspection endpoint
+	ValidationModeIntrospection = "introspection"
+)
+
+const (
+	defaultClockSkew           = 30 * time.Second
+	defaultJWKSRefreshInterval = time.Hour
+	defaultHTTPTimeout         = 10 * time.Second
+)
+
+// ErrIdPUnavailable is wrapped by ValidateToken errors when the identity
+// provider could not be reached or failed, so the token was not judged
+var ErrIdPUnavailable = errors.New("identity provider unavailable")
+
 // ZitadelConfig holds Zitadel OAuth2 configuration
 type ZitadelConfig struct {
 	Issuer       string
 	ClientID     string
 	ClientSecret string
 	Scopes       []string
+	// ValidationMode is one of userinfo (default), jwt or introspection
+	ValidationMode string
+	// Audience lists accepted aud values for JWT validation; defaults to ClientID
+	Audience []string
+	// ProjectID is the Zitadel project whose roles are granted. Without it,
+	// roles are only read from tokens whose audience is in Audience
+	ProjectID           string
+	ClockSkew           time.Duration
+	JWKSRefreshInterval time.Duration
 }
 
 // ZitadelClient wraps Zitadel OAuth2 client
@@ -24,6 +56,14 @@ type ZitadelClient struct {
 	config       *oauth2.Config
 	clientConfig *clientcredentials.Config
 	issuer       string
+	mode         string
+	audience     []string
+	projectID    string
+	clockSkew    time.Duration
+	httpClient   *http.Client
+	keys         *keySet
+
+	introspectionCache *collection.Cache
 }
 
 // UserInfo represents user information from Zitadel
@@ -36,10 +76,45 @@ type UserInfo struct {
 	FamilyName    string   `json:"family_name"`
 	Picture       string   `json:"picture"`
 	Roles         []string `json:"roles"`
+	Scopes        []string `json:"scopes,omitempty"`
 }
 
 // NewZitadelClient creates a new Zitadel OAuth2 client
 func NewZitadelClient(config ZitadelConfig) (*ZitadelClient, error) {
+	mode := config.ValidationMode
+	if mode == "" {
+		mode = ValidationModeUserInfo
+	}
+	switch mode {
+	case ValidationModeUserInfo, ValidationModeJWT:
+	case ValidationModeIntrospection:
+		if config.ClientID == "" || config.ClientSecret == "" {
+			return nil, fmt.Errorf("introspection requires a client ID and secret")
+		}
+	default:
+		return nil, fmt.Errorf("unknown token validation mode: %s", mode)
+	}
+
+	audience := config.Audience
+	if len(audience) == 0 && config.ClientID != "" {
+		audience = []string{config.ClientID}
+	}
+	if mode == ValidationModeJWT && len(audience) == 0 {
+		return nil, fmt.Errorf("jwt validation requires an audience or client ID")
+	}
+
+	clockSkew := config.ClockSkew
+	if clockSkew <= 0 {
+		clockSkew = defaultClockSkew
+	}
+	refreshInterval := config.JWKSRefreshInterval
+	if refreshInterval <= 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+
+	issuer := strings.TrimSuffix(config.Issuer, "/")
+	httpClient := &http.Client{Timeout: defaultHTTPTimeout}
+
 	oauth2Config := &oauth2.Config{
 		ClientID:     config.ClientID,
 		ClientSecret: config.ClientSecret,
@@ -57,20 +132,52 @@ func NewZitadelClient(config ZitadelConfig) (*ZitadelClient, error) {
 		TokenURL:     fmt.Sprintf("%s/oauth/v2/token", config.Issuer),
 	}
 
+	introspectionCache, err := collection.NewCache(introspectionCacheExpire,
+		collection.WithLimit(introspectionCacheLimit),
+		collection.WithName("zitadel-introspection"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create introspection cache: %w", err)
+	}
+
 	return &ZitadelClient{
 		config:       oauth2Config,
 		clientConfig: clientConfig,
-		issuer:       config.Issuer,
+		issuer:       issuer,
+		mode:         mode,
+		audience:     audience,
+		projectID:    config.ProjectID,
+		clockSkew:    clockSkew,
+		httpClient:   httpClient,
+		keys:         newKeySet(issuer, httpClient, refreshInterval),
+
+		introspectionCache: introspectionCache,
 	}, nil
 }
 
-// ValidateToken validates an OAuth2 token
+// ValidateToken validates an OAuth2 token using the configured validation mode
 func (z *ZitadelClient) ValidateToken(ctx context.Context, token string) (*UserInfo, error) {
 	// Extract token from "Bearer <token>" format
 	token = strings.TrimPrefix(token, "Bearer ")
 	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, fmt.Errorf("empty token")
+	}
+
+	switch z.mode {
+	case ValidationModeJWT:
+		return z.validateJWT(ctx, token)
+	case ValidationModeIntrospection:
+		return z.introspect(ctx, token)
+	default:
+		return z.validateUserInfo(ctx, token)
+	}
+}
 
+// validateUserInfo validates the token by calling the userinfo endpoint
+func (z *ZitadelClient) validateUserInfo(ctx context.Context, token string) (*UserInfo, error) {
 	// Create HTTP client with token
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, z.httpClient)
 	client := oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{
 		AccessToken: token,
 		TokenType:   "Bearer",
@@ -87,20 +194,23 @@ func (z *ZitadelClient) ValidateToken(ctx context.Context, token string) (*UserI
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call userinfo endpoint: %w", err)
+		return nil, fmt.Errorf("failed to call userinfo endpoint: %w: %w", ErrIdPUnavailable, err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, fmt.Errorf("userinfo failed: %w: status code %d", ErrIdPUnavailable, resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("invalid token: status code %d", resp.StatusCode)
 	}
 
-	var userInfo UserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		return nil, fmt.Errorf("failed to decode userinfo: %w", err)
+	var claims map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode userinfo: %w: %w", ErrIdPUnavailable, err)
 	}
 
-	return &userInfo, nil
+	return z.userInfoFromClaims(claims), nil
 }
 
 // GetClientCredentialsToken gets a token using client credentials flow
@@ -127,4 +237,3 @@ func ExtractTokenFromRequest(r *http.Request) string {
 
 	return ""
 }
-
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Rate limiting algorithms
+const (
+	AlgorithmFixedWindow   = "fixed_window"
+	AlgorithmSlidingWindow = "sliding_window"
+	AlgorithmTokenBucket   = "token_bucket"
+)
+
+// fixedWindowScript counts requests in a window starting with the first one.
+// KEYS[1] = bucket key, ARGV[1] = window in ms, ARGV[2] = limit.
+// Returns {allowed, remaining, retry_after_ms, reset_ms}
+var fixedWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+local count = redis.call('INCR', key)
+local reset = redis.call('PTTL', key)
+if reset < 0 then
+	redis.call('PEXPIRE', key, window)
+	reset = window
+end
+
+local allowed = 0
+local retry = reset
+if count <= limit then
+	allowed = 1
+	retry = 0
+end
+
+return {allowed, math.max(limit - count, 0), retry, reset}
+`)
+
+// slidingWindowScript keeps one sorted-set member per request in the window.
+// KEYS[1] = bucket key, ARGV[1] = window in ms, ARGV[2] = limit, ARGV[3] = unique member.
+// Returns {allowed, remaining, retry_after_ms, reset_ms}
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+local count = redis.call('ZCARD', key)
+
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[3])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+
+local retry = 0
+if allowed == 0 then
+	retry = reset
+end
+
+return {allowed, limit - count, retry, reset}
+`)
+
+// tokenBucketScript refills limit tokens evenly over the window.
+// KEYS[1] = bucket key, ARGV[1] = window in ms, ARGV[2] = capacity.
+// Returns {allowed, remaining, retry_after_ms, reset_ms}
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local rate = capacity / window
+
+local state = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', key, window)
+
+local reset = math.ceil((capacity - tokens) / rate)
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+// RateLimit describes how many requests are allowed per window
+type RateLimit struct {
+	Algorithm string
+	Limit     int
+	Window    time.Duration
+}
+
+// RateLimitResult is the outcome of a single rate limit check
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	// ResetAfter is the time until the limit is fully restored
+	ResetAfter time.Duration
+}
+
+// RateLimiter implements distributed rate limiting with atomic Lua scripts
+type RateLimiter struct {
+	client *RedisClient
+	prefix string
+}
+
+// NewRateLimiter creates a rate limiter storing its buckets under prefix
+func NewRateLimiter(client *RedisClient, prefix string) *RateLimiter {
+	return &RateLimiter{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Allow records a request for key and reports whether it is within limit
+func (l *RateLimiter) Allow(ctx context.Context, key string, limit RateLimit) (*RateLimitResult, error) {
+	if limit.Limit <= 0 || limit.Window <= 0 {
+		return nil, fmt.Errorf("invalid rate limit: %d per %s", limit.Limit, limit.Window)
+	}
+
+	rdb, err := l.client.rdb()
+	if err != nil {
+		return nil, err
+	}
+
+	windowMs := limit.Window.Milliseconds()
+	redisKey := fmt.Sprintf("%s:%s:%s", l.prefix, limit.Algorithm, key)
+
+	var cmd *redis.Cmd
+	switch limit.Algorithm {
+	case AlgorithmFixedWindow:
+		cmd = fixedWindowScript.Run(ctx, rdb, []string{redisKey}, windowMs, limit.Limit)
+	case AlgorithmTokenBucket:
+		cmd = tokenBucketScript.Run(ctx, rdb, []string{redisKey}, windowMs, limit.Limit)
+	case AlgorithmSlidingWindow, "":
+		member, err := randomMember()
+		if err != nil {
+			return nil, err
+		}
+		cmd = slidingWindowScript.Run(ctx, rdb, []string{redisKey}, windowMs, limit.Limit, member)
+	default:
+		return nil, ValidateRateLimitAlgorithm(limit.Algorithm)
+	}
+
+	values, err := cmd.Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	return &RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      limit.Limit,
+		Remaining:  int(max(values[1], 0)),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
+
+// ValidateRateLimitAlgorithm returns an error unless algorithm is one of the
+// supported algorithms or empty, which selects the sliding window
+func ValidateRateLimitAlgorithm(algorithm string) error {
+	switch algorithm {
+	case AlgorithmFixedWindow, AlgorithmSlidingWindow, AlgorithmTokenBucket, "":
+		return nil
+	default:
+		return fmt.Errorf("unknown rate limit algorithm %q, expected %s, %s or %s",
+			algorithm, AlgorithmFixedWindow, AlgorithmSlidingWindow, AlgorithmTokenBucket)
+	}
+}
+
+// randomMember returns a unique sorted-set member so concurrent requests in
+// the same millisecond are all counted
+func randomMember() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate rate limit member: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
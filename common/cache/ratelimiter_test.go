@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRateLimiter(t *testing.T) (*RateLimiter, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewRedisClient(RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRateLimiter(client, "ratelimit"), mr
+}
+
+// step is one call to Allow at an offset from the start of the test
+type step struct {
+	at            time.Duration
+	wantAllowed   bool
+	wantRemaining int
+	wantRetry     time.Duration
+	wantReset     time.Duration
+}
+
+func runSteps(t *testing.T, limiter *RateLimiter, mr *miniredis.Miniredis, limit RateLimit, steps []step) {
+	t.Helper()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var elapsed time.Duration
+	for i, s := range steps {
+		// TIME in the scripts follows SetTime, key TTLs follow FastForward
+		mr.SetTime(start.Add(s.at))
+		mr.FastForward(s.at - elapsed)
+		elapsed = s.at
+
+		result, err := limiter.Allow(context.Background(), "caller", limit)
+		if err != nil {
+			t.Fatalf("step %d: Allow() error = %v", i, err)
+		}
+		if result.Allowed != s.wantAllowed || result.Remaining != s.wantRemaining ||
+			result.RetryAfter != s.wantRetry || result.ResetAfter != s.wantReset || result.Limit != limit.Limit {
+			t.Fatalf("step %d at %s: Allow() = %+v, want allowed %v, remaining %d, retry %s, reset %s",
+				i, s.at, result, s.wantAllowed, s.wantRemaining, s.wantRetry, s.wantReset)
+		}
+	}
+}
+
+func TestRateLimiterFixedWindow(t *testing.T) {
+	limiter, mr := newTestRateLimiter(t)
+	limit := RateLimit{Algorithm: AlgorithmFixedWindow, Limit: 3, Window: time.Minute}
+
+	runSteps(t, limiter, mr, limit, []step{
+		{at: 0, wantAllowed: true, wantRemaining: 2, wantReset: time.Minute},
+		{at: 10 * time.Second, wantAllowed: true, wantRemaining: 1, wantReset: 50 * time.Second},
+		{at: 20 * time.Second, wantAllowed: true, wantRemaining: 0, wantReset: 40 * time.Second},
+		{at: 30 * time.Second, wantAllowed: false, wantRemaining: 0, wantRetry: 30 * time.Second, wantReset: 30 * time.Second},
+		// The window restarts once its key expires
+		{at: 61 * time.Second, wantAllowed: true, wantRemaining: 2, wantReset: time.Minute},
+	})
+
+	mr.FastForward(time.Minute)
+	if mr.Exists("ratelimit:fixed_window:caller") {
+		t.Fatal("fixed window key still exists after the window")
+	}
+}
+
+func TestRateLimiterSlidingWindow(t *testing.T) {
+	limiter, mr := newTestRateLimiter(t)
+	limit := RateLimit{Algorithm: AlgorithmSlidingWindow, Limit: 2, Window: 10 * time.Second}
+
+	runSteps(t, limiter, mr, limit, []step{
+		{at: 0, wantAllowed: true, wantRemaining: 1, wantReset: 10 * time.Second},
+		{at: 4 * time.Second, wantAllowed: true, wantRemaining: 0, wantReset: 6 * time.Second},
+		{at: 5 * time.Second, wantAllowed: false, wantRemaining: 0, wantRetry: 5 * time.Second, wantReset: 5 * time.Second},
+		// The first request leaves the window, the second is still in it
+		{at: 10*time.Second + time.Millisecond, wantAllowed: true, wantRemaining: 0, wantReset: 3999 * time.Millisecond},
+		{at: 12 * time.Second, wantAllowed: false, wantRemaining: 0, wantRetry: 2 * time.Second, wantReset: 2 * time.Second},
+	})
+
+	mr.FastForward(10 * time.Second)
+	if mr.Exists("ratelimit:sliding_window:caller") {
+		t.Fatal("sliding window key still exists after the window")
+	}
+}
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	limiter, mr := newTestRateLimiter(t)
+	// One token per second, up to four
+	limit := RateLimit{Algorithm: AlgorithmTokenBucket, Limit: 4, Window: 4 * time.Second}
+
+	runSteps(t, limiter, mr, limit, []step{
+		{at: 0, wantAllowed: true, wantRemaining: 3, wantReset: time.Second},
+		{at: 0, wantAllowed: true, wantRemaining: 2, wantReset: 2 * time.Second},
+		{at: 0, wantAllowed: true, wantRemaining: 1, wantReset: 3 * time.Second},
+		{at: 0, wantAllowed: true, wantRemaining: 0, wantReset: 4 * time.Second},
+		{at: 0, wantAllowed: false, wantRemaining: 0, wantRetry: time.Second, wantReset: 4 * time.Second},
+		// Two tokens refill in two seconds
+		{at: 2 * time.Second, wantAllowed: true, wantRemaining: 1, wantReset: 3 * time.Second},
+		{at: 2 * time.Second, wantAllowed: true, wantRemaining: 0, wantReset: 4 * time.Second},
+		{at: 2500 * time.Millisecond, wantAllowed: false, wantRemaining: 0, wantRetry: 500 * time.Millisecond, wantReset: 3500 * time.Millisecond},
+		// The bucket refills up to its capacity and no further
+		{at: 20 * time.Second, wantAllowed: true, wantRemaining: 3, wantReset: time.Second},
+	})
+
+	mr.FastForward(4 * time.Second)
+	if mr.Exists("ratelimit:token_bucket:caller") {
+		t.Fatal("token bucket key still exists after the window")
+	}
+}
+
+func TestRateLimiterKeysAreIndependent(t *testing.T) {
+	limiter, _ := newTestRateLimiter(t)
+	limit := RateLimit{Algorithm: AlgorithmFixedWindow, Limit: 1, Window: time.Minute}
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b"} {
+		result, err := limiter.Allow(ctx, key, limit)
+		if err != nil || !result.Allowed {
+			t.Fatalf("Allow(%s) = %+v, %v, want allowed", key, result, err)
+		}
+	}
+	if result, err := limiter.Allow(ctx, "a", limit); err != nil || result.Allowed {
+		t.Fatalf("second Allow(a) = %+v, %v, want denied", result, err)
+	}
+}
+
+func TestRateLimiterErrors(t *testing.T) {
+	limiter, _ := newTestRateLimiter(t)
+	ctx := context.Background()
+
+	if _, err := limiter.Allow(ctx, "caller", RateLimit{Algorithm: "leaky_bucket", Limit: 1, Window: time.Second}); err == nil {
+		t.Fatal("Allow() with an unknown algorithm succeeded")
+	}
+	if _, err := limiter.Allow(ctx, "caller", RateLimit{Algorithm: AlgorithmFixedWindow, Window: time.Second}); err == nil {
+		t.Fatal("Allow() with a zero limit succeeded")
+	}
+
+	disconnected := NewRateLimiter(&RedisClient{}, "ratelimit")
+	_, err := disconnected.Allow(ctx, "caller", RateLimit{Algorithm: AlgorithmFixedWindow, Limit: 1, Window: time.Second})
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Allow() while disconnected error = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestValidateRateLimitAlgorithm(t *testing.T) {
+	for _, algorithm := range []string{"", AlgorithmFixedWindow, AlgorithmSlidingWindow, AlgorithmTokenBucket} {
+		if err := ValidateRateLimitAlgorithm(algorithm); err != nil {
+			t.Errorf("ValidateRateLimitAlgorithm(%q) error = %v", algorithm, err)
+		}
+	}
+	if err := ValidateRateLimitAlgorithm("sliding-window"); err == nil {
+		t.Error(`ValidateRateLimitAlgorithm("sliding-window") succeeded`)
+	}
+}
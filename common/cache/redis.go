@@ -3,17 +3,46 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
-// RedisClient wraps Redis client with helper methods
+var (
+	// ErrKeyNotFound is returned by Get and GetJSON when the key does not exist
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrUnavailable is returned while the client is not connected
+	ErrUnavailable = errors.New("redis unavailable")
+)
+
+// deleteIfEqualsScript deletes KEYS[1] only if it still holds ARGV[1]
+var deleteIfEqualsScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// setWithLeaseScript sets KEYS[1] to ARGV[2] for ARGV[3] milliseconds and
+// releases the lease in KEYS[2], but only while it still holds ARGV[1]
+var setWithLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[2]) == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+	redis.call('DEL', KEYS[2])
+	return 1
+end
+return 0
+`)
+
+// RedisClient wraps Redis client with helper methods. The zero value (and a
+// nil pointer) is a disconnected client whose operations return ErrUnavailable
+// until Connect succeeds
 type RedisClient struct {
-	client *redis.Client
-	ctx    context.Context
+	client atomic.Pointer[redis.Client]
 }
 
 // RedisConfig holds Redis configuration
@@ -27,6 +56,16 @@ type RedisConfig struct {
 
 // NewRedisClient creates a new Redis client
 func NewRedisClient(config RedisConfig) (*RedisClient, error) {
+	r := &RedisClient{}
+	if err := r.Connect(config); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Connect dials Redis and, once it answers, makes the client use the new
+// connection. It is safe to call while other goroutines use the client
+func (r *RedisClient) Connect(config RedisConfig) error {
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     fmt.Sprintf("%s:%d", config.Host, config.Port),
 		Password: config.Password,
@@ -34,30 +73,50 @@ func NewRedisClient(config RedisConfig) (*RedisClient, error) {
 		PoolSize: config.PoolSize,
 	})
 
-	ctx := context.Background()
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		_ = rdb.Close()
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	if old := r.client.Swap(rdb); old != nil {
+		_ = old.Close()
 	}
 
 	logx.Infof("Successfully connected to Redis at %s:%d", config.Host, config.Port)
+	return nil
+}
 
-	return &RedisClient{
-		client: rdb,
-		ctx:    ctx,
-	}, nil
+// rdb returns the connected client or ErrUnavailable
+func (r *RedisClient) rdb() (*redis.Client, error) {
+	if r == nil {
+		return nil, ErrUnavailable
+	}
+	if c := r.client.Load(); c != nil {
+		return c, nil
+	}
+	return nil, ErrUnavailable
 }
 
 // Get retrieves a value from cache
 func (r *RedisClient) Get(key string) (string, error) {
-	val, err := r.client.Get(r.ctx, key).Result()
+	rdb, err := r.rdb()
+	if err != nil {
+		return "", err
+	}
+	val, err := rdb.Get(context.Background(), key).Result()
 	if err == redis.Nil {
-		return "", fmt.Errorf("key not found: %s", key)
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, key)
 	}
 	return val, err
 }
 
 // Set sets a value in cache with expiration
 func (r *RedisClient) Set(key string, value interface{}, expiration time.Duration) error {
+	rdb, err := r.rdb()
+	if err != nil {
+		return err
+	}
+
 	var val string
 	switch v := value.(type) {
 	case string:
@@ -70,17 +129,68 @@ func (r *RedisClient) Set(key string, value interface{}, expiration time.Duratio
 		val = string(bytes)
 	}
 
-	return r.client.Set(r.ctx, key, val, expiration).Err()
+	return rdb.Set(context.Background(), key, val, expiration).Err()
+}
+
+// SetNX sets a value only if the key does not exist and reports whether it was set
+func (r *RedisClient) SetNX(key string, value string, expiration time.Duration) (bool, error) {
+	rdb, err := r.rdb()
+	if err != nil {
+		return false, err
+	}
+	return rdb.SetNX(context.Background(), key, value, expiration).Result()
+}
+
+// DeleteIfEquals removes a key only if it still holds value, e.g. to release a lock
+func (r *RedisClient) DeleteIfEquals(key string, value string) (bool, error) {
+	rdb, err := r.rdb()
+	if err != nil {
+		return false, err
+	}
+	deleted, err := deleteIfEqualsScript.Run(context.Background(), rdb, []string{key}, value).Int()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+// SetJSONWithLease marshals value and sets it under key only while leaseKey
+// still holds token, and reports whether it was set. Deleting leaseKey
+// revokes the lease, so a value read before an invalidation cannot be
+// written after it
+func (r *RedisClient) SetJSONWithLease(key, leaseKey, token string, value interface{}, expiration time.Duration) (bool, error) {
+	rdb, err := r.rdb()
+	if err != nil {
+		return false, err
+	}
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	set, err := setWithLeaseScript.Run(context.Background(), rdb, []string{key, leaseKey},
+		token, string(bytes), expiration.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return set > 0, nil
 }
 
 // Delete removes a key from cache
 func (r *RedisClient) Delete(key string) error {
-	return r.client.Del(r.ctx, key).Err()
+	rdb, err := r.rdb()
+	if err != nil {
+		return err
+	}
+	return rdb.Del(context.Background(), key).Err()
 }
 
 // Exists checks if a key exists
 func (r *RedisClient) Exists(key string) (bool, error) {
-	count, err := r.client.Exists(r.ctx, key).Result()
+	rdb, err := r.rdb()
+	if err != nil {
+		return false, err
+	}
+	count, err := rdb.Exists(context.Background(), key).Result()
 	if err != nil {
 		return false, err
 	}
@@ -105,13 +215,30 @@ func (r *RedisClient) SetJSON(key string, value interface{}, expiration time.Dur
 	return r.Set(key, string(bytes), expiration)
 }
 
+// Ping checks that Redis is reachable
+func (r *RedisClient) Ping(ctx context.Context) error {
+	rdb, err := r.rdb()
+	if err != nil {
+		return err
+	}
+	return rdb.Ping(ctx).Err()
+}
+
 // Close closes the Redis connection
 func (r *RedisClient) Close() error {
-	return r.client.Close()
+	if r == nil {
+		return nil
+	}
+	if c := r.client.Swap(nil); c != nil {
+		return c.Close()
+	}
+	return nil
 }
 
-// GetClient returns the underlying Redis client
+// GetClient returns the underlying Redis client, or nil while disconnected
 func (r *RedisClient) GetClient() *redis.Client {
-	return r.client
+	if r == nil {
+		return nil
+	}
+	return r.client.Load()
 }
-
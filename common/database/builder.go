@@ -0,0 +1,96 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SelectBuilder builds a parameterized SELECT statement. Every SQL fragment
+// passed to it must be a constant chosen by the caller; values, including all
+// user input, are only accepted as args and bound to $n placeholders
+type SelectBuilder struct {
+	columns string
+	from    string
+	where   []string
+	orderBy []string
+	limit   string
+	args    []interface{}
+}
+
+// Select starts a statement selecting columns
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: strings.Join(columns, ", ")}
+}
+
+// From sets the table
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.from = table
+	return b
+}
+
+// Where adds a condition joined with AND. Each "?" in cond is bound to the
+// next arg
+func (b *SelectBuilder) Where(cond string, args ...interface{}) *SelectBuilder {
+	b.where = append(b.where, "("+b.bind(cond, args)+")")
+	return b
+}
+
+// OrderBy appends ORDER BY expressions
+func (b *SelectBuilder) OrderBy(exprs ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, exprs...)
+	return b
+}
+
+// Limit binds the row limit
+func (b *SelectBuilder) Limit(n int64) *SelectBuilder {
+	b.limit = b.bind("?", []interface{}{n})
+	return b
+}
+
+// Build returns the SQL and its args
+func (b *SelectBuilder) Build() (string, []interface{}) {
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	sb.WriteString(b.columns)
+	sb.WriteString(" FROM ")
+	sb.WriteString(b.from)
+	if len(b.where) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(b.where, " AND "))
+	}
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(strings.Join(b.orderBy, ", "))
+	}
+	if b.limit != "" {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(b.limit)
+	}
+	return sb.String(), b.args
+}
+
+// bind replaces each "?" in fragment with the next $n placeholder and records
+// the matching arg
+func (b *SelectBuilder) bind(fragment string, args []interface{}) string {
+	var sb strings.Builder
+	for _, arg := range args {
+		i := strings.IndexByte(fragment, '?')
+		if i < 0 {
+			panic("database: more args than placeholders in " + fragment)
+		}
+		b.args = append(b.args, arg)
+		sb.WriteString(fragment[:i])
+		sb.WriteString("$" + strconv.Itoa(len(b.args)))
+		fragment = fragment[i+1:]
+	}
+	if strings.IndexByte(fragment, '?') >= 0 {
+		panic("database: fewer args than placeholders")
+	}
+	sb.WriteString(fragment)
+	return sb.String()
+}
+
+// EscapeLike escapes the LIKE wildcards in s so it matches literally
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
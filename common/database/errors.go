@@ -0,0 +1,125 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	common_errors "github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/lib/pq"
+)
+
+// Kinds of database errors, matched with errors.Is on the result of Classify
+var (
+	ErrUniqueViolation     = errors.New("unique constraint violated")
+	ErrForeignKeyViolation = errors.New("foreign key constraint violated")
+	ErrNotNullViolation    = errors.New("not-null constraint violated")
+	// ErrRetryable marks serialization failures, deadlocks and lock timeouts,
+	// after which the whole transaction can be run again
+	ErrRetryable = errors.New("transaction can be retried")
+)
+
+// kinds maps SQLSTATE codes to the error kinds above
+var kinds = map[pq.ErrorCode]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23502": ErrNotNullViolation,
+	"40001": ErrRetryable, // serialization_failure
+	"40P01": ErrRetryable, // deadlock_detected
+	"55P03": ErrRetryable, // lock_not_available
+}
+
+// Error is a classified database error. It matches its Kind and its driver
+// cause with errors.Is and errors.As
+type Error struct {
+	Kind       error
+	Table      string
+	Constraint string
+	// Field is the column the error is about, when Postgres reports one
+	Field string
+	Cause error
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Cause.Error()
+	}
+	return e.Kind.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Kind, e.Cause}
+	}
+	return []error{e.Kind}
+}
+
+// detailKey extracts the columns from details like
+// "Key (email)=(a@example.com) already exists."
+var detailKey = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// Classify wraps a Postgres error of a known kind in an *Error and returns
+// any other error unchanged
+func Classify(err error) error {
+	var dbErr *Error
+	if err == nil || errors.As(err, &dbErr) {
+		return err
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	kind, ok := kinds[pqErr.Code]
+	if !ok {
+		return err
+	}
+
+	field := pqErr.Column
+	if match := detailKey.FindStringSubmatch(pqErr.Detail); field == "" && match != nil {
+		field = match[1]
+	}
+	return &Error{
+		Kind:       kind,
+		Table:      pqErr.Table,
+		Constraint: pqErr.Constraint,
+		Field:      field,
+		Cause:      err,
+	}
+}
+
+// IsRetryable reports whether err is a serialization failure, deadlock or
+// lock timeout, after which the whole transaction can be run again
+func IsRetryable(err error) bool {
+	return errors.Is(Classify(err), ErrRetryable)
+}
+
+// AppError maps a classified error to the error returned to clients, naming
+// the resource and field it is about. It returns nil for other errors
+func AppError(err error, resource string) *common_errors.Error {
+	var dbErr *Error
+	if !errors.As(Classify(err), &dbErr) {
+		return nil
+	}
+	field := dbErr.Field
+	if field == "" {
+		field = "value"
+	}
+
+	switch dbErr.Kind {
+	case ErrUniqueViolation:
+		return common_errors.ErrConflict.
+			WithDetails(fmt.Sprintf("%s with this %s already exists", resource, field)).
+			WithViolations(common_errors.FieldViolation{Field: field, Description: "already exists"})
+	case ErrForeignKeyViolation:
+		return common_errors.ErrBadRequest.
+			WithDetails(fmt.Sprintf("Referenced %s does not exist", field)).
+			WithViolations(common_errors.FieldViolation{Field: field, Description: "does not exist"})
+	case ErrNotNullViolation:
+		return common_errors.ErrBadRequest.
+			WithDetails(fmt.Sprintf("%s is required", field)).
+			WithViolations(common_errors.FieldViolation{Field: field, Description: "is required"})
+	case ErrRetryable:
+		return common_errors.ErrConflict.WithDetails(resource + " was modified concurrently, retry the request")
+	}
+	return nil
+}
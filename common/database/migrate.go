@@ -0,0 +1,354 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+var (
+	// ErrChecksumMismatch is returned when an applied migration file was
+	// edited afterwards. Restore the file, or record the new checksum with Force
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+	// ErrUnknownMigration is returned when the database records a version
+	// that has no migration file
+	ErrUnknownMigration = errors.New("applied migration has no file")
+	// ErrNoDownMigration is returned when rolling back a migration without a
+	// down file
+	ErrNoDownMigration = errors.New("migration has no down file")
+)
+
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change loaded from
+// "<version>_<name>.up.sql" and an optional "<version>_<name>.down.sql"
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the hex SHA-256 of Up
+	Checksum string
+}
+
+// MigrationStatus describes one migration known to the files or the database
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Modified is set when the file changed after it was applied
+	Modified bool
+	// Missing is set when the version was applied but has no file
+	Missing bool
+}
+
+// LoadMigrations reads the migration files at the root of fsys, ordered by version
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies one service's migrations. Several services can share a
+// database: versions are recorded per service in schema_migrations, and a
+// per-service advisory lock keeps replicas from migrating concurrently
+type Migrator struct {
+	db         *sql.DB
+	service    string
+	lockID     int64
+	migrations []Migration
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// NewMigrator loads the migrations in fsys for service
+func NewMigrator(db *sql.DB, fsys fs.FS, service string) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	h := fnv.New64a()
+	h.Write([]byte("schema_migrations:" + service))
+	return &Migrator{
+		db:         db,
+		service:    service,
+		lockID:     int64(h.Sum64()),
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies all pending migrations in version order, each in its own
+// transaction, and returns the ones applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn, applied map[int64]appliedMigration) error {
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			logx.Infof("Applying migration %s %d_%s", m.service, mig.Version, mig.Name)
+			err := m.inTx(ctx, conn, mig.Up, `
+				INSERT INTO schema_migrations (service, version, name, checksum)
+				VALUES ($1, $2, $3, $4)
+			`, m.service, mig.Version, mig.Name, mig.Checksum)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the n most recently applied migrations and returns them
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn, applied map[int64]appliedMigration) error {
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < n; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDownMigration, mig.Version, mig.Name)
+			}
+			logx.Infof("Rolling back migration %s %d_%s", m.service, mig.Version, mig.Name)
+			err := m.inTx(ctx, conn, mig.Down, `
+				DELETE FROM schema_migrations WHERE service = $1 AND version = $2
+			`, m.service, mig.Version)
+			if err != nil {
+				return fmt.Errorf("rollback of %d_%s failed: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Force records migrations up to version as applied with their current
+// checksums and forgets those above it, without running any SQL. Use it to
+// baseline an existing schema or to accept an edited file
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn, _ map[int64]appliedMigration) error {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE service = $1 AND version > $2`,
+			m.service, version)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO schema_migrations (service, version, name, checksum)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (service, version) DO UPDATE SET name = EXCLUDED.name, checksum = EXCLUDED.checksum
+			`, m.service, mig.Version, mig.Name, mig.Checksum)
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// Status lists every migration file and every applied version, by version
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(_ *sql.Conn, applied map[int64]appliedMigration) error {
+		for _, mig := range m.migrations {
+			s := MigrationStatus{Version: mig.Version, Name: mig.Name}
+			if a, ok := applied[mig.Version]; ok {
+				s.Applied, s.AppliedAt, s.Modified = true, a.appliedAt, a.checksum != mig.Checksum
+			}
+			statuses = append(statuses, s)
+		}
+		for version, a := range applied {
+			if m.find(version) == nil {
+				statuses = append(statuses, MigrationStatus{
+					Version: version, Name: a.name, Applied: true, AppliedAt: a.appliedAt, Missing: true,
+				})
+			}
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+// withLock runs fn on a dedicated connection holding the service's advisory
+// lock, after making sure schema_migrations exists
+func (m *Migrator) withLock(ctx context.Context, fn func(*sql.Conn, map[int64]appliedMigration) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Advisory locks belong to the session, so lock and unlock on this conn
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, m.lockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, m.lockID); err != nil {
+			logx.Errorf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			service VARCHAR(100) NOT NULL,
+			version BIGINT NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (service, version)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT version, name, checksum, applied_at FROM schema_migrations WHERE service = $1
+	`, m.service)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var version int64
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return err
+		}
+		applied[version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+// verify fails when an applied migration was edited or removed
+func (m *Migrator) verify(applied map[int64]appliedMigration) error {
+	for version, a := range applied {
+		mig := m.find(version)
+		if mig == nil {
+			return fmt.Errorf("%w: %d_%s", ErrUnknownMigration, version, a.name)
+		}
+		if mig.Checksum != a.checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// inTx runs the migration script and the bookkeeping statement atomically
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
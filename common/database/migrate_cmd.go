@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// MigrateUsage describes the arguments accepted by RunMigrateCommand
+const MigrateUsage = `usage: migrate <command>
+  up        apply all pending migrations
+  down [N]  roll back the last N migrations (default 1)
+  status    list migrations and whether they are applied
+  force V   mark migrations up to version V as applied without running them`
+
+// RunMigrateCommand runs a migrate subcommand and writes its report to w
+func RunMigrateCommand(ctx context.Context, m *Migrator, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", MigrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			fmt.Fprintf(w, "applied %d_%s\n", mig.Version, mig.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(w, "no pending migrations")
+		}
+		return err
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		rolledBack, err := m.Down(ctx, n)
+		for _, mig := range rolledBack {
+			fmt.Fprintf(w, "rolled back %d_%s\n", mig.Version, mig.Name)
+		}
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", ""
+			switch {
+			case s.Missing:
+				state = "applied, file missing"
+			case s.Modified:
+				state = "applied, modified"
+			case s.Applied:
+				state = "applied"
+			}
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		return tw.Flush()
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("force needs a version\n%s", MigrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := m.Force(ctx, version); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "forced version %d\n", version)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], MigrateUsage)
+	}
+}
@@ -0,0 +1,303 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var testMigrations = fstest.MapFS{
+	"001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id BIGINT)")},
+	"001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+	"002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD email TEXT")},
+	"002_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP email")},
+	"003_add_index.up.sql":      {Data: []byte("CREATE INDEX users_email ON users (email)")},
+	"README.md":                 {Data: []byte("ignored")},
+}
+
+func checksum(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	m, err := NewMigrator(db, testMigrations, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, mock
+}
+
+// appliedRow is a schema_migrations row of the user service
+type appliedRow struct {
+	version  int64
+	name     string
+	checksum string
+}
+
+// expectLocked expects the lock and the read of applied migrations
+func expectLocked(mock sqlmock.Sqlmock, m *Migrator, applied ...appliedRow) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(m.lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"})
+	for _, a := range applied {
+		rows.AddRow(a.version, a.name, a.checksum, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	}
+	mock.ExpectQuery(`SELECT version, name, checksum, applied_at FROM schema_migrations`).
+		WithArgs("user").WillReturnRows(rows)
+}
+
+func expectUnlocked(mock sqlmock.Sqlmock, m *Migrator) {
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(m.lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("loaded %d migrations, want 3", len(migrations))
+	}
+	for i, want := range []string{"create_users", "add_email", "add_index"} {
+		if mig := migrations[i]; mig.Version != int64(i+1) || mig.Name != want || mig.Checksum != checksum(mig.Up) {
+			t.Fatalf("migration %d = %d_%s with checksum %s", i, mig.Version, mig.Name, mig.Checksum)
+		}
+	}
+	if migrations[2].Down != "" {
+		t.Fatalf("migration 3 down = %q, want none", migrations[2].Down)
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"invalid name":    {"1_Users.up.sql": {Data: []byte("SELECT 1")}},
+		"zero version":    {"0_users.up.sql": {Data: []byte("SELECT 1")}},
+		"no up file":      {"001_users.down.sql": {Data: []byte("SELECT 1")}},
+		"different names": {"001_users.up.sql": {Data: []byte("SELECT 1")}, "001_people.down.sql": {Data: []byte("SELECT 1")}},
+	} {
+		if _, err := LoadMigrations(fsys); err == nil {
+			t.Errorf("LoadMigrations() with %s succeeded, want an error", name)
+		}
+	}
+}
+
+func TestMigratorLockPerService(t *testing.T) {
+	user, _ := newTestMigrator(t)
+	order, err := NewMigrator(nil, testMigrations, "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.lockID == order.lockID {
+		t.Fatalf("services share the advisory lock %d", user.lockID)
+	}
+
+	// Nothing runs without the lock
+	m, mock := newTestMigrator(t)
+	errLock := errors.New("lock timeout")
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(m.lockID).WillReturnError(errLock)
+	if _, err := m.Up(context.Background()); !errors.Is(err, errLock) {
+		t.Fatalf("Up() error = %v, want %v", err, errLock)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateUp(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	expectLocked(mock, m, appliedRow{1, "create_users", checksum("CREATE TABLE users (id BIGINT)")})
+	for _, mig := range m.migrations[1:] {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(mig.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO schema_migrations`).
+			WithArgs("user", mig.Version, mig.Name, mig.Checksum).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	expectUnlocked(mock, m)
+
+	var out bytes.Buffer
+	if err := RunMigrateCommand(context.Background(), m, []string{"up"}, &out); err != nil {
+		t.Fatalf("up error = %v", err)
+	}
+	if want := "applied 2_add_email\napplied 3_add_index\n"; out.String() != want {
+		t.Fatalf("up output = %q, want %q", out.String(), want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateUpStopsAtFailure(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	errFailed := errors.New("syntax error")
+	expectLocked(mock, m)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(m.migrations[0].Up)).WillReturnError(errFailed)
+	mock.ExpectRollback()
+	expectUnlocked(mock, m)
+
+	applied, err := m.Up(context.Background())
+	if !errors.Is(err, errFailed) || len(applied) != 0 {
+		t.Fatalf("Up() = %v, %v, want nothing applied and %v", applied, err, errFailed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateRefusesChangedHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied appliedRow
+		want    error
+	}{
+		{name: "modified file", applied: appliedRow{1, "create_users", checksum("CREATE TABLE users ()")}, want: ErrChecksumMismatch},
+		{name: "missing file", applied: appliedRow{9, "dropped", checksum("SELECT 1")}, want: ErrUnknownMigration},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, mock := newTestMigrator(t)
+			// Neither direction runs any migration, and the lock is released
+			expectLocked(mock, m, tt.applied)
+			expectUnlocked(mock, m)
+			expectLocked(mock, m, tt.applied)
+			expectUnlocked(mock, m)
+
+			if _, err := m.Up(context.Background()); !errors.Is(err, tt.want) {
+				t.Fatalf("Up() error = %v, want %v", err, tt.want)
+			}
+			if _, err := m.Down(context.Background(), 1); !errors.Is(err, tt.want) {
+				t.Fatalf("Down() error = %v, want %v", err, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	expectLocked(mock, m,
+		appliedRow{1, "create_users", m.migrations[0].Checksum},
+		appliedRow{2, "add_email", m.migrations[1].Checksum})
+	for _, mig := range []Migration{m.migrations[1], m.migrations[0]} {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(mig.Down)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`DELETE FROM schema_migrations WHERE service = \$1 AND version = \$2`).
+			WithArgs("user", mig.Version).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	expectUnlocked(mock, m)
+
+	var out bytes.Buffer
+	if err := RunMigrateCommand(context.Background(), m, []string{"down", "5"}, &out); err != nil {
+		t.Fatalf("down error = %v", err)
+	}
+	if want := "rolled back 2_add_email\nrolled back 1_create_users\n"; out.String() != want {
+		t.Fatalf("down output = %q, want %q", out.String(), want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateDownWithoutDownFile(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	var applied []appliedRow
+	for _, mig := range m.migrations {
+		applied = append(applied, appliedRow{mig.Version, mig.Name, mig.Checksum})
+	}
+	expectLocked(mock, m, applied...)
+	expectUnlocked(mock, m)
+
+	if _, err := m.Down(context.Background(), 1); !errors.Is(err, ErrNoDownMigration) {
+		t.Fatalf("Down() error = %v, want %v", err, ErrNoDownMigration)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateForce(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	// An edited file is accepted by recording its current checksum
+	expectLocked(mock, m, appliedRow{1, "create_users", checksum("CREATE TABLE users ()")})
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM schema_migrations WHERE service = \$1 AND version > \$2`).
+		WithArgs("user", int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, mig := range m.migrations[:2] {
+		mock.ExpectExec(`INSERT INTO schema_migrations (.+) ON CONFLICT`).
+			WithArgs("user", mig.Version, mig.Name, mig.Checksum).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+	expectUnlocked(mock, m)
+
+	var out bytes.Buffer
+	if err := RunMigrateCommand(context.Background(), m, []string{"force", "2"}, &out); err != nil {
+		t.Fatalf("force error = %v", err)
+	}
+	if want := "forced version 2\n"; out.String() != want {
+		t.Fatalf("force output = %q, want %q", out.String(), want)
+	}
+
+	// Unknown versions are refused before touching the database
+	if err := m.Force(context.Background(), 7); err == nil {
+		t.Fatal("Force(7) succeeded, want an error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateStatus(t *testing.T) {
+	m, mock := newTestMigrator(t)
+	expectLocked(mock, m,
+		appliedRow{1, "create_users", m.migrations[0].Checksum},
+		appliedRow{2, "add_email", checksum("ALTER TABLE users ADD mail TEXT")},
+		appliedRow{9, "dropped", checksum("SELECT 1")})
+	expectUnlocked(mock, m)
+
+	var out bytes.Buffer
+	if err := RunMigrateCommand(context.Background(), m, []string{"status"}, &out); err != nil {
+		t.Fatalf("status error = %v", err)
+	}
+	want := "VERSION  NAME          STATUS                 APPLIED AT\n" +
+		"1        create_users  applied                2024-05-01T00:00:00Z\n" +
+		"2        add_email     applied, modified      2024-05-01T00:00:00Z\n" +
+		"3        add_index     pending                \n" +
+		"9        dropped       applied, file missing  2024-05-01T00:00:00Z\n"
+	if out.String() != want {
+		t.Fatalf("status output =\n%s\nwant\n%s", out.String(), want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunMigrateCommandArgs(t *testing.T) {
+	m, _ := newTestMigrator(t)
+	for _, args := range [][]string{nil, {"sideways"}, {"down", "0"}, {"down", "x"}, {"force"}, {"force", "-1"}} {
+		if err := RunMigrateCommand(context.Background(), m, args, &bytes.Buffer{}); err == nil {
+			t.Errorf("RunMigrateCommand(%q) succeeded, want an error", args)
+		}
+	}
+}
@@ -22,7 +22,26 @@ type PostgresConfig struct {
 	ConnMaxIdleTime time.Duration
 }
 
+// NewPostgresConnection opens a connection pool and verifies the database is reachable
 func NewPostgresConnection(config PostgresConfig) (*sql.DB, error) {
+	db, err := OpenPostgres(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to ping postgres database: %w", err)
+	}
+
+	logx.Infof("Successfully connected to PostgreSQL database: %s", config.Database)
+	return db, nil
+}
+
+// OpenPostgres configures a connection pool without connecting. The pool
+// dials lazily and reconnects on its own, so it can be created while the
+// database is down
+func OpenPostgres(config PostgresConfig) (*sql.DB, error) {
 	dsn := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		config.Host,
@@ -51,11 +70,6 @@ func NewPostgresConnection(config PostgresConfig) (*sql.DB, error) {
 		db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping postgres database: %w", err)
-	}
-
-	logx.Infof("Successfully connected to PostgreSQL database: %s", config.Database)
 	return db, nil
 }
 
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// DBTX is implemented by *sql.DB and *sql.Tx, so queries run the same inside
+// or outside a transaction
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var (
+	_ DBTX = (*sql.DB)(nil)
+	_ DBTX = (*sql.Tx)(nil)
+)
+
+// TxOptions configures the transactions started by a TxManager
+type TxOptions struct {
+	// Isolation defaults to the database default, READ COMMITTED on Postgres
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// MaxRetries is how many more times a transaction that fails with an
+	// ErrRetryable error is run
+	MaxRetries int
+}
+
+// retryBaseDelay is the first backoff between retries; it doubles each time
+const retryBaseDelay = 10 * time.Millisecond
+
+// ParseIsolationLevel parses "read_committed", "repeatable_read" or
+// "serializable", with spaces or dashes also accepted
+func ParseIsolationLevel(value string) (sql.IsolationLevel, error) {
+	switch strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(value))) {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "read_committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable_read":
+		return sql.LevelRepeatableRead, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("unknown isolation level %q", value)
+	}
+}
+
+// TxManager runs functions in transactions carried by the context. Code that
+// reads its connection with Conn joins the transaction without knowing about
+// it, and nested WithinTx calls become savepoints
+type TxManager struct {
+	db       *sql.DB
+	defaults TxOptions
+}
+
+// txKey is keyed by the pool so transactions on different databases do not mix
+type txKey struct {
+	db *sql.DB
+}
+
+type txState struct {
+	tx          *sql.Tx
+	savepoints  int
+	afterCommit []func()
+}
+
+func NewTxManager(db *sql.DB, defaults TxOptions) *TxManager {
+	return &TxManager{
+		db:       db,
+		defaults: defaults,
+	}
+}
+
+// Conn returns the transaction in ctx, or the pool outside a transaction
+func (m *TxManager) Conn(ctx context.Context) DBTX {
+	if state, ok := ctx.Value(txKey{m.db}).(*txState); ok {
+		return state.tx
+	}
+	return m.db
+}
+
+// InTx reports whether ctx carries a transaction of m
+func (m *TxManager) InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{m.db}).(*txState)
+	return ok
+}
+
+// AfterCommit runs fn once the transaction in ctx has committed, or right
+// away outside a transaction. fn is dropped if the transaction, or the
+// savepoint it was registered in, rolls back
+func (m *TxManager) AfterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txKey{m.db}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// WithinTx runs fn in a transaction with the default options. See WithinTxOptions
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return m.WithinTxOptions(ctx, m.defaults, fn)
+}
+
+// WithinTxOptions runs fn in a transaction that is committed if fn returns
+// nil and rolled back otherwise. fn must use the ctx it is given.
+//
+// Inside another transaction fn runs in a savepoint instead: its failure only
+// undoes its own work, and opts are ignored. An outermost transaction that
+// fails with an error IsRetryable accepts is run again, up to
+// opts.MaxRetries times, so fn must be safe to repeat
+func (m *TxManager) WithinTxOptions(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error {
+	if state, ok := ctx.Value(txKey{m.db}).(*txState); ok {
+		return m.withinSavepoint(ctx, state, fn)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := m.run(ctx, opts, fn)
+		if err == nil || attempt >= opts.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		delay := retryBaseDelay << attempt
+		delay += time.Duration(rand.Int63n(int64(delay)))
+		logx.WithContext(ctx).Infof("Retrying transaction in %s after: %v", delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (m *TxManager) run(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error {
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{m.db}, state)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, hook := range state.afterCommit {
+		hook()
+	}
+	return nil
+}
+
+func (m *TxManager) withinSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context) error) error {
+	state.savepoints++
+	name := fmt.Sprintf("sp_%d", state.savepoints)
+	defer func() { state.savepoints-- }()
+
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	// Hooks registered in the savepoint belong to the work it undoes
+	hooks := len(state.afterCommit)
+	if err := fn(ctx); err != nil {
+		state.afterCommit = state.afterCommit[:hooks]
+		if _, rbErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	_, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func newTestTxManager(t *testing.T, opts TxOptions) (*TxManager, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return NewTxManager(db, opts), mock
+}
+
+func expectExec(mock sqlmock.Sqlmock, query string) {
+	mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestWithinTxSavepoints(t *testing.T) {
+	m, mock := newTestTxManager(t, TxOptions{})
+	errFailed := errors.New("failed")
+	var ran []string
+	hook := func(name string) func() {
+		return func() { ran = append(ran, name) }
+	}
+
+	mock.ExpectBegin()
+	expectExec(mock, "SAVEPOINT sp_1")
+	expectExec(mock, "RELEASE SAVEPOINT sp_1")
+	expectExec(mock, "SAVEPOINT sp_1")
+	expectExec(mock, "SAVEPOINT sp_2")
+	expectExec(mock, "RELEASE SAVEPOINT sp_2")
+	expectExec(mock, "ROLLBACK TO SAVEPOINT sp_1")
+	mock.ExpectCommit()
+
+	err := m.WithinTx(context.Background(), func(ctx context.Context) error {
+		if !m.InTx(ctx) {
+			t.Fatal("InTx() = false inside WithinTx")
+		}
+		m.AfterCommit(ctx, hook("outer"))
+
+		if err := m.WithinTx(ctx, func(ctx context.Context) error {
+			m.AfterCommit(ctx, hook("released"))
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// The failed savepoint drops its hooks, including those of the
+		// savepoint released inside it
+		err := m.WithinTx(ctx, func(ctx context.Context) error {
+			m.AfterCommit(ctx, hook("rolled back"))
+			if err := m.WithinTx(ctx, func(ctx context.Context) error {
+				m.AfterCommit(ctx, hook("nested in rolled back"))
+				return nil
+			}); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) {
+			t.Fatalf("savepoint error = %v, want %v", err, errFailed)
+		}
+
+		m.AfterCommit(ctx, hook("last"))
+		if len(ran) != 0 {
+			t.Fatalf("hooks ran before commit: %v", ran)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+
+	if want := []string{"outer", "released", "last"}; !reflect.DeepEqual(ran, want) {
+		t.Fatalf("hooks ran = %v, want %v", ran, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithinTxRollbackDropsHooks(t *testing.T) {
+	m, mock := newTestTxManager(t, TxOptions{})
+	errFailed := errors.New("failed")
+	ran := false
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	err := m.WithinTx(context.Background(), func(ctx context.Context) error {
+		m.AfterCommit(ctx, func() { ran = true })
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errFailed)
+	}
+	if ran {
+		t.Fatal("hook ran after rollback")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAfterCommitOutsideTx(t *testing.T) {
+	m, _ := newTestTxManager(t, TxOptions{})
+	ctx := context.Background()
+
+	if m.InTx(ctx) {
+		t.Fatal("InTx() = true outside a transaction")
+	}
+	ran := false
+	m.AfterCommit(ctx, func() { ran = true })
+	if !ran {
+		t.Fatal("hook did not run right away outside a transaction")
+	}
+}
+
+func TestWithinTxRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxRetries   int
+		errs         []error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "serialization failure and deadlock",
+			maxRetries:   2,
+			errs:         []error{&pq.Error{Code: "40001"}, &pq.Error{Code: "40P01"}, nil},
+			wantAttempts: 3,
+		},
+		{
+			name:         "retries exhausted",
+			maxRetries:   1,
+			errs:         []error{&pq.Error{Code: "40001"}, &pq.Error{Code: "40001"}},
+			wantAttempts: 2,
+			wantErr:      true,
+		},
+		{
+			name:         "not retryable",
+			maxRetries:   2,
+			errs:         []error{&pq.Error{Code: "23505"}},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, mock := newTestTxManager(t, TxOptions{MaxRetries: tt.maxRetries})
+			for _, err := range tt.errs {
+				mock.ExpectBegin()
+				if err != nil {
+					mock.ExpectRollback()
+				} else {
+					mock.ExpectCommit()
+				}
+			}
+
+			attempts, hooks := 0, 0
+			err := m.WithinTx(context.Background(), func(ctx context.Context) error {
+				m.AfterCommit(ctx, func() { hooks++ })
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WithinTx() error = %v, want error %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			wantHooks := 1
+			if tt.wantErr {
+				wantHooks = 0
+			}
+			if hooks != wantHooks {
+				t.Fatalf("hooks ran %d times, want %d", hooks, wantHooks)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestWithinTxStopsRetryingWhenCancelled(t *testing.T) {
+	m, mock := newTestTxManager(t, TxOptions{MaxRetries: 3})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// database/sql may roll back the cancelled transaction from its own
+	// goroutine, so when the rollback reaches the mock is not checked
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	attempts := 0
+	err := m.WithinTx(ctx, func(ctx context.Context) error {
+		attempts++
+		cancel()
+		return &pq.Error{Code: "40001"}
+	})
+	if !IsRetryable(err) {
+		t.Fatalf("WithinTx() error = %v, want the serialization failure", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1 after cancellation", attempts)
+	}
+}
@@ -1,36 +1,52 @@
 package errors
 
 import (
-	"errors"
 	"net/http"
-
-	"github.com/zeromicro/go-zero/core/logx"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 var (
 	// Common errors
-	ErrNotFound      = NewError(http.StatusNotFound, "NOT_FOUND", "Resource not found")
-	ErrBadRequest    = NewError(http.StatusBadRequest, "BAD_REQUEST", "Invalid request")
-	ErrUnauthorized  = NewError(http.StatusUnauthorized, "UNAUTHORIZED", "Unauthorized")
-	ErrForbidden     = NewError(http.StatusForbidden, "FORBIDDEN", "Forbidden")
-	ErrInternalError = NewError(http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
-	ErrConflict      = NewError(http.StatusConflict, "CONFLICT", "Resource conflict")
+	ErrNotFound             = NewError(http.StatusNotFound, "NOT_FOUND", "Resource not found")
+	ErrBadRequest           = NewError(http.StatusBadRequest, "BAD_REQUEST", "Invalid request")
+	ErrUnauthorized         = NewError(http.StatusUnauthorized, "UNAUTHORIZED", "Unauthorized")
+	ErrForbidden            = NewError(http.StatusForbidden, "FORBIDDEN", "Forbidden")
+	ErrInternalError        = NewError(http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+	ErrConflict             = NewError(http.StatusConflict, "CONFLICT", "Resource conflict")
+	ErrPreconditionFailed   = NewError(http.StatusPreconditionFailed, "PRECONDITION_FAILED", "Precondition failed")
+	ErrPreconditionRequired = NewError(http.StatusPreconditionRequired, "PRECONDITION_REQUIRED", "Precondition required")
+	ErrTooManyRequests      = NewError(http.StatusTooManyRequests, "TOO_MANY_REQUESTS", "Too many requests")
+	ErrServiceUnavailable   = NewError(http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "Service unavailable")
+	ErrGatewayTimeout       = NewError(http.StatusGatewayTimeout, "GATEWAY_TIMEOUT", "Upstream request timed out")
+	ErrCanceled             = NewError(statusClientClosedRequest, "CANCELED", "Request canceled")
 )
 
 // Error represents a custom error with HTTP status code and error code
 type Error struct {
-	StatusCode int    `json:"status_code"`
-	Code       string `json:"code"`
-	Message    string `json:"message"`
-	Details    string `json:"details,omitempty"`
+	StatusCode int              `json:"status_code"`
+	Code       string           `json:"code"`
+	Message    string           `json:"message"`
+	Details    string           `json:"details,omitempty"`
+	Violations []FieldViolation `json:"violations,omitempty"`
+	// Cause is the underlying failure. It is logged by the server and never
+	// sent to clients
+	Cause error `json:"-"`
+}
+
+// FieldViolation describes why a single request field is invalid
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
 }
 
 func (e *Error) Error() string {
 	return e.Message
 }
 
+// Unwrap returns the Cause, so errors.Is and errors.As see through e
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
 // NewError creates a new error
 func NewError(statusCode int, code, message string) *Error {
 	return &Error{
@@ -47,46 +63,31 @@ func (e *Error) WithDetails(details string) *Error {
 		Code:       e.Code,
 		Message:    e.Message,
 		Details:    details,
+		Violations: e.Violations,
+		Cause:      e.Cause,
 	}
 }
 
-// ToGRPCError converts HTTP error to gRPC error
-func ToGRPCError(err error) error {
-	if err == nil {
-		return nil
-	}
-
-	var customErr *Error
-	if errors.As(err, &customErr) {
-		grpcCode := httpStatusToGRPCCode(customErr.StatusCode)
-		return status.Error(grpcCode, customErr.Message)
+// WithViolations adds field violations to the error
+func (e *Error) WithViolations(violations ...FieldViolation) *Error {
+	return &Error{
+		StatusCode: e.StatusCode,
+		Code:       e.Code,
+		Message:    e.Message,
+		Details:    e.Details,
+		Violations: append(append([]FieldViolation(nil), e.Violations...), violations...),
+		Cause:      e.Cause,
 	}
-
-	logx.Errorf("Unknown error type: %v", err)
-	return status.Error(codes.Internal, "Internal server error")
 }
 
-// httpStatusToGRPCCode converts HTTP status code to gRPC code
-func httpStatusToGRPCCode(httpCode int) codes.Code {
-	switch httpCode {
-	case http.StatusOK:
-		return codes.OK
-	case http.StatusBadRequest:
-		return codes.InvalidArgument
-	case http.StatusUnauthorized:
-		return codes.Unauthenticated
-	case http.StatusForbidden:
-		return codes.PermissionDenied
-	case http.StatusNotFound:
-		return codes.NotFound
-	case http.StatusConflict:
-		return codes.AlreadyExists
-	case http.StatusInternalServerError:
-		return codes.Internal
-	case http.StatusServiceUnavailable:
-		return codes.Unavailable
-	default:
-		return codes.Internal
+// WithCause records the underlying failure for logging
+func (e *Error) WithCause(cause error) *Error {
+	return &Error{
+		StatusCode: e.StatusCode,
+		Code:       e.Code,
+		Message:    e.Message,
+		Details:    e.Details,
+		Violations: e.Violations,
+		Cause:      cause,
 	}
 }
-
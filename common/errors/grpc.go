@@ -0,0 +1,202 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+const (
+	// errorInfoDomain identifies ErrorInfo details attached by ToGRPCError
+	errorInfoDomain = "go-zero-template"
+
+	metadataDetails    = "details"
+	metadataStatusCode = "status_code"
+
+	// statusClientClosedRequest is the de facto status for requests the client canceled
+	statusClientClosedRequest = 499
+)
+
+// ToGRPCError converts HTTP error to gRPC error. The Code, Details and field
+// violations travel as status details so FromGRPCError can rebuild the error
+func ToGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// An *Error is converted even when its Cause is a gRPC status, which
+	// status.FromError would find through Unwrap
+	var customErr *Error
+	if !errors.As(err, &customErr) {
+		// Already a gRPC status, e.g. returned by a downstream call
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		logx.Errorf("Unknown error type: %v", err)
+		return status.Error(codes.Internal, "Internal server error")
+	}
+
+	st := status.New(httpStatusToGRPCCode(customErr.StatusCode), customErr.Message)
+
+	metadata := map[string]string{
+		metadataStatusCode: strconv.Itoa(customErr.StatusCode),
+	}
+	if customErr.Details != "" {
+		metadata[metadataDetails] = customErr.Details
+	}
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason:   customErr.Code,
+		Domain:   errorInfoDomain,
+		Metadata: metadata,
+	}}
+
+	if len(customErr.Violations) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, v := range customErr.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+		details = append(details, badRequest)
+	}
+
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		logx.Errorf("Failed to attach error details: %v", err)
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+// FromGRPCError rebuilds the *Error sent by ToGRPCError. Statuses without
+// attached details, such as transport failures, are mapped by their gRPC code
+func FromGRPCError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var customErr *Error
+	if errors.As(err, &customErr) {
+		return customErr
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrCanceled
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return ErrInternalError
+	}
+
+	// Without ErrorInfo only client errors keep the status message, since
+	// server and transport messages may leak internals
+	result := fromGRPCCode(st.Code())
+	if result.StatusCode < http.StatusInternalServerError && st.Message() != "" {
+		result = result.WithDetails(st.Message())
+	}
+
+	for _, d := range st.Details() {
+		switch detail := d.(type) {
+		case *errdetails.ErrorInfo:
+			if detail.GetDomain() != errorInfoDomain {
+				continue
+			}
+			result = &Error{
+				StatusCode: result.StatusCode,
+				Code:       detail.GetReason(),
+				Message:    st.Message(),
+				Details:    detail.GetMetadata()[metadataDetails],
+				Violations: result.Violations,
+			}
+			if statusCode, err := strconv.Atoi(detail.GetMetadata()[metadataStatusCode]); err == nil {
+				result.StatusCode = statusCode
+			}
+		case *errdetails.BadRequest:
+			violations := make([]FieldViolation, 0, len(detail.GetFieldViolations()))
+			for _, v := range detail.GetFieldViolations() {
+				violations = append(violations, FieldViolation{
+					Field:       v.GetField(),
+					Description: v.GetDescription(),
+				})
+			}
+			result = result.WithViolations(violations...)
+		}
+	}
+
+	return result
+}
+
+// fromGRPCCode returns the common error for a gRPC code
+func fromGRPCCode(code codes.Code) *Error {
+	switch code {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return ErrBadRequest
+	case codes.Unauthenticated:
+		return ErrUnauthorized
+	case codes.PermissionDenied:
+		return ErrForbidden
+	case codes.NotFound:
+		return ErrNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return ErrConflict
+	case codes.ResourceExhausted:
+		return ErrTooManyRequests
+	case codes.FailedPrecondition:
+		return ErrPreconditionFailed
+	case codes.Unavailable:
+		return ErrServiceUnavailable
+	case codes.DeadlineExceeded:
+		return ErrGatewayTimeout
+	case codes.Canceled:
+		return ErrCanceled
+	case codes.Unimplemented:
+		return NewError(http.StatusNotImplemented, "NOT_IMPLEMENTED", "Not implemented")
+	default:
+		return ErrInternalError
+	}
+}
+
+// httpStatusToGRPCCode converts HTTP status code to gRPC code
+func httpStatusToGRPCCode(httpCode int) codes.Code {
+	switch httpCode {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusInternalServerError:
+		return codes.Internal
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,144 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFromGRPCErrorCodes(t *testing.T) {
+	tests := []struct {
+		code        codes.Code
+		wantStatus  int
+		wantCode    string
+		wantDetails string
+	}{
+		{code: codes.InvalidArgument, wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST", wantDetails: "status message"},
+		{code: codes.OutOfRange, wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST", wantDetails: "status message"},
+		{code: codes.Unauthenticated, wantStatus: http.StatusUnauthorized, wantCode: "UNAUTHORIZED", wantDetails: "status message"},
+		{code: codes.PermissionDenied, wantStatus: http.StatusForbidden, wantCode: "FORBIDDEN", wantDetails: "status message"},
+		{code: codes.NotFound, wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND", wantDetails: "status message"},
+		{code: codes.AlreadyExists, wantStatus: http.StatusConflict, wantCode: "CONFLICT", wantDetails: "status message"},
+		{code: codes.Aborted, wantStatus: http.StatusConflict, wantCode: "CONFLICT", wantDetails: "status message"},
+		{code: codes.ResourceExhausted, wantStatus: http.StatusTooManyRequests, wantCode: "TOO_MANY_REQUESTS", wantDetails: "status message"},
+		{code: codes.FailedPrecondition, wantStatus: http.StatusPreconditionFailed, wantCode: "PRECONDITION_FAILED", wantDetails: "status message"},
+		{code: codes.Canceled, wantStatus: statusClientClosedRequest, wantCode: "CANCELED", wantDetails: "status message"},
+		// Server and transport messages are not passed on
+		{code: codes.Unavailable, wantStatus: http.StatusServiceUnavailable, wantCode: "SERVICE_UNAVAILABLE"},
+		{code: codes.DeadlineExceeded, wantStatus: http.StatusGatewayTimeout, wantCode: "GATEWAY_TIMEOUT"},
+		{code: codes.Unimplemented, wantStatus: http.StatusNotImplemented, wantCode: "NOT_IMPLEMENTED"},
+		{code: codes.Internal, wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_ERROR"},
+		{code: codes.Unknown, wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_ERROR"},
+		{code: codes.DataLoss, wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			got := FromGRPCError(status.Error(tt.code, "status message"))
+			if got.StatusCode != tt.wantStatus || got.Code != tt.wantCode || got.Details != tt.wantDetails {
+				t.Fatalf("FromGRPCError() = %+v, want status %d, code %s, details %q",
+					got, tt.wantStatus, tt.wantCode, tt.wantDetails)
+			}
+
+			body := got.Response("")
+			if body.StatusCode != tt.wantStatus || body.Code != tt.wantCode || body.Message == "" {
+				t.Fatalf("Response() = %+v, want status %d and code %s with a message", body, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestFromGRPCErrorContext(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *Error
+	}{
+		{name: "deadline exceeded", err: fmt.Errorf("rpc: %w", context.DeadlineExceeded), want: ErrGatewayTimeout},
+		{name: "canceled", err: context.Canceled, want: ErrCanceled},
+		{name: "not a status", err: fmt.Errorf("dial tcp: connection refused"), want: ErrInternalError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromGRPCError(tt.err); got != tt.want {
+				t.Fatalf("FromGRPCError() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCErrorRoundTrip(t *testing.T) {
+	violation := FieldViolation{Field: "email", Description: "must be a valid email"}
+
+	tests := []struct {
+		err      *Error
+		wantCode codes.Code
+	}{
+		{err: ErrBadRequest.WithViolations(violation), wantCode: codes.InvalidArgument},
+		{err: NewError(http.StatusUnprocessableEntity, "UNPROCESSABLE", "Unprocessable"), wantCode: codes.InvalidArgument},
+		{err: ErrUnauthorized, wantCode: codes.Unauthenticated},
+		{err: ErrForbidden.WithDetails("Requires one of roles: admin"), wantCode: codes.PermissionDenied},
+		{err: ErrNotFound.WithDetails("User not found"), wantCode: codes.NotFound},
+		{err: NewError(http.StatusConflict, "EMAIL_TAKEN", "Email already registered"), wantCode: codes.AlreadyExists},
+		{err: ErrPreconditionFailed, wantCode: codes.FailedPrecondition},
+		{err: ErrTooManyRequests, wantCode: codes.ResourceExhausted},
+		{err: ErrInternalError, wantCode: codes.Internal},
+		{err: ErrServiceUnavailable, wantCode: codes.Unavailable},
+		{err: ErrGatewayTimeout, wantCode: codes.DeadlineExceeded},
+		// Statuses without a gRPC equivalent keep their HTTP status
+		{err: NewError(http.StatusTeapot, "TEAPOT", "I'm a teapot"), wantCode: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Code, func(t *testing.T) {
+			grpcErr := ToGRPCError(tt.err)
+			if got := status.Code(grpcErr); got != tt.wantCode {
+				t.Fatalf("ToGRPCError() code = %s, want %s", got, tt.wantCode)
+			}
+
+			got := FromGRPCError(grpcErr)
+			if !reflect.DeepEqual(got.Response("request-1"), tt.err.Response("request-1")) {
+				t.Fatalf("FromGRPCError(ToGRPCError()) = %+v, want %+v", got, tt.err)
+			}
+		})
+	}
+}
+
+func TestToGRPCErrorWrappedStatus(t *testing.T) {
+	downstream := status.Error(codes.Unavailable, "dial tcp 10.0.0.5:5432: connection refused")
+
+	// An *Error caused by a downstream status is sent as itself
+	want := ErrNotFound.WithDetails("User not found")
+	grpcErr := ToGRPCError(want.WithCause(downstream))
+	if got := status.Code(grpcErr); got != codes.NotFound {
+		t.Fatalf("ToGRPCError() code = %s, want %s", got, codes.NotFound)
+	}
+	if got := FromGRPCError(grpcErr); !reflect.DeepEqual(got.Response("request-1"), want.Response("request-1")) {
+		t.Fatalf("FromGRPCError(ToGRPCError()) = %+v, want %+v", got, want)
+	}
+
+	// A bare status is passed on
+	if got := ToGRPCError(downstream); got != downstream {
+		t.Fatalf("ToGRPCError() = %v, want the status unchanged", got)
+	}
+}
+
+func TestToGRPCErrorUnknown(t *testing.T) {
+	err := ToGRPCError(fmt.Errorf("pq: connection refused"))
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("code = %s, want %s", status.Code(err), codes.Internal)
+	}
+	if got := status.Convert(err).Message(); got != "Internal server error" {
+		t.Fatalf("message = %q, want the generic message", got)
+	}
+
+	if err := ToGRPCError(nil); err != nil {
+		t.Fatalf("ToGRPCError(nil) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,70 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// ErrorResponse is the JSON error envelope returned to HTTP clients
+type ErrorResponse struct {
+	StatusCode int              `json:"status_code"`
+	Code       string           `json:"code"`
+	Message    string           `json:"message"`
+	Details    string           `json:"details,omitempty"`
+	Violations []FieldViolation `json:"violations,omitempty"`
+	RequestID  string           `json:"request_id,omitempty"`
+}
+
+// ProblemDetails is an RFC 7807 problem document carrying the error code as an extension
+type ProblemDetails struct {
+	Type       string           `json:"type"`
+	Title      string           `json:"title"`
+	Status     int              `json:"status"`
+	Detail     string           `json:"detail,omitempty"`
+	Code       string           `json:"code"`
+	Violations []FieldViolation `json:"violations,omitempty"`
+	RequestID  string           `json:"request_id,omitempty"`
+}
+
+// FromError returns err as an *Error. Unknown errors become ErrInternalError
+// so internal messages are never exposed to clients
+func FromError(err error) *Error {
+	var customErr *Error
+	if errors.As(err, &customErr) {
+		return customErr
+	}
+	return ErrInternalError
+}
+
+// Response builds the JSON error envelope for e
+func (e *Error) Response(requestID string) *ErrorResponse {
+	return &ErrorResponse{
+		StatusCode: e.StatusCode,
+		Code:       e.Code,
+		Message:    e.Message,
+		Details:    e.Details,
+		Violations: e.Violations,
+		RequestID:  requestID,
+	}
+}
+
+// Problem builds the RFC 7807 problem document for e
+func (e *Error) Problem(requestID string) *ProblemDetails {
+	title := e.Message
+	if title == "" {
+		title = http.StatusText(e.StatusCode)
+	}
+
+	return &ProblemDetails{
+		Type:       "about:blank",
+		Title:      title,
+		Status:     e.StatusCode,
+		Detail:     e.Details,
+		Code:       e.Code,
+		Violations: e.Violations,
+		RequestID:  requestID,
+	}
+}
@@ -0,0 +1,91 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestErrorResponseAndProblem(t *testing.T) {
+	violation := FieldViolation{Field: "email", Description: "must be a valid email"}
+
+	tests := []struct {
+		name        string
+		err         *Error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+		wantTitle   string
+	}{
+		{name: "bad request", err: ErrBadRequest.WithDetails("Invalid id"), wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST", wantMessage: "Invalid request"},
+		{name: "validation", err: ErrBadRequest.WithViolations(violation), wantStatus: http.StatusBadRequest, wantCode: "BAD_REQUEST", wantMessage: "Invalid request"},
+		{name: "unauthorized", err: ErrUnauthorized, wantStatus: http.StatusUnauthorized, wantCode: "UNAUTHORIZED", wantMessage: "Unauthorized"},
+		{name: "forbidden", err: ErrForbidden, wantStatus: http.StatusForbidden, wantCode: "FORBIDDEN", wantMessage: "Forbidden"},
+		{name: "not found", err: ErrNotFound.WithDetails("User not found"), wantStatus: http.StatusNotFound, wantCode: "NOT_FOUND", wantMessage: "Resource not found"},
+		{name: "conflict", err: ErrConflict, wantStatus: http.StatusConflict, wantCode: "CONFLICT", wantMessage: "Resource conflict"},
+		{name: "precondition failed", err: ErrPreconditionFailed, wantStatus: http.StatusPreconditionFailed, wantCode: "PRECONDITION_FAILED", wantMessage: "Precondition failed"},
+		{name: "too many requests", err: ErrTooManyRequests, wantStatus: http.StatusTooManyRequests, wantCode: "TOO_MANY_REQUESTS", wantMessage: "Too many requests"},
+		{name: "internal", err: ErrInternalError, wantStatus: http.StatusInternalServerError, wantCode: "INTERNAL_ERROR", wantMessage: "Internal server error"},
+		{name: "unavailable", err: ErrServiceUnavailable, wantStatus: http.StatusServiceUnavailable, wantCode: "SERVICE_UNAVAILABLE", wantMessage: "Service unavailable"},
+		{name: "gateway timeout", err: ErrGatewayTimeout, wantStatus: http.StatusGatewayTimeout, wantCode: "GATEWAY_TIMEOUT", wantMessage: "Upstream request timed out"},
+		{name: "canceled", err: ErrCanceled, wantStatus: statusClientClosedRequest, wantCode: "CANCELED", wantMessage: "Request canceled"},
+		{name: "empty message", err: NewError(http.StatusTeapot, "TEAPOT", ""), wantStatus: http.StatusTeapot, wantCode: "TEAPOT", wantTitle: "I'm a teapot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantResponse := &ErrorResponse{
+				StatusCode: tt.wantStatus,
+				Code:       tt.wantCode,
+				Message:    tt.wantMessage,
+				Details:    tt.err.Details,
+				Violations: tt.err.Violations,
+				RequestID:  "request-1",
+			}
+			if got := tt.err.Response("request-1"); !reflect.DeepEqual(got, wantResponse) {
+				t.Errorf("Response() = %+v, want %+v", got, wantResponse)
+			}
+
+			wantTitle := tt.wantTitle
+			if wantTitle == "" {
+				wantTitle = tt.wantMessage
+			}
+			wantProblem := &ProblemDetails{
+				Type:       "about:blank",
+				Title:      wantTitle,
+				Status:     tt.wantStatus,
+				Detail:     tt.err.Details,
+				Code:       tt.wantCode,
+				Violations: tt.err.Violations,
+				RequestID:  "request-1",
+			}
+			if got := tt.err.Problem("request-1"); !reflect.DeepEqual(got, wantProblem) {
+				t.Errorf("Problem() = %+v, want %+v", got, wantProblem)
+			}
+		})
+	}
+}
+
+func TestFromError(t *testing.T) {
+	notFound := ErrNotFound.WithDetails("User not found")
+
+	tests := []struct {
+		name string
+		err  error
+		want *Error
+	}{
+		{name: "error", err: notFound, want: notFound},
+		{name: "wrapped error", err: fmt.Errorf("get user: %w", notFound), want: notFound},
+		{name: "unknown error", err: errors.New("pq: connection refused"), want: ErrInternalError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromError(tt.err); got != tt.want {
+				t.Errorf("FromError() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
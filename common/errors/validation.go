@@ -0,0 +1,24 @@
+package errors
+
+import (
+	"errors"
+
+	"github.com/Nha1410/go-zero-template/common/validator"
+)
+
+// FromValidationError returns ErrBadRequest for a failed validator.Validate,
+// with one violation per invalid field
+func FromValidationError(err error) *Error {
+	appErr := ErrBadRequest.WithDetails(err.Error())
+
+	var validationErr *validator.ValidationError
+	if errors.As(err, &validationErr) {
+		for _, f := range validationErr.Fields {
+			appErr = appErr.WithViolations(FieldViolation{
+				Field:       f.Field,
+				Description: f.Message,
+			})
+		}
+	}
+	return appErr
+}
@@ -0,0 +1,222 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Component and overall statuses
+const (
+	StatusUp           = "up"
+	StatusDown         = "down"
+	StatusDegraded     = "degraded"
+	StatusShuttingDown = "shutting_down"
+)
+
+const defaultTimeout = 2 * time.Second
+
+// CheckFunc reports whether a dependency is usable
+type CheckFunc func(ctx context.Context) error
+
+// ComponentStatus is the result of a single check. Error is logged rather
+// than served, since it can reveal hosts and ports of the dependency
+type ComponentStatus struct {
+	Status    string  `json:"status"`
+	Optional  bool    `json:"optional,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"-"`
+}
+
+// Report is the aggregated result of all registered checks
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Ready reports whether every required component is up. A degraded
+// service is still ready
+func (r Report) Ready() bool {
+	return r.Status == StatusUp || r.Status == StatusDegraded
+}
+
+type check struct {
+	fn       CheckFunc
+	optional bool
+}
+
+// Checker runs registered dependency checks concurrently, each bounded by a
+// timeout, and reuses the report for a while so frequent probes do not load
+// the dependencies
+type Checker struct {
+	timeout      time.Duration
+	cacheTTL     time.Duration
+	shuttingDown atomic.Bool
+
+	mu     sync.RWMutex
+	checks map[string]check
+
+	// reportMu is held while checks run, so concurrent probes share one run
+	reportMu  sync.Mutex
+	report    Report
+	checkedAt time.Time
+}
+
+// NewChecker creates a checker whose checks time out after timeout and whose
+// report is reused for cacheTTL; a zero cacheTTL runs the checks every time
+func NewChecker(timeout, cacheTTL time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Checker{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		checks:   make(map[string]check),
+	}
+}
+
+// Register adds or replaces the check for a required component
+func (c *Checker) Register(name string, fn CheckFunc) {
+	c.register(name, check{fn: fn})
+}
+
+// RegisterOptional adds or replaces the check for a component whose failure
+// only degrades the service
+func (c *Checker) RegisterOptional(name string, fn CheckFunc) {
+	c.register(name, check{fn: fn, optional: true})
+}
+
+func (c *Checker) register(name string, ch check) {
+	c.mu.Lock()
+	c.checks[name] = ch
+	c.mu.Unlock()
+
+	// The cached report does not cover the new check
+	c.reportMu.Lock()
+	c.checkedAt = time.Time{}
+	c.reportMu.Unlock()
+}
+
+// MarkShuttingDown makes every later Check report StatusShuttingDown so load
+// balancers stop routing new requests while in-flight ones drain
+func (c *Checker) MarkShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Names returns the registered component names in order
+func (c *Checker) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check runs every registered check and aggregates the results, or returns
+// the report of a run less than the cache TTL ago. The report is shared and
+// must not be modified
+func (c *Checker) Check(ctx context.Context) Report {
+	if c.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown, Components: map[string]ComponentStatus{}}
+	}
+
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.cacheTTL {
+		return c.report
+	}
+
+	// Other probes may reuse the report, so a caller going away must not
+	// fail the checks
+	c.report = c.checkAll(context.WithoutCancel(ctx))
+	c.checkedAt = time.Now()
+	return c.report
+}
+
+func (c *Checker) checkAll(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := make(map[string]check, len(c.checks))
+	for name, ch := range c.checks {
+		checks[name] = ch
+	}
+	c.mu.RUnlock()
+
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		down       bool
+		degraded   bool
+		components = make(map[string]ComponentStatus, len(checks))
+	)
+	for name, ch := range checks {
+		wg.Add(1)
+		go func(name string, ch check) {
+			defer wg.Done()
+			status := c.run(ctx, ch.fn)
+			status.Optional = ch.optional
+			if status.Error != "" {
+				logx.WithContext(ctx).Errorf("Health check %s failed: %s", name, status.Error)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			components[name] = status
+			if status.Status != StatusUp {
+				if ch.optional {
+					degraded = true
+				} else {
+					down = true
+				}
+			}
+		}(name, ch)
+	}
+	wg.Wait()
+
+	rep := Report{Status: StatusUp, Components: components}
+	switch {
+	case down:
+		rep.Status = StatusDown
+	case degraded:
+		rep.Status = StatusDegraded
+	}
+	return rep
+}
+
+func (c *Checker) run(ctx context.Context, check CheckFunc) (status ComponentStatus) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		status.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	}()
+
+	// Run the check separately so a check ignoring ctx still honours the timeout
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return ComponentStatus{Status: StatusDown, Error: err.Error()}
+		}
+		return ComponentStatus{Status: StatusUp}
+	case <-ctx.Done():
+		return ComponentStatus{Status: StatusDown, Error: ctx.Err().Error()}
+	}
+}
@@ -0,0 +1,167 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx/logtest"
+)
+
+var errDialFailed = errors.New("dial tcp 10.0.0.5:5432: connection refused")
+
+func up(ctx context.Context) error {
+	return nil
+}
+
+func down(ctx context.Context) error {
+	return errDialFailed
+}
+
+// counted returns a check reporting err and the number of times it ran
+func counted(err error) (CheckFunc, *atomic.Int32) {
+	var calls atomic.Int32
+	return func(ctx context.Context) error {
+		calls.Add(1)
+		return err
+	}, &calls
+}
+
+func TestCheckerAggregation(t *testing.T) {
+	tests := []struct {
+		name      string
+		required  CheckFunc
+		optional  CheckFunc
+		want      string
+		wantReady bool
+	}{
+		{name: "all up", required: up, optional: up, want: StatusUp, wantReady: true},
+		{name: "optional down", required: up, optional: down, want: StatusDegraded, wantReady: true},
+		{name: "required down", required: down, optional: up, want: StatusDown},
+		{name: "both down", required: down, optional: down, want: StatusDown},
+		{
+			name: "required ignores the timeout",
+			required: func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			},
+			optional: up,
+			want:     StatusDown,
+		},
+		{
+			name:     "required panics",
+			required: func(ctx context.Context) error { panic("nil pool") },
+			optional: up,
+			want:     StatusDown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logtest.Discard(t)
+			c := NewChecker(50*time.Millisecond, 0)
+			c.Register("database", tt.required)
+			c.RegisterOptional("redis", tt.optional)
+
+			report := c.Check(context.Background())
+			if report.Status != tt.want || report.Ready() != tt.wantReady {
+				t.Fatalf("Check() = %s, ready %v, want %s, ready %v", report.Status, report.Ready(), tt.want, tt.wantReady)
+			}
+			if len(report.Components) != 2 || !report.Components["redis"].Optional || report.Components["database"].Optional {
+				t.Fatalf("components = %+v, want required database and optional redis", report.Components)
+			}
+		})
+	}
+}
+
+func TestCheckerHidesErrors(t *testing.T) {
+	logs := logtest.NewCollector(t)
+	c := NewChecker(time.Second, 0)
+	c.Register("database", down)
+
+	report := c.Check(context.Background())
+	body, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "10.0.0.5") {
+		t.Fatalf("report %s leaks the check error", body)
+	}
+	if !strings.Contains(logs.String(), errDialFailed.Error()) {
+		t.Fatalf("log = %q, want the check error", logs.String())
+	}
+}
+
+func TestCheckerCachesReport(t *testing.T) {
+	check, calls := counted(nil)
+	c := NewChecker(time.Second, time.Hour)
+	c.Register("database", check)
+
+	for i := 0; i < 3; i++ {
+		if report := c.Check(context.Background()); report.Status != StatusUp {
+			t.Fatalf("Check() = %s, want %s", report.Status, StatusUp)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("check ran %d times, want 1 within the cache TTL", got)
+	}
+
+	// A new check is not hidden by the cached report
+	c.Register("redis", down)
+	logtest.Discard(t)
+	if report := c.Check(context.Background()); report.Status != StatusDown {
+		t.Fatalf("Check() after Register = %s, want %s", report.Status, StatusDown)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("check ran %d times, want 2 after Register", got)
+	}
+}
+
+func TestCheckerWithoutCache(t *testing.T) {
+	check, calls := counted(nil)
+	c := NewChecker(time.Second, 0)
+	c.Register("database", check)
+
+	c.Check(context.Background())
+	c.Check(context.Background())
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("check ran %d times, want 2 without a cache TTL", got)
+	}
+}
+
+func TestCheckerSurvivesCanceledCaller(t *testing.T) {
+	c := NewChecker(time.Second, time.Hour)
+	c.Register("database", func(ctx context.Context) error {
+		return ctx.Err()
+	})
+
+	// The report is shared, so the first caller's cancellation must not fail it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if report := c.Check(ctx); report.Status != StatusUp {
+		t.Fatalf("Check() = %s, want %s", report.Status, StatusUp)
+	}
+}
+
+func TestCheckerShuttingDown(t *testing.T) {
+	check, calls := counted(nil)
+	c := NewChecker(time.Second, time.Hour)
+	c.Register("database", check)
+	if report := c.Check(context.Background()); !report.Ready() {
+		t.Fatalf("Check() = %s, want ready", report.Status)
+	}
+
+	// Draining overrides the cached report without running the checks
+	c.MarkShuttingDown()
+	report := c.Check(context.Background())
+	if report.Status != StatusShuttingDown || report.Ready() {
+		t.Fatalf("Check() = %s, ready %v, want %s, not ready", report.Status, report.Ready(), StatusShuttingDown)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("check ran %d times, want 1", got)
+	}
+}
@@ -0,0 +1,40 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Pinger is implemented by clients that can verify their connection
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingCheck checks a client implementing Pinger
+func PingCheck(p Pinger) CheckFunc {
+	return p.Ping
+}
+
+// DBCheck pings a database connection pool
+func DBCheck(db *sql.DB) CheckFunc {
+	return db.PingContext
+}
+
+// GRPCCheck asks a gRPC server's grpc.health.v1 service whether service is serving
+func GRPCCheck(conn *grpc.ClientConn, service string) CheckFunc {
+	client := grpc_health_v1.NewHealthClient(conn)
+	return func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+			return fmt.Errorf("service is %s", resp.GetStatus())
+		}
+		return nil
+	}
+}
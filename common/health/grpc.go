@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// watchInterval is how often Watch checks for a change of status
+var watchInterval = 5 * time.Second
+
+// GRPCServer implements grpc.health.v1 on top of a Checker, so gRPC probes
+// reflect the same dependency state as the HTTP readiness endpoint
+type GRPCServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	checker  *Checker
+	services map[string]struct{}
+}
+
+var _ grpc_health_v1.HealthServer = (*GRPCServer)(nil)
+
+// NewGRPCServer serves health for the overall server ("") and the named services
+func NewGRPCServer(checker *Checker, services ...string) *GRPCServer {
+	known := map[string]struct{}{"": {}}
+	for _, s := range services {
+		known[s] = struct{}{}
+	}
+	return &GRPCServer{
+		checker:  checker,
+		services: known,
+	}
+}
+
+// Check runs the dependency checks and reports SERVING only if all are up
+func (s *GRPCServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if _, ok := s.services[req.GetService()]; !ok {
+		return nil, status.Error(codes.NotFound, "unknown service")
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: s.servingStatus(ctx)}, nil
+}
+
+// Watch streams the serving status whenever it changes
+func (s *GRPCServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	ctx := stream.Context()
+	if _, ok := s.services[req.GetService()]; !ok {
+		return stream.Send(&grpc_health_v1.HealthCheckResponse{
+			Status: grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN,
+		})
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	last := grpc_health_v1.HealthCheckResponse_UNKNOWN
+	for {
+		if current := s.servingStatus(ctx); current != last {
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *GRPCServer) servingStatus(ctx context.Context) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if s.checker.Check(ctx).Ready() {
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+}
@@ -0,0 +1,130 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx/logtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// watchStream collects the statuses sent by Watch
+type watchStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan grpc_health_v1.HealthCheckResponse_ServingStatus
+}
+
+func newWatchStream(ctx context.Context) *watchStream {
+	return &watchStream{ctx: ctx, sent: make(chan grpc_health_v1.HealthCheckResponse_ServingStatus, 10)}
+}
+
+func (s *watchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *watchStream) Send(resp *grpc_health_v1.HealthCheckResponse) error {
+	s.sent <- resp.GetStatus()
+	return nil
+}
+
+// whileHealthy returns a check that fails once healthy is false
+func whileHealthy(healthy *atomic.Bool) CheckFunc {
+	return func(ctx context.Context) error {
+		if !healthy.Load() {
+			return errDialFailed
+		}
+		return nil
+	}
+}
+
+func TestGRPCServerCheck(t *testing.T) {
+	logtest.Discard(t)
+	var healthy atomic.Bool
+	healthy.Store(true)
+	c := NewChecker(time.Second, 0)
+	c.Register("database", whileHealthy(&healthy))
+	s := NewGRPCServer(c, "user.User")
+
+	for _, service := range []string{"", "user.User"} {
+		resp, err := s.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil || resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+			t.Fatalf("Check(%q) = %v, %v, want SERVING", service, resp, err)
+		}
+	}
+
+	if _, err := s.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "order.Order"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Check() of an unknown service error = %v, want code %s", err, codes.NotFound)
+	}
+
+	healthy.Store(false)
+	resp, err := s.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil || resp.GetStatus() != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("Check() with the database down = %v, %v, want NOT_SERVING", resp, err)
+	}
+}
+
+func TestGRPCServerWatch(t *testing.T) {
+	interval := watchInterval
+	watchInterval = 10 * time.Millisecond
+	t.Cleanup(func() { watchInterval = interval })
+
+	logtest.Discard(t)
+	var healthy atomic.Bool
+	healthy.Store(true)
+	c := NewChecker(time.Second, 0)
+	c.Register("database", whileHealthy(&healthy))
+	s := NewGRPCServer(c, "user.User")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := newWatchStream(ctx)
+	done := make(chan error)
+	go func() {
+		done <- s.Watch(&grpc_health_v1.HealthCheckRequest{Service: "user.User"}, stream)
+	}()
+
+	// Only changes are sent
+	next := func() grpc_health_v1.HealthCheckResponse_ServingStatus {
+		select {
+		case got := <-stream.sent:
+			return got
+		case <-time.After(time.Second):
+			t.Fatal("Watch sent nothing")
+			return 0
+		}
+	}
+	if got := next(); got != grpc_health_v1.HealthCheckResponse_SERVING {
+		t.Fatalf("first status = %s, want SERVING", got)
+	}
+	healthy.Store(false)
+	if got := next(); got != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("status after the outage = %s, want NOT_SERVING", got)
+	}
+	select {
+	case got := <-stream.sent:
+		t.Fatalf("Watch sent %s without a change", got)
+	case <-time.After(5 * watchInterval):
+	}
+
+	cancel()
+	if err := <-done; status.Code(err) != codes.Canceled {
+		t.Fatalf("Watch() error = %v, want code %s", err, codes.Canceled)
+	}
+}
+
+func TestGRPCServerWatchUnknownService(t *testing.T) {
+	s := NewGRPCServer(NewChecker(time.Second, 0))
+	stream := newWatchStream(context.Background())
+
+	if err := s.Watch(&grpc_health_v1.HealthCheckRequest{Service: "order.Order"}, stream); err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if got := <-stream.sent; got != grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN {
+		t.Fatalf("status = %s, want SERVICE_UNKNOWN", got)
+	}
+}
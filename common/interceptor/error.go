@@ -0,0 +1,26 @@
+package interceptor
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"google.golang.org/grpc"
+)
+
+// UnaryErrorInterceptor converts errors returned by handlers into gRPC
+// statuses carrying the original *errors.Error. The cause of an *errors.Error
+// is logged here, since it does not travel to the client
+func UnaryErrorInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		var e *errors.Error
+		if stderrors.As(err, &e) && e.Cause != nil {
+			logx.WithContext(ctx).Errorf("%s failed with %s: %v", info.FullMethod, e.Code, e.Cause)
+		}
+		return nil, errors.ToGRPCError(err)
+	}
+	return resp, nil
+}
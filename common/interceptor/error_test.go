@@ -0,0 +1,84 @@
+package interceptor
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/zeromicro/go-zero/core/logx/logtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testInfo = &grpc.UnaryServerInfo{FullMethod: "/user.User/GetUser"}
+
+func TestUnaryErrorInterceptor(t *testing.T) {
+	cause := stderrors.New("dial tcp 10.0.0.5:5432: connection refused")
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode codes.Code
+		wantErr  *errors.Error
+		wantLog  string
+	}{
+		{
+			name:     "error",
+			err:      errors.ErrNotFound.WithDetails("User not found"),
+			wantCode: codes.NotFound,
+			wantErr:  errors.ErrNotFound.WithDetails("User not found"),
+		},
+		{
+			name:     "wrapped error with a cause",
+			err:      fmt.Errorf("get user: %w", errors.ErrServiceUnavailable.WithCause(cause)),
+			wantCode: codes.Unavailable,
+			wantErr:  errors.ErrServiceUnavailable,
+			wantLog:  cause.Error(),
+		},
+		{
+			name:     "unknown error",
+			err:      cause,
+			wantCode: codes.Internal,
+			wantErr:  errors.ErrInternalError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := logtest.NewCollector(t)
+			resp, err := UnaryErrorInterceptor(context.Background(), nil, testInfo,
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					return "ignored", tt.err
+				})
+
+			if resp != nil || status.Code(err) != tt.wantCode {
+				t.Fatalf("UnaryErrorInterceptor() = %v, %v, want code %s", resp, err, tt.wantCode)
+			}
+			if got := errors.FromGRPCError(err); !reflect.DeepEqual(got.Response(""), tt.wantErr.Response("")) {
+				t.Fatalf("FromGRPCError() = %+v, want %+v", got, tt.wantErr)
+			}
+			// The cause is logged, not sent
+			if status.Convert(err).Message() == cause.Error() {
+				t.Fatalf("status message leaks the cause: %v", err)
+			}
+			if tt.wantLog != "" && !strings.Contains(logs.String(), tt.wantLog) {
+				t.Fatalf("log = %q, want it to contain %q", logs.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestUnaryErrorInterceptorSuccess(t *testing.T) {
+	resp, err := UnaryErrorInterceptor(context.Background(), nil, testInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "user", nil
+		})
+	if resp != "user" || err != nil {
+		t.Fatalf("UnaryErrorInterceptor() = %v, %v, want user, nil", resp, err)
+	}
+}
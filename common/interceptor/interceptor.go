@@ -0,0 +1,16 @@
+package interceptor
+
+import "google.golang.org/grpc"
+
+// UnaryServerInterceptors returns the standard interceptor chain for services,
+// outermost first. Logging sees the final status after error translation, and
+// panics are recovered before translation
+func UnaryServerInterceptors() []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		UnaryMetadataInterceptor,
+		UnaryLoggingInterceptor,
+		UnaryErrorInterceptor,
+		UnaryRecoverInterceptor,
+		UnaryValidationInterceptor,
+	}
+}
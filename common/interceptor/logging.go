@@ -0,0 +1,45 @@
+package interceptor
+
+import (
+	"context"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryLoggingInterceptor writes one structured access log entry per call
+// with the method, status code, duration and caller. Health probes are not logged
+func UnaryLoggingInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if info.FullMethod == grpc_health_v1.Health_Check_FullMethodName {
+		return handler(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	code := status.Code(err)
+	fields := []logx.LogField{
+		logx.Field("method", info.FullMethod),
+		logx.Field("code", code.String()),
+	}
+	if caller, ok := CallerFromContext(ctx); ok {
+		fields = append(fields, logx.Field("user_id", caller.UserID))
+	}
+
+	logger := logx.WithContext(ctx).WithDuration(time.Since(start))
+	switch code {
+	case codes.OK, codes.NotFound, codes.InvalidArgument, codes.AlreadyExists, codes.FailedPrecondition,
+		codes.Unauthenticated, codes.PermissionDenied, codes.ResourceExhausted, codes.Canceled:
+		logger.Infow("grpc request", fields...)
+	default:
+		fields = append(fields, logx.Field("error", err.Error()))
+		logger.Errorw("grpc request", fields...)
+	}
+
+	return resp, err
+}
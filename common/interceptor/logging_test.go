@@ -0,0 +1,71 @@
+package interceptor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/zeromicro/go-zero/core/logx/logtest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryLoggingInterceptor(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantLevel string
+		wantCode  string
+		wantError bool
+	}{
+		{name: "ok", wantLevel: "info", wantCode: "OK"},
+		{name: "client error", err: status.Error(codes.NotFound, "User not found"), wantLevel: "info", wantCode: "NotFound"},
+		{name: "server error", err: status.Error(codes.Internal, "Internal server error"), wantLevel: "error", wantCode: "Internal", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := logtest.NewCollector(t)
+			ctx := ContextWithCaller(context.Background(), Caller{UserID: "user-1"})
+
+			_, err := UnaryLoggingInterceptor(ctx, nil, testInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, tt.err
+			})
+			if err != tt.err {
+				t.Fatalf("UnaryLoggingInterceptor() error = %v, want %v", err, tt.err)
+			}
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("log %q is not one JSON entry: %v", logs.String(), err)
+			}
+			if entry["level"] != tt.wantLevel || entry["method"] != testInfo.FullMethod ||
+				entry["code"] != tt.wantCode || entry["user_id"] != "user-1" {
+				t.Fatalf("log entry = %v, want level %s, method, code %s and user_id", entry, tt.wantLevel, tt.wantCode)
+			}
+			if _, ok := entry["duration"]; !ok {
+				t.Fatalf("log entry = %v, want a duration", entry)
+			}
+			if _, ok := entry["error"]; ok != tt.wantError {
+				t.Fatalf("log entry = %v, want error field %v", entry, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestUnaryLoggingInterceptorSkipsHealthChecks(t *testing.T) {
+	logs := logtest.NewCollector(t)
+	info := &grpc.UnaryServerInfo{FullMethod: grpc_health_v1.Health_Check_FullMethodName}
+
+	_, err := UnaryLoggingInterceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logs.String() != "" {
+		t.Fatalf("health check logged %q", logs.String())
+	}
+}
@@ -0,0 +1,115 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata keys used to propagate request context between services
+const (
+	MetadataRequestID = "x-request-id"
+	MetadataUserID    = "x-user-id"
+	MetadataUserEmail = "x-user-email"
+	MetadataUserRoles = "x-user-roles"
+)
+
+type contextKey string
+
+const (
+	requestIDKey contextKey = "requestID"
+	callerKey    contextKey = "caller"
+)
+
+// Caller is the authenticated identity a request was made on behalf of
+type Caller struct {
+	UserID string
+	Email  string
+	Roles  []string
+}
+
+// ContextWithRequestID stores the request ID in ctx
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// ContextWithCaller stores the caller identity in ctx
+func ContextWithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey, caller)
+}
+
+// CallerFromContext returns the caller identity stored in ctx
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey).(Caller)
+	return caller, ok
+}
+
+// UnaryMetadataInterceptor moves the request ID and caller identity from the
+// incoming metadata into the context. The identity is set by the gateway after
+// token validation, so services must only be reachable from trusted peers
+func UnaryMetadataInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	if requestID := firstValue(md, MetadataRequestID); requestID != "" {
+		ctx = ContextWithRequestID(ctx, requestID)
+		ctx = logx.ContextWithFields(ctx, logx.Field("request_id", requestID))
+	}
+
+	if userID := firstValue(md, MetadataUserID); userID != "" {
+		var roles []string
+		if v := firstValue(md, MetadataUserRoles); v != "" {
+			roles = strings.Split(v, ",")
+		}
+		ctx = ContextWithCaller(ctx, Caller{
+			UserID: userID,
+			Email:  firstValue(md, MetadataUserEmail),
+			Roles:  roles,
+		})
+	}
+
+	return handler(ctx, req)
+}
+
+// UnaryClientMetadataInterceptor forwards the request ID and caller identity
+// stored in the context as outgoing metadata
+func UnaryClientMetadataInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	var pairs []string
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		pairs = append(pairs, MetadataRequestID, requestID)
+	}
+	if caller, ok := CallerFromContext(ctx); ok {
+		pairs = append(pairs, MetadataUserID, caller.UserID)
+		if caller.Email != "" {
+			pairs = append(pairs, MetadataUserEmail, caller.Email)
+		}
+		if len(caller.Roles) > 0 {
+			pairs = append(pairs, MetadataUserRoles, strings.Join(caller.Roles, ","))
+		}
+	}
+	if len(pairs) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+	}
+
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package interceptor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryMetadataInterceptor(t *testing.T) {
+	tests := []struct {
+		name          string
+		md            metadata.MD
+		wantRequestID string
+		wantCaller    *Caller
+	}{
+		{
+			name: "request and caller",
+			md: metadata.Pairs(
+				MetadataRequestID, "request-1",
+				MetadataUserID, "user-1",
+				MetadataUserEmail, "jane@example.com",
+				MetadataUserRoles, "admin,user",
+			),
+			wantRequestID: "request-1",
+			wantCaller:    &Caller{UserID: "user-1", Email: "jane@example.com", Roles: []string{"admin", "user"}},
+		},
+		{
+			name:       "caller without roles",
+			md:         metadata.Pairs(MetadataUserID, "user-1"),
+			wantCaller: &Caller{UserID: "user-1"},
+		},
+		{
+			name:          "no caller",
+			md:            metadata.Pairs(MetadataRequestID, "request-1", MetadataUserEmail, "jane@example.com"),
+			wantRequestID: "request-1",
+		},
+		{
+			name: "no metadata",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+
+			_, err := UnaryMetadataInterceptor(ctx, nil, testInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+				if got := RequestIDFromContext(ctx); got != tt.wantRequestID {
+					t.Fatalf("RequestIDFromContext() = %q, want %q", got, tt.wantRequestID)
+				}
+				caller, ok := CallerFromContext(ctx)
+				if tt.wantCaller == nil {
+					if ok {
+						t.Fatalf("CallerFromContext() = %+v, want none", caller)
+					}
+				} else if !ok || !reflect.DeepEqual(caller, *tt.wantCaller) {
+					t.Fatalf("CallerFromContext() = %+v, %v, want %+v", caller, ok, *tt.wantCaller)
+				}
+				return nil, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestUnaryClientMetadataInterceptor(t *testing.T) {
+	caller := Caller{UserID: "user-1", Email: "jane@example.com", Roles: []string{"admin", "user"}}
+	ctx := ContextWithCaller(ContextWithRequestID(context.Background(), "request-1"), caller)
+
+	// What the client sends, the server interceptor reads back
+	var outgoing metadata.MD
+	err := UnaryClientMetadataInterceptor(ctx, testInfo.FullMethod, nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			outgoing, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = UnaryMetadataInterceptor(metadata.NewIncomingContext(context.Background(), outgoing), nil, testInfo,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			if got := RequestIDFromContext(ctx); got != "request-1" {
+				t.Fatalf("RequestIDFromContext() = %q, want request-1", got)
+			}
+			if got, ok := CallerFromContext(ctx); !ok || !reflect.DeepEqual(got, caller) {
+				t.Fatalf("CallerFromContext() = %+v, %v, want %+v", got, ok, caller)
+			}
+			return nil, nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without request context nothing is added
+	err = UnaryClientMetadataInterceptor(context.Background(), testInfo.FullMethod, nil, nil, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			if md, ok := metadata.FromOutgoingContext(ctx); ok {
+				t.Fatalf("outgoing metadata = %v, want none", md)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
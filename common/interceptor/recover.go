@@ -0,0 +1,25 @@
+package interceptor
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryRecoverInterceptor turns a panic in the handler into codes.Internal
+// so one bad request cannot take down the server
+func UnaryRecoverInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			logx.WithContext(ctx).Errorf("Panic in %s: %v\n%s", info.FullMethod, p, debug.Stack())
+			err = status.Error(codes.Internal, "Internal server error")
+		}
+	}()
+
+	return handler(ctx, req)
+}
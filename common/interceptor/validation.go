@@ -0,0 +1,25 @@
+package interceptor
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/common/validator"
+	"google.golang.org/grpc"
+)
+
+// UnaryValidationInterceptor rejects requests that fail the rules registered
+// with validator.RegisterRules before they reach the handler
+func UnaryValidationInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if v := reflect.ValueOf(req); v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return handler(ctx, req)
+	}
+
+	if err := validator.Validate(req); err != nil {
+		return nil, errors.ToGRPCError(errors.FromValidationError(err))
+	}
+
+	return handler(ctx, req)
+}
@@ -0,0 +1,71 @@
+package interceptor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/common/validator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// createReq stands in for a generated message without validate tags
+type createReq struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+func init() {
+	validator.RegisterRules(map[string]string{
+		"Email": "required,email",
+		"Name":  "required",
+	}, createReq{})
+}
+
+func TestUnaryValidationInterceptor(t *testing.T) {
+	tests := []struct {
+		name           string
+		req            interface{}
+		wantCalled     bool
+		wantViolations []string
+	}{
+		{name: "valid", req: &createReq{Email: "jane@example.com", Name: "Jane"}, wantCalled: true},
+		{name: "invalid", req: &createReq{Email: "jane"}, wantViolations: []string{"email", "name"}},
+		{name: "not a struct", req: "jane", wantCalled: true},
+		{name: "nil message", req: (*createReq)(nil), wantCalled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			_, err := UnaryValidationInterceptor(context.Background(), tt.req, testInfo,
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					called = true
+					return nil, nil
+				})
+
+			if called != tt.wantCalled {
+				t.Fatalf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+			if tt.wantViolations == nil {
+				if err != nil {
+					t.Fatalf("UnaryValidationInterceptor() error = %v", err)
+				}
+				return
+			}
+
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("error = %v, want code %s", err, codes.InvalidArgument)
+			}
+			var fields []string
+			for _, v := range errors.FromGRPCError(err).Violations {
+				fields = append(fields, v.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantViolations) {
+				t.Fatalf("violations = %v, want %v", fields, tt.wantViolations)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type namedCloser struct {
+	name  string
+	close func() error
+}
+
+// Closers releases resources in the reverse order they were acquired
+type Closers struct {
+	mu      sync.Mutex
+	closers []namedCloser
+	closed  bool
+}
+
+// Add registers close to run on Close. Resources should be added as soon as
+// they are acquired so a failed startup releases what it already opened
+func (c *Closers) Add(name string, close func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closers = append(c.closers, namedCloser{name: name, close: close})
+}
+
+// Close runs every registered closer once, newest first, and joins their errors
+func (c *Closers) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	closers := c.closers
+	c.mu.Unlock()
+
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].close(); err != nil {
+			logx.Errorf("Failed to close %s: %v", closers[i].name, err)
+			errs = append(errs, fmt.Errorf("close %s: %w", closers[i].name, err))
+			continue
+		}
+		logx.Infof("Closed %s", closers[i].name)
+	}
+	return errors.Join(errs...)
+}
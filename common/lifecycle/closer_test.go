@@ -0,0 +1,44 @@
+package lifecycle
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zeromicro/go-zero/core/logx/logtest"
+)
+
+func TestClosersClose(t *testing.T) {
+	logtest.Discard(t)
+	errFlush := errors.New("flush failed")
+
+	var closed []string
+	closer := func(name string, err error) func() error {
+		return func() error {
+			closed = append(closed, name)
+			return err
+		}
+	}
+
+	var c Closers
+	c.Add("database", closer("database", nil))
+	c.Add("redis", closer("redis", errFlush))
+	c.Add("rabbitmq", closer("rabbitmq", nil))
+
+	// Newest first, and a failure does not stop the rest
+	err := c.Close()
+	if !errors.Is(err, errFlush) {
+		t.Fatalf("Close() error = %v, want %v", err, errFlush)
+	}
+	if want := []string{"rabbitmq", "redis", "database"}; !reflect.DeepEqual(closed, want) {
+		t.Fatalf("closed %v, want %v", closed, want)
+	}
+
+	// Closing again does nothing
+	if err := c.Close(); err != nil {
+		t.Fatalf("second Close() error = %v", err)
+	}
+	if len(closed) != 3 {
+		t.Fatalf("closed %v after a second Close, want each once", closed)
+	}
+}
@@ -0,0 +1,71 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Nha1410/go-zero-template/common/health"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Connector connects dependencies according to their Requirement and keeps
+// retrying failed optional ones in the background until Stop
+type Connector struct {
+	conf   StartupConfig
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewConnector creates a connector using conf for retries and backoff
+func NewConnector(conf StartupConfig) *Connector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Connector{
+		conf:   conf,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Connect runs connect according to req. Required dependencies are retried
+// until startupCtx expires and their failure is returned. Optional ones get a
+// single attempt before reconnecting in the background, so connect must be
+// safe to run concurrently with users of the dependency. It reports whether
+// the dependency is enabled
+func (c *Connector) Connect(startupCtx context.Context, req Requirement, name string, connect func() error) (bool, error) {
+	switch req {
+	case Disabled:
+		logx.Infof("%s is disabled", name)
+		return false, nil
+	case Optional:
+		if err := connect(); err != nil {
+			logx.Errorf("Optional dependency %s is unavailable, starting degraded: %v", name, err)
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				_ = Retry(c.ctx, c.conf, name, connect)
+			}()
+		}
+		return true, nil
+	default:
+		return true, Retry(startupCtx, c.conf, name, connect)
+	}
+}
+
+// Stop ends background reconnects and waits for any attempt in progress
+func (c *Connector) Stop() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+// RegisterCheck adds the health check for a dependency. Optional dependencies
+// only degrade readiness and disabled ones are not checked
+func RegisterCheck(checker *health.Checker, req Requirement, name string, check health.CheckFunc) {
+	switch req {
+	case Disabled:
+	case Optional:
+		checker.RegisterOptional(name, check)
+	default:
+		checker.Register(name, check)
+	}
+}
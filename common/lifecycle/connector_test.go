@@ -0,0 +1,127 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/common/health"
+	"github.com/zeromicro/go-zero/core/logx/logtest"
+)
+
+var testStartup = StartupConfig{InitialBackoff: 2 * time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+
+// flakyConnect fails until it has been called succeedOn times; zero never succeeds
+func flakyConnect(succeedOn int32) (func() error, *atomic.Int32) {
+	var calls atomic.Int32
+	return func() error {
+		if n := calls.Add(1); succeedOn == 0 || n < succeedOn {
+			return errRefused
+		}
+		return nil
+	}, &calls
+}
+
+func TestConnectorDisabled(t *testing.T) {
+	logtest.Discard(t)
+	c := NewConnector(testStartup)
+	defer c.Stop()
+
+	connect, calls := flakyConnect(1)
+	enabled, err := c.Connect(context.Background(), Disabled, "rabbitmq", connect)
+	if enabled || err != nil || calls.Load() != 0 {
+		t.Fatalf("Connect() = %v, %v after %d calls, want disabled without connecting", enabled, err, calls.Load())
+	}
+}
+
+func TestConnectorRequired(t *testing.T) {
+	logtest.Discard(t)
+	c := NewConnector(testStartup)
+	defer c.Stop()
+
+	// Retried until it connects
+	connect, calls := flakyConnect(3)
+	enabled, err := c.Connect(context.Background(), Required, "database", connect)
+	if !enabled || err != nil || calls.Load() != 3 {
+		t.Fatalf("Connect() = %v, %v after %d calls, want enabled after 3", enabled, err, calls.Load())
+	}
+
+	// Its failure at the startup deadline is returned
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	connect, _ = flakyConnect(0)
+	if _, err := c.Connect(ctx, Required, "database", connect); !errors.Is(err, errRefused) {
+		t.Fatalf("Connect() error = %v, want %v", err, errRefused)
+	}
+}
+
+func TestConnectorOptionalReconnects(t *testing.T) {
+	logtest.Discard(t)
+	c := NewConnector(testStartup)
+	defer c.Stop()
+
+	// The first failure does not hold up startup
+	connect, calls := flakyConnect(3)
+	enabled, err := c.Connect(context.Background(), Optional, "redis", connect)
+	if !enabled || err != nil {
+		t.Fatalf("Connect() = %v, %v, want enabled without error", enabled, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("connect called %d times, want a background reconnect", calls.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Once connected it is not retried again
+	time.Sleep(10 * testStartup.MaxBackoff)
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("connect called %d times after connecting, want 3", got)
+	}
+}
+
+func TestConnectorStopEndsReconnects(t *testing.T) {
+	logtest.Discard(t)
+	c := NewConnector(testStartup)
+
+	connect, calls := flakyConnect(0)
+	if _, err := c.Connect(context.Background(), Optional, "redis", connect); err != nil {
+		t.Fatal(err)
+	}
+	for calls.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Stop waits for the reconnect loop, which then stays stopped
+	c.Stop()
+	stopped := calls.Load()
+	time.Sleep(10 * testStartup.MaxBackoff)
+	if got := calls.Load(); got != stopped {
+		t.Fatalf("connect called %d times after Stop, want %d", got, stopped)
+	}
+}
+
+func TestRegisterCheck(t *testing.T) {
+	logtest.Discard(t)
+	down := func(ctx context.Context) error { return errRefused }
+	checker := health.NewChecker(time.Second, 0)
+
+	RegisterCheck(checker, Required, "database", func(ctx context.Context) error { return nil })
+	RegisterCheck(checker, Optional, "redis", down)
+	RegisterCheck(checker, Disabled, "rabbitmq", down)
+
+	report := checker.Check(context.Background())
+	if report.Status != health.StatusDegraded {
+		t.Fatalf("Check() = %s, want %s with only the optional dependency down", report.Status, health.StatusDegraded)
+	}
+	if _, ok := report.Components["rabbitmq"]; ok || len(report.Components) != 2 {
+		t.Fatalf("components = %v, want database and redis only", report.Components)
+	}
+	if !report.Components["redis"].Optional || report.Components["database"].Optional {
+		t.Fatalf("components = %+v, want optional redis and required database", report.Components)
+	}
+}
@@ -0,0 +1,29 @@
+package lifecycle
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Requirement declares how a service depends on an external system
+type Requirement string
+
+const (
+	// Required dependencies must connect before the startup deadline
+	Required Requirement = "required"
+	// Optional dependencies may be down; the service starts degraded and
+	// reconnects in the background
+	Optional Requirement = "optional"
+	// Disabled dependencies are never connected
+	Disabled Requirement = "disabled"
+)
+
+// ParseRequirement parses "required", "optional" or "disabled"
+func ParseRequirement(s string) (Requirement, error) {
+	switch r := Requirement(strings.ToLower(strings.TrimSpace(s))); r {
+	case Required, Optional, Disabled:
+		return r, nil
+	default:
+		return "", fmt.Errorf("invalid dependency requirement %q, expected required, optional or disabled", s)
+	}
+}
@@ -0,0 +1,26 @@
+package lifecycle
+
+import "testing"
+
+func TestParseRequirement(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Requirement
+		wantErr bool
+	}{
+		{value: "required", want: Required},
+		{value: "optional", want: Optional},
+		{value: "disabled", want: Disabled},
+		{value: " Optional ", want: Optional},
+		{value: "DISABLED", want: Disabled},
+		{value: "", wantErr: true},
+		{value: "sometimes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRequirement(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRequirement(%q) = %q, %v, want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	defaultStartupTimeout = time.Minute
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+)
+
+// StartupConfig bounds how long a service keeps retrying its dependencies at boot
+type StartupConfig struct {
+	// Timeout is the deadline for all dependencies to become available
+	Timeout time.Duration
+	// InitialBackoff is the delay after the first failed attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts
+	MaxBackoff time.Duration
+}
+
+// Context returns a context that expires at the startup deadline
+func (c StartupConfig) Context() (context.Context, context.CancelFunc) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultStartupTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// Retry calls fn until it succeeds or ctx expires, doubling the delay between
+// attempts up to MaxBackoff with jitter so replicas do not retry in lockstep
+func Retry(ctx context.Context, conf StartupConfig, name string, fn func() error) error {
+	backoff := conf.InitialBackoff
+	if backoff <= 0 {
+		backoff = defaultInitialBackoff
+	}
+	maxBackoff := conf.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			if attempt > 1 {
+				logx.Infof("Connected to %s after %d attempts", name, attempt)
+			}
+			return nil
+		}
+
+		// Sleep for between half and the full backoff
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		logx.Errorf("Failed to connect to %s (attempt %d), retrying in %s: %v", name, attempt, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s unavailable after %d attempts: %w", name, attempt, err)
+		case <-timer.C:
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
@@ -0,0 +1,112 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx/logtest"
+)
+
+var errRefused = errors.New("connection refused")
+
+// retryDelays reads the delays Retry logged before each retry
+func retryDelays(t *testing.T, logs *logtest.Buffer) []time.Duration {
+	t.Helper()
+	pattern := regexp.MustCompile(`retrying in (\S+):`)
+	var delays []time.Duration
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry struct {
+			Content string `json:"content"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		match := pattern.FindStringSubmatch(entry.Content)
+		if match == nil {
+			continue
+		}
+		delay, err := time.ParseDuration(match[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		delays = append(delays, delay)
+	}
+	return delays
+}
+
+func TestRetryBacksOff(t *testing.T) {
+	logs := logtest.NewCollector(t)
+	conf := StartupConfig{InitialBackoff: 4 * time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+
+	attempts := 0
+	err := Retry(context.Background(), conf, "database", func() error {
+		attempts++
+		if attempts < 5 {
+			return errRefused
+		}
+		return nil
+	})
+	if err != nil || attempts != 5 {
+		t.Fatalf("Retry() = %v after %d attempts, want nil after 5", err, attempts)
+	}
+
+	// Each delay is between half and all of a backoff that doubles up to the cap
+	delays := retryDelays(t, logs)
+	backoffs := []time.Duration{4 * time.Millisecond, 8 * time.Millisecond, 10 * time.Millisecond, 10 * time.Millisecond}
+	if len(delays) != len(backoffs) {
+		t.Fatalf("logged delays %v, want %d", delays, len(backoffs))
+	}
+	for i, backoff := range backoffs {
+		if delays[i] < backoff/2 || delays[i] > backoff {
+			t.Fatalf("delay %d = %s, want between %s and %s", i+1, delays[i], backoff/2, backoff)
+		}
+	}
+}
+
+func TestRetryDefaults(t *testing.T) {
+	logs := logtest.NewCollector(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := Retry(ctx, StartupConfig{}, "database", func() error { return errRefused }); err == nil {
+		t.Fatal("Retry() succeeded, want an error")
+	}
+	delays := retryDelays(t, logs)
+	if len(delays) != 1 || delays[0] < defaultInitialBackoff/2 || delays[0] > defaultInitialBackoff {
+		t.Fatalf("delays = %v, want one between %s and %s", delays, defaultInitialBackoff/2, defaultInitialBackoff)
+	}
+
+	startupCtx, cancel := StartupConfig{}.Context()
+	defer cancel()
+	if deadline, ok := startupCtx.Deadline(); !ok || time.Until(deadline) > defaultStartupTimeout {
+		t.Fatalf("startup deadline = %v, want within %s", deadline, defaultStartupTimeout)
+	}
+}
+
+func TestRetryStopsWhenContextExpires(t *testing.T) {
+	logtest.Discard(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	conf := StartupConfig{InitialBackoff: 4 * time.Millisecond, MaxBackoff: 8 * time.Millisecond}
+
+	attempts := 0
+	start := time.Now()
+	err := Retry(ctx, conf, "database", func() error {
+		attempts++
+		return errRefused
+	})
+	if !errors.Is(err, errRefused) || !strings.Contains(err.Error(), "database unavailable") {
+		t.Fatalf("Retry() error = %v, want it to wrap %v", err, errRefused)
+	}
+	if attempts < 2 {
+		t.Fatalf("attempts = %d, want retries before the deadline", attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Retry() returned after %s, want soon after the deadline", elapsed)
+	}
+}
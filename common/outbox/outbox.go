@@ -0,0 +1,60 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event is a domain event recorded in the outbox table. It is published as
+// JSON with its ID as the AMQP message ID and its Type as the routing key
+type Event struct {
+	// ID is assigned once when the event is created and survives redelivery,
+	// so consumers can use it to drop duplicates
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// NewEvent creates an event with a new ID and data marshaled as JSON
+func NewEvent(eventType, aggregateType, aggregateID string, data interface{}) (Event, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to marshal %s event: %w", eventType, err)
+	}
+	return Event{
+		ID:            uuid.NewString(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		OccurredAt:    time.Now().UTC(),
+		Data:          body,
+	}, nil
+}
+
+// Execer is implemented by *sql.DB and *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Add records events with tx, so they are published only if tx commits. The
+// outbox table is created by each service's migrations
+func Add(ctx context.Context, tx Execer, events ...Event) error {
+	for _, e := range events {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO outbox (event_id, event_type, aggregate_type, aggregate_id, occurred_at, data)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, e.ID, e.Type, e.AggregateType, e.AggregateID, e.OccurredAt, string(e.Data))
+		if err != nil {
+			return fmt.Errorf("failed to record %s event: %w", e.Type, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/common/queue"
+	"github.com/streadway/amqp"
+)
+
+// Publisher publishes to an exchange and returns once the broker has
+// confirmed the message. *queue.RabbitMQClient implements it
+type Publisher interface {
+	DeclareExchange(name, kind string, durable, autoDelete, internal, noWait bool) error
+	PublishToExchange(exchange, routingKey string, message interface{}, opts ...queue.PublishOption) error
+	// Reconnect replaces a connection the broker closed or stopped confirming on
+	Reconnect() error
+}
+
+// Relay publishes pending outbox events to a durable topic exchange, oldest
+// first. An event is marked sent only after the broker confirms it, so a
+// crash in between publishes it again: delivery is at least once. A Relay is
+// not safe for concurrent use; replicas each run their own and skip the rows
+// another one has locked
+type Relay struct {
+	db        *sql.DB
+	publisher Publisher
+	exchange  string
+	declared  bool
+	// reconnect is set when the connection failed, to replace it before the
+	// next publish
+	reconnect bool
+}
+
+func NewRelay(db *sql.DB, publisher Publisher, exchange string) *Relay {
+	return &Relay{
+		db:        db,
+		publisher: publisher,
+		exchange:  exchange,
+	}
+}
+
+// PublishPending publishes up to limit unsent events and returns how many
+// were sent. It stops at the first failure so events keep their order
+func (r *Relay) PublishPending(ctx context.Context, limit int64) (int, error) {
+	if r.reconnect {
+		if err := r.publisher.Reconnect(); err != nil {
+			return 0, fmt.Errorf("failed to reconnect publisher: %w", err)
+		}
+		r.reconnect = false
+	}
+	if !r.declared {
+		if err := r.publisher.DeclareExchange(r.exchange, "topic", true, false, false, false); err != nil {
+			r.reconnect = connectionFailed(err)
+			return 0, fmt.Errorf("failed to declare exchange %s: %w", r.exchange, err)
+		}
+		r.declared = true
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	events, ids, err := r.lockPending(ctx, tx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var publishErr error
+	for i, e := range events {
+		publishErr = r.publisher.PublishToExchange(r.exchange, e.Type, e,
+			queue.WithMessageID(e.ID),
+			queue.WithType(e.Type),
+			queue.WithTimestamp(e.OccurredAt),
+			queue.WithPersistent(),
+		)
+		if publishErr != nil {
+			// The exchange is declared again once the broker is back
+			r.declared = false
+			r.reconnect = connectionFailed(publishErr)
+			_, err := tx.ExecContext(ctx, `
+				UPDATE outbox SET attempts = attempts + 1, last_error = $1 WHERE id = $2
+			`, publishErr.Error(), ids[i])
+			if err != nil {
+				return sent, err
+			}
+			break
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE outbox SET sent_at = NOW() WHERE id = $1`, ids[i]); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if publishErr != nil {
+		return sent, fmt.Errorf("failed to publish %s event %s: %w", events[sent].Type, events[sent].ID, publishErr)
+	}
+	return sent, nil
+}
+
+// connectionFailed reports whether err means the connection to the broker
+// is closed or no longer confirms publishes
+func connectionFailed(err error) bool {
+	return errors.Is(err, amqp.ErrClosed) || errors.Is(err, queue.ErrNotConfirmed)
+}
+
+// lockPending locks the oldest unsent events. SKIP LOCKED lets relays on
+// other replicas take the next rows instead of waiting
+func (r *Relay) lockPending(ctx context.Context, tx *sql.Tx, limit int64) ([]Event, []int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, event_id, event_type, aggregate_type, aggregate_id, occurred_at, data
+		FROM outbox
+		WHERE sent_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var e Event
+		var data []byte
+		if err := rows.Scan(&id, &e.ID, &e.Type, &e.AggregateType, &e.AggregateID, &e.OccurredAt, &data); err != nil {
+			return nil, nil, err
+		}
+		e.Data = data
+		events = append(events, e)
+		ids = append(ids, id)
+	}
+	return events, ids, rows.Err()
+}
+
+// Prune deletes events that were sent before the cutoff and returns how many
+// were removed
+func (r *Relay) Prune(ctx context.Context, sentBefore time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE sent_at < $1`, sentBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
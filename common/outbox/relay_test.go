@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Nha1410/go-zero-template/common/queue"
+	"github.com/streadway/amqp"
+)
+
+// fakePublisher fails like a channel the broker closed until Reconnect
+type fakePublisher struct {
+	closed     bool
+	reconnects int
+	published  []string
+}
+
+func (p *fakePublisher) DeclareExchange(name, kind string, durable, autoDelete, internal, noWait bool) error {
+	if p.closed {
+		return amqp.ErrClosed
+	}
+	return nil
+}
+
+func (p *fakePublisher) PublishToExchange(exchange, routingKey string, message interface{}, opts ...queue.PublishOption) error {
+	if p.closed {
+		return amqp.ErrClosed
+	}
+	p.published = append(p.published, message.(Event).ID)
+	return nil
+}
+
+func (p *fakePublisher) Reconnect() error {
+	p.reconnects++
+	p.closed = false
+	return nil
+}
+
+var outboxColumns = []string{"id", "event_id", "event_type", "aggregate_type", "aggregate_id", "occurred_at", "data"}
+
+func expectPending(mock sqlmock.Sqlmock, id int64, eventID string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT (.+) FROM outbox`).
+		WillReturnRows(sqlmock.NewRows(outboxColumns).
+			AddRow(id, eventID, "user.created", "user", "1", time.Now(), []byte(`{}`)))
+}
+
+func TestRelayReconnectsAfterChannelClosed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	publisher := &fakePublisher{}
+	relay := NewRelay(db, publisher, "user.events")
+	ctx := context.Background()
+
+	expectPending(mock, 1, "event-1")
+	mock.ExpectExec(`UPDATE outbox SET sent_at`).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if n, err := relay.PublishPending(ctx, 10); err != nil || n != 1 {
+		t.Fatalf("PublishPending = %d, %v, want 1, nil", n, err)
+	}
+
+	// The broker closes the channel, so the next publish fails
+	publisher.closed = true
+	expectPending(mock, 2, "event-2")
+	mock.ExpectExec(`UPDATE outbox SET attempts`).
+		WithArgs(amqp.ErrClosed.Error(), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	n, err := relay.PublishPending(ctx, 10)
+	if !errors.Is(err, amqp.ErrClosed) || n != 0 {
+		t.Fatalf("PublishPending on a closed channel = %d, %v, want 0, amqp.ErrClosed", n, err)
+	}
+
+	// The next poll reconnects and publishes
+	expectPending(mock, 2, "event-2")
+	mock.ExpectExec(`UPDATE outbox SET sent_at`).
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	n, err = relay.PublishPending(ctx, 10)
+	if err != nil || n != 1 {
+		t.Fatalf("PublishPending after reconnect = %d, %v, want 1, nil", n, err)
+	}
+	if publisher.reconnects != 1 {
+		t.Fatalf("reconnects = %d, want 1", publisher.reconnects)
+	}
+	if len(publisher.published) != 2 || publisher.published[1] != "event-2" {
+		t.Fatalf("published = %v, want [event-1 event-2]", publisher.published)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
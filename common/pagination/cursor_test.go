@@ -0,0 +1,72 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func newTestCodec(t *testing.T, secret string) *CursorCodec {
+	t.Helper()
+	cc, err := NewCursorCodec(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cc
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cc := newTestCodec(t, "secret")
+	tests := []Cursor{
+		{Key: "2024-05-01T02:00:00Z", ID: 7, Scope: "abc"},
+		{Key: "jane@example.com", ID: 1, Backward: true, Scope: "abc"},
+		{Key: "", ID: 0},
+	}
+
+	for _, want := range tests {
+		got, err := cc.Decode(cc.Encode(want))
+		if err != nil || got != want {
+			t.Errorf("Decode(Encode(%+v)) = %+v, %v", want, got, err)
+		}
+	}
+}
+
+func TestCursorRejectsTampering(t *testing.T) {
+	cc := newTestCodec(t, "secret")
+	token := cc.Encode(Cursor{Key: "a", ID: 7, Scope: "abc"})
+	body, sig, _ := strings.Cut(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"k":"a","i":1,"s":"abc"}`))
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "empty", token: ""},
+		{name: "unsigned", token: body},
+		{name: "changed body", token: forged + "." + sig},
+		{name: "changed signature", token: body + "." + sig[:len(sig)-2] + "AA"},
+		{name: "malformed signature", token: body + ".!"},
+		{name: "other secret", token: newTestCodec(t, "other").Encode(Cursor{Key: "a", ID: 7, Scope: "abc"})},
+		{name: "signed garbage", token: "e30x." + base64.RawURLEncoding.EncodeToString(cc.sign("e30x"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := cc.Decode(tt.token); err != ErrInvalidCursor {
+				t.Fatalf("Decode(%q) error = %v, want %v", tt.token, err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestCursorRandomSecret(t *testing.T) {
+	a, b := newTestCodec(t, ""), newTestCodec(t, "")
+	token := a.Encode(Cursor{Key: "a", ID: 1})
+
+	if _, err := a.Decode(token); err != nil {
+		t.Fatalf("Decode() error = %v with the issuing codec", err)
+	}
+	if _, err := b.Decode(token); err != ErrInvalidCursor {
+		t.Fatalf("Decode() error = %v with another random secret, want %v", err, ErrInvalidCursor)
+	}
+}
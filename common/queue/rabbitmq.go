@@ -1,8 +1,12 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/streadway/amqp"
 	"github.com/zeromicro/go-zero/core/logx"
@@ -17,14 +21,67 @@ type RabbitMQConfig struct {
 	VHost    string
 }
 
-// RabbitMQClient wraps RabbitMQ connection and channel
+// ErrUnavailable is returned while the client is not connected
+var ErrUnavailable = errors.New("rabbitmq unavailable")
+
+// ErrNotConfirmed is returned by PublishToExchange when the broker nacks the
+// message or does not confirm it in time. The message may still have been
+// routed, so callers retrying it must tolerate duplicates
+var ErrNotConfirmed = errors.New("rabbitmq did not confirm the message")
+
+const (
+	// confirmTimeout bounds how long PublishToExchange waits for a confirm
+	confirmTimeout = 5 * time.Second
+	// Delays between attempts to reconnect after the broker drops the connection
+	reconnectInitialBackoff = 500 * time.Millisecond
+	reconnectMaxBackoff     = 10 * time.Second
+)
+
+// RabbitMQClient wraps RabbitMQ connection and channel. The zero value (and a
+// nil pointer) is a disconnected client whose operations return ErrUnavailable
+// until Connect succeeds. When the broker drops the connection or a channel,
+// the client becomes disconnected again until Connect or Reconnect succeeds
 type RabbitMQClient struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	mu sync.Mutex
+	// config is the one last passed to Connect, used by Reconnect
+	config *RabbitMQConfig
+	// done is closed by Close to stop reconnecting
+	done      chan struct{}
+	conn      *amqp.Connection
+	channel   *amqp.Channel
+	publisher *confirmChannel
+	consumers []string
+}
+
+// confirmChannel is a channel in confirm mode. Publishes are serialized so
+// each one can wait for its own confirm
+type confirmChannel struct {
+	mu       sync.Mutex
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+	// tag is the delivery tag of the last publish
+	tag uint64
 }
 
 // NewRabbitMQClient creates a new RabbitMQ client
 func NewRabbitMQClient(config RabbitMQConfig) (*RabbitMQClient, error) {
+	r := &RabbitMQClient{}
+	if err := r.Connect(config); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Connect dials the broker and makes the client use the new connection. It is
+// safe to call while other goroutines use the client
+func (r *RabbitMQClient) Connect(config RabbitMQConfig) error {
+	r.mu.Lock()
+	r.config = &config
+	if r.done == nil {
+		r.done = make(chan struct{})
+	}
+	r.mu.Unlock()
+
 	dsn := fmt.Sprintf("amqp://%s:%s@%s:%d/%s",
 		config.User,
 		config.Password,
@@ -35,26 +92,198 @@ func NewRabbitMQClient(config RabbitMQConfig) (*RabbitMQClient, error) {
 
 	conn, err := amqp.Dial(dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to rabbitmq: %w", err)
+		return fmt.Errorf("failed to connect to rabbitmq: %w", err)
 	}
 
 	channel, err := conn.Channel()
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to open channel: %w", err)
+		return fmt.Errorf("failed to open channel: %w", err)
 	}
 
+	publisher, err := newConfirmChannel(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	r.mu.Lock()
+	oldConn, done := r.conn, r.done
+	r.conn, r.channel, r.publisher = conn, channel, publisher
+	r.mu.Unlock()
+	if oldConn != nil {
+		oldConn.Close()
+	}
+	r.watch(conn, channel, publisher, done)
+
 	logx.Infof("Successfully connected to RabbitMQ at %s:%d", config.Host, config.Port)
+	return nil
+}
 
-	return &RabbitMQClient{
-		conn:    conn,
-		channel: channel,
+// Reconnect dials the broker again with the config last passed to Connect. It
+// returns ErrUnavailable if Connect was never called
+func (r *RabbitMQClient) Reconnect() error {
+	if r == nil {
+		return ErrUnavailable
+	}
+	r.mu.Lock()
+	config := r.config
+	r.mu.Unlock()
+	if config == nil {
+		return ErrUnavailable
+	}
+	return r.Connect(*config)
+}
+
+// watch disconnects the client when the broker closes conn or one of its
+// channels, so operations return ErrUnavailable instead of amqp.ErrClosed, and
+// reconnects with backoff until it succeeds or done is closed. Closes made by
+// Close and Connect are ignored
+func (r *RabbitMQClient) watch(conn *amqp.Connection, channel *amqp.Channel, publisher *confirmChannel, done chan struct{}) {
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	channelClosed := channel.NotifyClose(make(chan *amqp.Error, 1))
+	publisherClosed := publisher.channel.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		var err *amqp.Error
+		select {
+		case err = <-connClosed:
+		case err = <-channelClosed:
+		case err = <-publisherClosed:
+		}
+		r.mu.Lock()
+		current := r.conn == conn
+		if current {
+			r.conn, r.channel, r.publisher = nil, nil, nil
+		}
+		r.mu.Unlock()
+		if !current {
+			return
+		}
+		// A channel closed by the broker leaves the connection open
+		conn.Close()
+		logx.Errorf("RabbitMQ connection lost, reconnecting: %v", err)
+		r.reconnect(done)
+	}()
+}
+
+// reconnect calls Reconnect until it succeeds, another caller reconnects the
+// client or done is closed
+func (r *RabbitMQClient) reconnect(done chan struct{}) {
+	backoff := reconnectInitialBackoff
+	for {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		r.mu.Lock()
+		connected := r.conn != nil
+		r.mu.Unlock()
+		if connected {
+			return
+		}
+		err := r.Reconnect()
+		if err == nil {
+			return
+		}
+		backoff = min(backoff*2, reconnectMaxBackoff)
+		logx.Errorf("Failed to reconnect to RabbitMQ, retrying in %s: %v", backoff, err)
+	}
+}
+
+// ch returns the open channel or ErrUnavailable
+func (r *RabbitMQClient) ch() (*amqp.Channel, error) {
+	if r == nil {
+		return nil, ErrUnavailable
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.channel == nil {
+		return nil, ErrUnavailable
+	}
+	return r.channel, nil
+}
+
+func newConfirmChannel(conn *amqp.Connection) (*confirmChannel, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open publisher channel: %w", err)
+	}
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	return &confirmChannel{
+		channel:  channel,
+		confirms: channel.NotifyPublish(make(chan amqp.Confirmation, 1)),
 	}, nil
 }
 
+// publish sends msg and waits until the broker confirms it
+func (c *confirmChannel) publish(exchange, routingKey string, msg amqp.Publishing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.channel.Publish(exchange, routingKey, false, false, msg); err != nil {
+		return err
+	}
+	c.tag++
+
+	timer := time.NewTimer(confirmTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case confirm, ok := <-c.confirms:
+			if !ok {
+				return fmt.Errorf("%w: channel closed", ErrNotConfirmed)
+			}
+			// Late confirm of an earlier publish that timed out
+			if confirm.DeliveryTag < c.tag {
+				continue
+			}
+			if !confirm.Ack {
+				return fmt.Errorf("%w: nacked by broker", ErrNotConfirmed)
+			}
+			return nil
+		case <-timer.C:
+			return fmt.Errorf("%w: timed out after %s", ErrNotConfirmed, confirmTimeout)
+		}
+	}
+}
+
+// PublishOption sets a property of a message published by PublishToExchange
+type PublishOption func(*amqp.Publishing)
+
+// WithMessageID sets the message ID consumers can deduplicate on
+func WithMessageID(id string) PublishOption {
+	return func(p *amqp.Publishing) { p.MessageId = id }
+}
+
+// WithType sets the message type
+func WithType(messageType string) PublishOption {
+	return func(p *amqp.Publishing) { p.Type = messageType }
+}
+
+// WithTimestamp sets the message timestamp
+func WithTimestamp(t time.Time) PublishOption {
+	return func(p *amqp.Publishing) { p.Timestamp = t }
+}
+
+// WithPersistent asks the broker to write the message to disk in durable queues
+func WithPersistent() PublishOption {
+	return func(p *amqp.Publishing) { p.DeliveryMode = amqp.Persistent }
+}
+
 // DeclareQueue declares a queue
 func (r *RabbitMQClient) DeclareQueue(name string, durable, autoDelete, exclusive, noWait bool) error {
-	_, err := r.channel.QueueDeclare(
+	channel, err := r.ch()
+	if err != nil {
+		return err
+	}
+	_, err = channel.QueueDeclare(
 		name,       // name
 		durable,    // durable
 		autoDelete, // auto-delete
@@ -72,7 +301,12 @@ func (r *RabbitMQClient) Publish(queue string, message interface{}) error {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	return r.channel.Publish(
+	channel, err := r.ch()
+	if err != nil {
+		return err
+	}
+
+	return channel.Publish(
 		"",    // exchange
 		queue, // routing key
 		false, // mandatory
@@ -84,9 +318,22 @@ func (r *RabbitMQClient) Publish(queue string, message interface{}) error {
 	)
 }
 
-// Consume consumes messages from a queue
+// Consume consumes messages from a queue. The consumer is cancelled by Close
+// so in-flight deliveries can finish before the connection goes away
 func (r *RabbitMQClient) Consume(queue string, consumer string, autoAck, exclusive, noLocal, noWait bool) (<-chan amqp.Delivery, error) {
-	return r.channel.Consume(
+	channel, err := r.ch()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if consumer == "" {
+		consumer = fmt.Sprintf("%s-consumer-%d", queue, len(r.consumers)+1)
+	}
+	r.consumers = append(r.consumers, consumer)
+	r.mu.Unlock()
+
+	return channel.Consume(
 		queue,     // queue
 		consumer,  // consumer
 		autoAck,   // auto-ack
@@ -99,7 +346,11 @@ func (r *RabbitMQClient) Consume(queue string, consumer string, autoAck, exclusi
 
 // DeclareExchange declares an exchange
 func (r *RabbitMQClient) DeclareExchange(name, kind string, durable, autoDelete, internal, noWait bool) error {
-	return r.channel.ExchangeDeclare(
+	channel, err := r.ch()
+	if err != nil {
+		return err
+	}
+	return channel.ExchangeDeclare(
 		name,       // name
 		kind,       // kind
 		durable,    // durable
@@ -110,38 +361,88 @@ func (r *RabbitMQClient) DeclareExchange(name, kind string, durable, autoDelete,
 	)
 }
 
-// PublishToExchange publishes a message to an exchange
-func (r *RabbitMQClient) PublishToExchange(exchange, routingKey string, message interface{}) error {
+// PublishToExchange publishes a message to an exchange and waits for the
+// broker to confirm it. It returns ErrNotConfirmed when no confirm arrives
+func (r *RabbitMQClient) PublishToExchange(exchange, routingKey string, message interface{}, opts ...PublishOption) error {
 	body, err := json.Marshal(message)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	return r.channel.Publish(
-		exchange,   // exchange
-		routingKey, // routing key
-		false,      // mandatory
-		false,      // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	)
+	if r == nil {
+		return ErrUnavailable
+	}
+	r.mu.Lock()
+	publisher := r.publisher
+	r.mu.Unlock()
+	if publisher == nil {
+		return ErrUnavailable
+	}
+
+	msg := amqp.Publishing{
+		ContentType: "application/json",
+		Body:        body,
+	}
+	for _, opt := range opts {
+		opt(&msg)
+	}
+	return publisher.publish(exchange, routingKey, msg)
 }
 
-// Close closes the RabbitMQ connection
+// Ping checks that the broker connection is open by opening and closing a
+// channel on it
+func (r *RabbitMQClient) Ping(ctx context.Context) error {
+	if r == nil {
+		return ErrUnavailable
+	}
+	r.mu.Lock()
+	conn := r.conn
+	r.mu.Unlock()
+	if conn == nil {
+		return ErrUnavailable
+	}
+	if conn.IsClosed() {
+		return fmt.Errorf("rabbitmq connection is closed")
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open channel: %w", err)
+	}
+	return ch.Close()
+}
+
+// Close stops all consumers, then closes the channel and connection
 func (r *RabbitMQClient) Close() error {
-	if r.channel != nil {
-		r.channel.Close()
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	conn, channel, consumers := r.conn, r.channel, r.consumers
+	r.conn, r.channel, r.publisher, r.consumers = nil, nil, nil, nil
+	if r.done != nil {
+		close(r.done)
+		r.done = nil
+	}
+	r.mu.Unlock()
+
+	if channel != nil {
+		for _, consumer := range consumers {
+			if err := channel.Cancel(consumer, false); err != nil {
+				logx.Errorf("Failed to cancel consumer %s: %v", consumer, err)
+			}
+		}
+		channel.Close()
 	}
-	if r.conn != nil {
-		return r.conn.Close()
+	if conn != nil {
+		return conn.Close()
 	}
 	return nil
 }
 
-// GetChannel returns the underlying channel
+// GetChannel returns the underlying channel, or nil while disconnected
 func (r *RabbitMQClient) GetChannel() *amqp.Channel {
-	return r.channel
+	channel, _ := r.ch()
+	return channel
 }
 
@@ -21,6 +21,31 @@ func init() {
 	})
 }
 
+// FieldError describes a single field that failed validation
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError lists every field that failed validation
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, fmt.Sprintf("Field '%s' failed validation: %s", f.Field, f.Message))
+	}
+	return fmt.Sprintf("validation failed: %s", strings.Join(messages, "; "))
+}
+
+// RegisterRules attaches validation rules keyed by Go field name to types
+// whose fields cannot carry validate tags, such as generated protobuf messages
+func RegisterRules(rules map[string]string, types ...interface{}) {
+	validate.RegisterStructValidationMapRules(rules, types...)
+}
+
 // Validate validates a struct
 func Validate(s interface{}) error {
 	if err := validate.Struct(s); err != nil {
@@ -32,12 +57,14 @@ func Validate(s interface{}) error {
 // formatValidationError formats validation errors into a readable message
 func formatValidationError(err error) error {
 	if validationErrors, ok := err.(validator.ValidationErrors); ok {
-		var messages []string
+		fields := make([]FieldError, 0, len(validationErrors))
 		for _, fieldError := range validationErrors {
-			message := fmt.Sprintf("Field '%s' failed validation: %s", fieldError.Field(), getValidationMessage(fieldError))
-			messages = append(messages, message)
+			fields = append(fields, FieldError{
+				Field:   fieldError.Field(),
+				Message: getValidationMessage(fieldError),
+			})
 		}
-		return fmt.Errorf("validation failed: %s", strings.Join(messages, "; "))
+		return &ValidationError{Fields: fields}
 	}
 	return err
 }
@@ -55,8 +82,16 @@ func getValidationMessage(fieldError validator.FieldError) string {
 		return fmt.Sprintf("must be at most %s", fieldError.Param())
 	case "len":
 		return fmt.Sprintf("must be exactly %s characters", fieldError.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldError.Param())
+	case "gte":
+		return fmt.Sprintf("must be at least %s", fieldError.Param())
+	case "lte":
+		return fmt.Sprintf("must be at most %s", fieldError.Param())
 	case "oneof":
 		return fmt.Sprintf("must be one of: %s", fieldError.Param())
+	case "datetime":
+		return fmt.Sprintf("must be a timestamp in the format %s", fieldError.Param())
 	default:
 		return fmt.Sprintf("failed validation rule: %s", fieldError.Tag())
 	}
@@ -1,17 +0,0 @@
--- Initialize database schema for PostgreSQL
--- This file will be executed when the PostgreSQL container starts for the first time
-
-CREATE TABLE IF NOT EXISTS users (
-    id BIGSERIAL PRIMARY KEY,
-    email VARCHAR(255) NOT NULL UNIQUE,
-    name VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-);
-
--- Create index on email for faster lookups
-CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
-
--- Create index on created_at for sorting
-CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
-
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"time"
+
 	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/common/lifecycle"
 	"github.com/zeromicro/go-zero/zrpc"
 )
 
@@ -10,6 +13,9 @@ type Config struct {
 	Database struct {
 		Postgres database.PostgresConfig
 		Type     string
+		// Tx sets the isolation level and serialization retries of usecase
+		// transactions
+		Tx database.TxOptions
 	}
 	AppRedis struct {
 		Host     string
@@ -25,4 +31,48 @@ type Config struct {
 		Password string
 		VHost    string
 	}
+	// Startup bounds how long dependencies are retried at boot
+	Startup lifecycle.StartupConfig
+	// Dependencies declares which external systems must be up to start. The
+	// database is always required
+	Dependencies struct {
+		Redis    lifecycle.Requirement
+		RabbitMQ lifecycle.Requirement
+	}
+	Migrations struct {
+		// AutoMigrate applies pending migrations at startup, before serving
+		AutoMigrate bool
+	}
+	SoftDelete struct {
+		// Retention is how long deleted users can be restored before they are
+		// purged; zero disables purging
+		Retention      time.Duration
+		PurgeInterval  time.Duration
+		PurgeBatchSize int64
+	}
+	Outbox struct {
+		// Exchange is the topic exchange user events are published to
+		Exchange     string
+		PollInterval time.Duration
+		BatchSize    int64
+		// Retention is how long sent events are kept; zero keeps them
+		Retention time.Duration
+	}
+	Cache struct {
+		// Enabled serves user lookups from Redis
+		Enabled bool
+		TTL     time.Duration
+		// NegativeTTL is how long a missing user is remembered; zero disables it
+		NegativeTTL time.Duration
+	}
+	Pagination struct {
+		// CursorSecret signs list cursors and must be shared by all replicas
+		CursorSecret string
+	}
+	HealthCheck struct {
+		// Timeout bounds each dependency check
+		Timeout time.Duration
+		// CacheTTL is how long a readiness report is reused
+		CacheTTL time.Duration
+	}
 }
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"database/sql"
 	"time"
 
 	envConfig "github.com/Nha1410/go-zero-template/common/config"
+	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/common/lifecycle"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/service"
 	"github.com/zeromicro/go-zero/zrpc"
@@ -28,6 +31,18 @@ func LoadFromEnv() Config {
 	}
 
 	c.Mode = envConfig.GetString("USER_SERVICE_MODE", "dev")
+	// The dev server exposes Prometheus metrics, including the user cache's
+	c.DevServer = service.DevServerConfig{
+		Enabled:        envConfig.GetBool("USER_SERVICE_METRICS_ENABLED", false),
+		Port:           envConfig.GetInt("USER_SERVICE_METRICS_PORT", 6470),
+		MetricsPath:    "/metrics",
+		HealthPath:     "/healthz",
+		HealthResponse: "OK",
+		EnableMetrics:  true,
+	}
+	// The built-in health server always reports SERVING; main registers one
+	// backed by the dependency checks instead
+	c.Health = false
 	c.Database.Type = envConfig.GetString("DATABASE_TYPE", "postgres")
 	c.Database.Postgres.Host = envConfig.GetString("DATABASE_HOST", "localhost")
 	c.Database.Postgres.Port = envConfig.GetInt("DATABASE_PORT", 5432)
@@ -39,6 +54,8 @@ func LoadFromEnv() Config {
 	c.Database.Postgres.MaxIdleConns = envConfig.GetInt("DATABASE_MAX_IDLE_CONNS", 10)
 	c.Database.Postgres.ConnMaxLifetime = time.Duration(envConfig.GetInt("DATABASE_CONN_MAX_LIFETIME", 3600)) * time.Second
 	c.Database.Postgres.ConnMaxIdleTime = time.Duration(envConfig.GetInt("DATABASE_CONN_MAX_IDLE_TIME", 600)) * time.Second
+	c.Database.Tx.Isolation = getIsolationLevel("DATABASE_TX_ISOLATION", sql.LevelSerializable)
+	c.Database.Tx.MaxRetries = envConfig.GetInt("DATABASE_TX_MAX_RETRIES", 3)
 
 	c.AppRedis.Host = envConfig.GetString("REDIS_HOST", "localhost")
 	c.AppRedis.Port = envConfig.GetInt("REDIS_PORT", 6379)
@@ -52,5 +69,62 @@ func LoadFromEnv() Config {
 	c.RabbitMQ.Password = envConfig.GetString("RABBITMQ_PASSWORD", "guest")
 	c.RabbitMQ.VHost = envConfig.GetString("RABBITMQ_VHOST", "/")
 
+	c.Migrations.AutoMigrate = envConfig.GetBool("USER_SERVICE_AUTO_MIGRATE", false)
+
+	c.SoftDelete.Retention = time.Duration(envConfig.GetInt("USER_DELETED_RETENTION_DAYS", 30)) * 24 * time.Hour
+	c.SoftDelete.PurgeInterval = time.Duration(envConfig.GetInt("USER_PURGE_INTERVAL", 3600)) * time.Second
+	c.SoftDelete.PurgeBatchSize = int64(envConfig.GetInt("USER_PURGE_BATCH_SIZE", 500))
+
+	c.Outbox.Exchange = envConfig.GetString("USER_EVENTS_EXCHANGE", "user.events")
+	c.Outbox.PollInterval = time.Duration(envConfig.GetInt("USER_OUTBOX_POLL_INTERVAL_MS", 1000)) * time.Millisecond
+	c.Outbox.BatchSize = int64(envConfig.GetInt("USER_OUTBOX_BATCH_SIZE", 100))
+	c.Outbox.Retention = time.Duration(envConfig.GetInt("USER_OUTBOX_RETENTION_DAYS", 7)) * 24 * time.Hour
+
+	c.Cache.Enabled = envConfig.GetBool("USER_CACHE_ENABLED", false)
+	c.Cache.TTL = time.Duration(envConfig.GetInt("USER_CACHE_TTL", 300)) * time.Second
+	c.Cache.NegativeTTL = time.Duration(envConfig.GetInt("USER_CACHE_NEGATIVE_TTL", 30)) * time.Second
+
+	c.Pagination.CursorSecret = envConfig.GetString("USER_CURSOR_SECRET", "")
+
+	c.HealthCheck.Timeout = time.Duration(envConfig.GetInt("HEALTH_CHECK_TIMEOUT", 2)) * time.Second
+	c.HealthCheck.CacheTTL = time.Duration(envConfig.GetInt("HEALTH_CHECK_CACHE_TTL", 1)) * time.Second
+
+	c.Startup.Timeout = time.Duration(envConfig.GetInt("STARTUP_TIMEOUT", 60)) * time.Second
+	c.Startup.MaxBackoff = time.Duration(envConfig.GetInt("STARTUP_MAX_BACKOFF", 10)) * time.Second
+	c.Dependencies.Redis = getRequirement("USER_SERVICE_REDIS_REQUIREMENT", lifecycle.Optional)
+	c.Dependencies.RabbitMQ = getRequirement("USER_SERVICE_RABBITMQ_REQUIREMENT", lifecycle.Optional)
+
+	c.Shutdown.WaitTime = time.Duration(envConfig.GetInt("SHUTDOWN_TIMEOUT", 30)) * time.Second
+
 	return c
 }
+
+// getRequirement reads a dependency requirement, falling back to defaultValue
+// when the variable is unset or invalid
+func getRequirement(key string, defaultValue lifecycle.Requirement) lifecycle.Requirement {
+	value := envConfig.GetString(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	req, err := lifecycle.ParseRequirement(value)
+	if err != nil {
+		logx.Errorf("Ignoring %s: %v", key, err)
+		return defaultValue
+	}
+	return req
+}
+
+// getIsolationLevel reads a transaction isolation level, falling back to
+// defaultValue when the variable is unset or invalid
+func getIsolationLevel(key string, defaultValue sql.IsolationLevel) sql.IsolationLevel {
+	value := envConfig.GetString(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	level, err := database.ParseIsolationLevel(value)
+	if err != nil {
+		logx.Errorf("Ignoring %s: %v", key, err)
+		return defaultValue
+	}
+	return level
+}
@@ -9,6 +9,10 @@ type User struct {
 	Name      string    `json:"name" db:"name"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// DeletedAt is set while the user is soft-deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	// Version starts at 1 and is incremented by every write
+	Version int64 `json:"version" db:"version"`
 }
 
 // TableName returns the table name for the user entity
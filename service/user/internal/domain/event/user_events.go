@@ -0,0 +1,41 @@
+package event
+
+import (
+	"time"
+
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/entity"
+)
+
+// AggregateUser is the aggregate type of user events
+const AggregateUser = "user"
+
+// Types of the user events, also used as their routing keys
+const (
+	UserCreated  = "user.created"
+	UserUpdated  = "user.updated"
+	UserDeleted  = "user.deleted"
+	UserRestored = "user.restored"
+)
+
+// UserData is the data of every user event: the user after the change
+type UserData struct {
+	ID        int64      `json:"id"`
+	Email     string     `json:"email"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	Version   int64      `json:"version"`
+}
+
+func NewUserData(user *entity.User) UserData {
+	return UserData{
+		ID:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		DeletedAt: user.DeletedAt,
+		Version:   user.Version,
+	}
+}
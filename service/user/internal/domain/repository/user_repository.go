@@ -2,15 +2,144 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/Nha1410/go-zero-template/service/user/internal/domain/entity"
 )
 
+// SortField is a column users can be listed by. Only the fields declared here
+// are accepted
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortByUpdatedAt SortField = "updated_at"
+	SortByName      SortField = "name"
+	SortByEmail     SortField = "email"
+	SortByID        SortField = "id"
+)
+
+// Valid reports whether f is in the allow-list
+func (f SortField) Valid() bool {
+	switch f {
+	case SortByCreatedAt, SortByUpdatedAt, SortByName, SortByEmail, SortByID:
+		return true
+	}
+	return false
+}
+
+// Key returns the value of f for user: a time.Time, string or int64
+func (f SortField) Key(user *entity.User) interface{} {
+	switch f {
+	case SortByUpdatedAt:
+		return user.UpdatedAt
+	case SortByName:
+		return user.Name
+	case SortByEmail:
+		return user.Email
+	case SortByID:
+		return user.ID
+	default:
+		return user.CreatedAt
+	}
+}
+
+// Sort orders a list by Field, with id as the tie-breaker in the same direction
+type Sort struct {
+	Field SortField
+	Desc  bool
+}
+
+// ListFilter narrows a list. Zero fields do not filter
+type ListFilter struct {
+	// Email matches exactly
+	Email string
+	// NameContains matches a case-insensitive substring of the name
+	NameContains  string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Query matches a case-insensitive substring of the email or name
+	Query string
+	// IncludeDeleted also returns soft-deleted users
+	IncludeDeleted bool
+}
+
+// IsZero reports whether the filter matches every active user
+func (f ListFilter) IsZero() bool {
+	return f.Email == "" && f.NameContains == "" && f.CreatedAfter == nil &&
+		f.CreatedBefore == nil && f.Query == "" && !f.IncludeDeleted
+}
+
+// ListKey is a position in the (sort field, id) ordering used for keyset
+// pagination. Value has the type returned by SortField.Key
+type ListKey struct {
+	Value interface{}
+	ID    int64
+}
+
+// ListQuery selects filtered users in Sort order. With After set, rows
+// strictly after the key are returned; with Before set, rows strictly before
+// it. At most one of them may be set
+type ListQuery struct {
+	Filter ListFilter
+	Sort   Sort
+	Limit  int64
+	After  *ListKey
+	Before *ListKey
+}
+
+// SearchResult is a user matched by Search with its relevance, higher first
+type SearchResult struct {
+	User *entity.User
+	Rank float64
+}
+
+// Errors returned by UserRepository, matched with errors.Is. Any other error
+// is an infrastructure failure
+var (
+	// ErrUserNotFound is returned when no user matches
+	ErrUserNotFound = errors.New("user not found")
+	// ErrVersionConflict is returned by writes when the user's version no
+	// longer matches the one that was read
+	ErrVersionConflict = errors.New("user version conflict")
+)
+
+// Transactor runs fn in a transaction carried by ctx. Repository calls made
+// with that ctx join it, and nested calls run in a savepoint. fn may be run
+// again after a serialization failure, so it must be safe to repeat
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Soft-deleted users are excluded unless includeDeleted or
+// ListFilter.IncludeDeleted is set
 type UserRepository interface {
 	Create(ctx context.Context, user *entity.User) error
-	GetByID(ctx context.Context, id int64) (*entity.User, error)
-	GetByEmail(ctx context.Context, email string) (*entity.User, error)
-	List(ctx context.Context, page, pageSize int64) ([]*entity.User, int64, error)
+	// GetByID and GetByEmail return ErrUserNotFound when no user matches
+	GetByID(ctx context.Context, id int64, includeDeleted bool) (*entity.User, error)
+	GetByEmail(ctx context.Context, email string, includeDeleted bool) (*entity.User, error)
+	// List returns up to query.Limit users, nearest to the key first: in Sort
+	// order for After (or no key) and in reverse for Before
+	List(ctx context.Context, query ListQuery) ([]*entity.User, error)
+	// Search returns up to limit active users matching query by full text or by
+	// trigram similarity, most relevant first
+	Search(ctx context.Context, query string, limit int64) ([]*SearchResult, error)
+	// Count returns the exact number of users matching filter
+	Count(ctx context.Context, filter ListFilter) (int64, error)
+	// EstimateCount returns the planner's row estimate for the whole table,
+	// which avoids a full scan. It includes soft-deleted rows not yet purged
+	EstimateCount(ctx context.Context) (int64, error)
+	// Update changes an active user if it is still at user.Version, then
+	// increments user.Version. It returns ErrVersionConflict otherwise
 	Update(ctx context.Context, user *entity.User) error
-	Delete(ctx context.Context, id int64) error
+	// Delete soft-deletes an active user at deletedAt if it is still at
+	// version. It returns ErrVersionConflict otherwise
+	Delete(ctx context.Context, id, version int64, deletedAt time.Time) error
+	// Restore clears the deletion of a soft-deleted user. It returns
+	// ErrUserNotFound if there is no such deleted user
+	Restore(ctx context.Context, id int64, restoredAt time.Time) error
+	// Purge hard-deletes up to limit users soft-deleted before the cutoff and
+	// returns how many were removed
+	Purge(ctx context.Context, before time.Time, limit int64) (int64, error)
 }
@@ -8,8 +8,11 @@ import (
 	"github.com/Nha1410/go-zero-template/service/user/userclient"
 )
 
+var _ userclient.UserServer = (*UserHandler)(nil)
+
 type UserHandler struct {
 	svcCtx *svc.ServiceContext
+	userclient.UnimplementedUserServer
 }
 
 func NewUserHandler(svcCtx *svc.ServiceContext) *UserHandler {
@@ -33,12 +36,27 @@ func (h *UserHandler) GetUsers(ctx context.Context, req *userclient.GetUsersReq)
 	return l.GetUsers(req)
 }
 
-func (h *UserHandler) UpdateUser(ctx context.Context, req *userclient.UpdateUserReq) (*userclient.UpdateUserResp, error) {
+func (h *UserHandler) SearchUsers(ctx context.Context, req *userclient.SearchUsersReq) (*userclient.SearchUsersResp, error) {
+	l := logic.NewSearchUsersLogic(ctx, h.svcCtx)
+	return l.SearchUsers(req)
+}
+
+func (h *UserHandler) UpdateUser(ctx context.Context, req *userclient.UpdateUserReq) (*userclient.GetUserResp, error) {
 	l := logic.NewUpdateUserLogic(ctx, h.svcCtx)
 	return l.UpdateUser(req)
 }
 
+func (h *UserHandler) PatchUser(ctx context.Context, req *userclient.PatchUserReq) (*userclient.GetUserResp, error) {
+	l := logic.NewPatchUserLogic(ctx, h.svcCtx)
+	return l.PatchUser(req)
+}
+
 func (h *UserHandler) DeleteUser(ctx context.Context, req *userclient.DeleteUserReq) (*userclient.DeleteUserResp, error) {
 	l := logic.NewDeleteUserLogic(ctx, h.svcCtx)
 	return l.DeleteUser(req)
 }
+
+func (h *UserHandler) RestoreUser(ctx context.Context, req *userclient.RestoreUserReq) (*userclient.GetUserResp, error) {
+	l := logic.NewRestoreUserLogic(ctx, h.svcCtx)
+	return l.RestoreUser(req)
+}
@@ -0,0 +1,815 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/common/cache"
+	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/common/interceptor"
+	"github.com/Nha1410/go-zero-template/common/pagination"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/entity"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/repository"
+	userrepo "github.com/Nha1410/go-zero-template/service/user/internal/repository"
+	"github.com/Nha1410/go-zero-template/service/user/internal/svc"
+	"github.com/Nha1410/go-zero-template/service/user/internal/usecase"
+	"github.com/Nha1410/go-zero-template/service/user/userclient"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/zeromicro/go-zero/zrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// memoryUserRepo is an in-memory repository.UserRepository used to run the
+// gRPC server without a database
+type memoryUserRepo struct {
+	mu     sync.Mutex
+	nextID int64
+	users  map[int64]*entity.User
+	// readErr, when set, fails lookups as an unreachable database would
+	readErr error
+}
+
+func newMemoryUserRepo() *memoryUserRepo {
+	return &memoryUserRepo{users: make(map[int64]*entity.User)}
+}
+
+func (r *memoryUserRepo) Create(ctx context.Context, user *entity.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.emailTaken(user.Email, 0) {
+		return errEmailTaken
+	}
+	r.nextID++
+	user.ID, user.Version = r.nextID, 1
+	u := *user
+	r.users[u.ID] = &u
+	return nil
+}
+
+func (r *memoryUserRepo) GetByID(ctx context.Context, id int64, includeDeleted bool) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.readErr != nil {
+		return nil, r.readErr
+	}
+	u, ok := r.users[id]
+	if !ok || u.DeletedAt != nil && !includeDeleted {
+		return nil, repository.ErrUserNotFound
+	}
+	cp := *u
+	return &cp, nil
+}
+
+func (r *memoryUserRepo) GetByEmail(ctx context.Context, email string, includeDeleted bool) (*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.Email == email && (u.DeletedAt == nil || includeDeleted) {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, repository.ErrUserNotFound
+}
+
+func (r *memoryUserRepo) List(ctx context.Context, query repository.ListQuery) ([]*entity.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	all := r.filter(query.Filter)
+	keyOf := func(u *entity.User) repository.ListKey {
+		return repository.ListKey{Value: query.Sort.Field.Key(u), ID: u.ID}
+	}
+	// less reports whether a comes before b in query.Sort order
+	less := func(a, b repository.ListKey) bool {
+		c := compareKeys(a.Value, b.Value)
+		if c == 0 {
+			c = compareKeys(a.ID, b.ID)
+		}
+		if query.Sort.Desc {
+			c = -c
+		}
+		return c < 0
+	}
+	sort.Slice(all, func(i, j int) bool { return less(keyOf(all[i]), keyOf(all[j])) })
+
+	var out []*entity.User
+	if query.Before != nil {
+		// Nearest to the key first, like the SQL repository
+		for i := len(all) - 1; i >= 0 && int64(len(out)) < query.Limit; i-- {
+			if less(keyOf(all[i]), *query.Before) {
+				out = append(out, all[i])
+			}
+		}
+		return out, nil
+	}
+	for _, u := range all {
+		if int64(len(out)) == query.Limit {
+			break
+		}
+		if query.After == nil || less(*query.After, keyOf(u)) {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+// Search stands in for full-text and trigram matching with a substring match
+func (r *memoryUserRepo) Search(ctx context.Context, query string, limit int64) ([]*repository.SearchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*repository.SearchResult
+	for _, u := range r.filter(repository.ListFilter{Query: query}) {
+		if int64(len(out)) == limit {
+			break
+		}
+		out = append(out, &repository.SearchResult{User: u, Rank: 1})
+	}
+	return out, nil
+}
+
+func (r *memoryUserRepo) filter(f repository.ListFilter) []*entity.User {
+	var out []*entity.User
+	for _, u := range r.users {
+		name, email := strings.ToLower(u.Name), strings.ToLower(u.Email)
+		q := strings.ToLower(f.Query)
+		if u.DeletedAt != nil && !f.IncludeDeleted ||
+			f.Email != "" && u.Email != f.Email ||
+			!strings.Contains(name, strings.ToLower(f.NameContains)) ||
+			f.CreatedAfter != nil && !u.CreatedAt.After(*f.CreatedAfter) ||
+			f.CreatedBefore != nil && !u.CreatedAt.Before(*f.CreatedBefore) ||
+			!strings.Contains(email, q) && !strings.Contains(name, q) {
+			continue
+		}
+		cp := *u
+		out = append(out, &cp)
+	}
+	return out
+}
+
+func compareKeys(a, b interface{}) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	default:
+		return strings.Compare(a.(string), b.(string))
+	}
+}
+
+func (r *memoryUserRepo) Count(ctx context.Context, filter repository.ListFilter) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return int64(len(r.filter(filter))), nil
+}
+
+func (r *memoryUserRepo) EstimateCount(ctx context.Context) (int64, error) {
+	return r.Count(ctx, repository.ListFilter{})
+}
+
+func (r *memoryUserRepo) Update(ctx context.Context, user *entity.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.users[user.ID]; !ok || u.DeletedAt != nil || u.Version != user.Version {
+		return repository.ErrVersionConflict
+	}
+	if r.emailTaken(user.Email, user.ID) {
+		return errEmailTaken
+	}
+	user.Version++
+	u := *user
+	r.users[u.ID] = &u
+	return nil
+}
+
+func (r *memoryUserRepo) Delete(ctx context.Context, id, version int64, deletedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || u.DeletedAt != nil || u.Version != version {
+		return repository.ErrVersionConflict
+	}
+	u.DeletedAt, u.UpdatedAt = &deletedAt, deletedAt
+	u.Version++
+	return nil
+}
+
+func (r *memoryUserRepo) Restore(ctx context.Context, id int64, restoredAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || u.DeletedAt == nil {
+		return repository.ErrUserNotFound
+	}
+	if r.emailTaken(u.Email, id) {
+		return errEmailTaken
+	}
+	u.DeletedAt, u.UpdatedAt = nil, restoredAt
+	u.Version++
+	return nil
+}
+
+// emailTaken mirrors the unique index on the emails of active users
+func (r *memoryUserRepo) emailTaken(email string, exceptID int64) bool {
+	for _, u := range r.users {
+		if u.Email == email && u.DeletedAt == nil && u.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryUserRepo) Purge(ctx context.Context, before time.Time, limit int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int64
+	for id, u := range r.users {
+		if n == limit {
+			break
+		}
+		if u.DeletedAt != nil && u.DeletedAt.Before(before) {
+			delete(r.users, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// errEmailTaken is what the database repository returns for a duplicate email
+var errEmailTaken error = &database.Error{Kind: database.ErrUniqueViolation, Table: "users", Field: "email"}
+
+// noTx runs usecase transactions directly; each memory repo call is atomic
+type noTx struct{}
+
+func (noTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// newTestClient starts the user gRPC server on an in-process bufconn listener
+// and returns a zrpc-backed userclient connected to it
+func newTestClient(t *testing.T) userclient.User {
+	t.Helper()
+	return newTestClientWithRepo(t, newMemoryUserRepo())
+}
+
+func newTestClientWithRepo(t *testing.T, repo repository.UserRepository) userclient.User {
+	t.Helper()
+
+	cursors, err := pagination.NewCursorCodec("test-secret")
+	if err != nil {
+		t.Fatalf("failed to create cursor codec: %v", err)
+	}
+	svcCtx := &svc.ServiceContext{
+		UserRepo:    repo,
+		UserUsecase: usecase.NewUserUsecase(repo, noTx{}, cursors),
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptor.UnaryServerInterceptors()...))
+	userclient.RegisterUserServer(server, NewUserHandler(svcCtx))
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	t.Cleanup(server.Stop)
+
+	cli, err := zrpc.NewClientWithTarget("passthrough:///bufnet",
+		zrpc.WithDialOption(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		})),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cli.Conn().Close()
+	})
+
+	return userclient.NewUser(cli)
+}
+
+func TestUserServiceEndToEnd(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: "not-an-email", Name: "Jane"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for invalid email, got %v", err)
+	}
+	if e := errors.FromGRPCError(err); len(e.Violations) != 1 || e.Violations[0].Field != "email" {
+		t.Fatalf("expected an email field violation, got %+v", e)
+	}
+
+	created, err := client.CreateUser(ctx, &userclient.CreateUserReq{
+		Email: "jane@example.com",
+		Name:  "Jane",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if created.Id == 0 || created.Email != "jane@example.com" || created.Name != "Jane" {
+		t.Fatalf("unexpected CreateUser response: %v", created)
+	}
+
+	_, err = client.CreateUser(ctx, &userclient.CreateUserReq{
+		Email: "jane@example.com",
+		Name:  "Jane Again",
+	})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("expected AlreadyExists for duplicate email, got %v", err)
+	}
+	if e := errors.FromGRPCError(err); e.StatusCode != http.StatusConflict || e.Code != "CONFLICT" ||
+		e.Details != "User with this email already exists" || len(e.Violations) != 1 || e.Violations[0].Field != "email" {
+		t.Fatalf("expected conflict to round trip, got %+v", e)
+	}
+
+	got, err := client.GetUser(ctx, &userclient.GetUserReq{Id: created.Id})
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if got.Email != created.Email || got.CreatedAt != created.CreatedAt {
+		t.Fatalf("unexpected GetUser response: %v", got)
+	}
+
+	updated, err := client.UpdateUser(ctx, &userclient.UpdateUserReq{
+		Id:   created.Id,
+		Name: "Jane Doe",
+	})
+	if err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if updated.Name != "Jane Doe" || updated.Email != "jane@example.com" {
+		t.Fatalf("unexpected UpdateUser response: %v", updated)
+	}
+
+	list, err := client.GetUsers(ctx, &userclient.GetUsersReq{PageSize: 10, TotalMode: userclient.TotalMode_TOTAL_MODE_EXACT})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if list.GetTotal() != 1 || !list.TotalExact || len(list.Users) != 1 || list.Users[0].Name != "Jane Doe" ||
+		list.NextCursor != "" || list.PrevCursor != "" {
+		t.Fatalf("unexpected GetUsers response: %v", list)
+	}
+
+	deleted, err := client.DeleteUser(ctx, &userclient.DeleteUserReq{Id: created.Id})
+	if err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if !deleted.Success {
+		t.Fatal("expected DeleteUser to report success")
+	}
+
+	_, err = client.GetUser(ctx, &userclient.GetUserReq{Id: created.Id})
+	if e := errors.FromGRPCError(err); e == nil || e.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected GetUser to return not found after delete, got %v", err)
+	}
+}
+
+func TestGetUsersCursorPagination(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: name + "@example.com", Name: name}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+
+	names := func(resp *userclient.GetUsersResp) string {
+		var out string
+		for _, u := range resp.Users {
+			out += u.Name
+		}
+		return out
+	}
+
+	first, err := client.GetUsers(ctx, &userclient.GetUsersReq{PageSize: 2, TotalMode: userclient.TotalMode_TOTAL_MODE_NONE})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if names(first) != "ed" || first.NextCursor == "" || first.PrevCursor != "" || first.Total != nil {
+		t.Fatalf("unexpected first page: %v", first)
+	}
+
+	second, err := client.GetUsers(ctx, &userclient.GetUsersReq{PageSize: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if names(second) != "cb" || second.NextCursor == "" || second.PrevCursor == "" {
+		t.Fatalf("unexpected second page: %v", second)
+	}
+
+	last, err := client.GetUsers(ctx, &userclient.GetUsersReq{PageSize: 2, Cursor: second.NextCursor})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if names(last) != "a" || last.NextCursor != "" || last.PrevCursor == "" {
+		t.Fatalf("unexpected last page: %v", last)
+	}
+
+	back, err := client.GetUsers(ctx, &userclient.GetUsersReq{PageSize: 2, Cursor: second.PrevCursor})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if names(back) != "ed" || back.PrevCursor != "" || back.NextCursor == "" {
+		t.Fatalf("unexpected previous page: %v", back)
+	}
+
+	_, err = client.GetUsers(ctx, &userclient.GetUsersReq{PageSize: 2, Cursor: first.NextCursor + "x"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for a tampered cursor, got %v", err)
+	}
+}
+
+func TestGetUsersFilterAndSort(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	for _, u := range []struct{ email, name string }{
+		{"carol@example.com", "Carol"},
+		{"alice@example.com", "Alice"},
+		{"bob@test.org", "Bob"},
+		{"dave@example.com", "Dave"},
+	} {
+		if _, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: u.email, Name: u.name}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+
+	req := &userclient.GetUsersReq{
+		PageSize: 2,
+		Q:        "EXAMPLE",
+		Sort:     "name",
+		Order:    userclient.SortOrder_SORT_ORDER_ASC,
+	}
+	first, err := client.GetUsers(ctx, req)
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if len(first.Users) != 2 || first.Users[0].Name != "Alice" || first.Users[1].Name != "Carol" ||
+		first.GetTotal() != 3 || !first.TotalExact {
+		t.Fatalf("unexpected first page: %v", first)
+	}
+
+	req.Cursor = first.NextCursor
+	second, err := client.GetUsers(ctx, req)
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if len(second.Users) != 1 || second.Users[0].Name != "Dave" || second.NextCursor != "" {
+		t.Fatalf("unexpected second page: %v", second)
+	}
+
+	req.Sort = "email"
+	_, err = client.GetUsers(ctx, req)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for a cursor reused with another sort, got %v", err)
+	}
+
+	_, err = client.GetUsers(ctx, &userclient.GetUsersReq{Sort: "password"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for an unsupported sort field, got %v", err)
+	}
+
+	list, err := client.GetUsers(ctx, &userclient.GetUsersReq{NameContains: "o", Email: "bob@test.org"})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if len(list.Users) != 1 || list.Users[0].Name != "Bob" {
+		t.Fatalf("unexpected filtered list: %v", list)
+	}
+
+	_, err = client.GetUsers(ctx, &userclient.GetUsersReq{CreatedAfter: "yesterday"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for an invalid created_after, got %v", err)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	for _, u := range []struct{ email, name string }{
+		{"alice@example.com", "Alice <Admin>"},
+		{"bob@example.com", "Bob"},
+	} {
+		if _, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: u.email, Name: u.name}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+
+	resp, err := client.SearchUsers(ctx, &userclient.SearchUsersReq{Query: "ali"})
+	if err != nil {
+		t.Fatalf("SearchUsers: %v", err)
+	}
+	if len(resp.Hits) != 1 {
+		t.Fatalf("expected one hit, got %v", resp)
+	}
+	hit := resp.Hits[0]
+	if hit.User.Email != "alice@example.com" || hit.NameHighlight != "<mark>Ali</mark>ce &lt;Admin&gt;" ||
+		hit.EmailHighlight != "<mark>ali</mark>ce@example.com" {
+		t.Fatalf("unexpected hit: %v", hit)
+	}
+
+	_, err = client.SearchUsers(ctx, &userclient.SearchUsersReq{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for an empty query, got %v", err)
+	}
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	first, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: "sam@example.com", Name: "Sam"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := client.DeleteUser(ctx, &userclient.DeleteUserReq{Id: first.Id}); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	_, err = client.DeleteUser(ctx, &userclient.DeleteUserReq{Id: first.Id})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound when deleting twice, got %v", err)
+	}
+
+	got, err := client.GetUser(ctx, &userclient.GetUserReq{Id: first.Id, IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("GetUser with include_deleted: %v", err)
+	}
+	if got.DeletedAt == "" {
+		t.Fatalf("expected deleted_at to be set, got %v", got)
+	}
+
+	list, err := client.GetUsers(ctx, &userclient.GetUsersReq{TotalMode: userclient.TotalMode_TOTAL_MODE_EXACT})
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if list.GetTotal() != 0 || len(list.Users) != 0 {
+		t.Fatalf("expected deleted users to be hidden, got %v", list)
+	}
+	list, err = client.GetUsers(ctx, &userclient.GetUsersReq{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("GetUsers with include_deleted: %v", err)
+	}
+	if len(list.Users) != 1 || list.Users[0].Id != first.Id {
+		t.Fatalf("expected the deleted user to be listed, got %v", list)
+	}
+
+	// The email is free again once its owner is deleted
+	second, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: "sam@example.com", Name: "Sam Two"})
+	if err != nil {
+		t.Fatalf("CreateUser with a deleted user's email: %v", err)
+	}
+
+	_, err = client.RestoreUser(ctx, &userclient.RestoreUserReq{Id: first.Id})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("expected AlreadyExists while the email is taken, got %v", err)
+	}
+	_, err = client.RestoreUser(ctx, &userclient.RestoreUserReq{Id: second.Id})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("expected AlreadyExists for an active user, got %v", err)
+	}
+
+	if _, err := client.DeleteUser(ctx, &userclient.DeleteUserReq{Id: second.Id}); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	restored, err := client.RestoreUser(ctx, &userclient.RestoreUserReq{Id: first.Id})
+	if err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+	if restored.Id != first.Id || restored.DeletedAt != "" || restored.Name != "Sam" {
+		t.Fatalf("unexpected RestoreUser response: %v", restored)
+	}
+	if _, err := client.GetUser(ctx, &userclient.GetUserReq{Id: first.Id}); err != nil {
+		t.Fatalf("GetUser after restore: %v", err)
+	}
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: "olive@example.com", Name: "Olive"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if created.Version != 1 {
+		t.Fatalf("expected a new user at version 1, got %d", created.Version)
+	}
+
+	updated, err := client.UpdateUser(ctx, &userclient.UpdateUserReq{Id: created.Id, Name: "Olive A", ExpectedVersion: 1})
+	if err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("expected the update to bump the version to 2, got %d", updated.Version)
+	}
+
+	// A second writer still holding version 1 must not overwrite the change
+	_, err = client.UpdateUser(ctx, &userclient.UpdateUserReq{Id: created.Id, Name: "Olive B", ExpectedVersion: 1})
+	if e := errors.FromGRPCError(err); e == nil || e.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected a stale update to fail its precondition, got %v", err)
+	}
+	_, err = client.DeleteUser(ctx, &userclient.DeleteUserReq{Id: created.Id, ExpectedVersion: 1})
+	if e := errors.FromGRPCError(err); e == nil || e.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected a stale delete to fail its precondition, got %v", err)
+	}
+
+	got, err := client.GetUser(ctx, &userclient.GetUserReq{Id: created.Id})
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if got.Name != "Olive A" || got.Version != 2 {
+		t.Fatalf("expected the first update to survive, got %v", got)
+	}
+
+	if _, err := client.DeleteUser(ctx, &userclient.DeleteUserReq{Id: created.Id, ExpectedVersion: 2}); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+}
+
+func TestPatchUser(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: "pat@example.com", Name: "Pat"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: "taken@example.com", Name: "Taken"}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	// Only masked fields change, even when the message carries others
+	patched, err := client.PatchUser(ctx, &userclient.PatchUserReq{
+		Id:         created.Id,
+		User:       &userclient.UserFields{Email: "ignored@example.com", Name: "Pat Lee"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	if err != nil {
+		t.Fatalf("PatchUser: %v", err)
+	}
+	if patched.Name != "Pat Lee" || patched.Email != "pat@example.com" || patched.Version != 2 {
+		t.Fatalf("unexpected PatchUser response: %v", patched)
+	}
+
+	// Clearing a field is explicit, and the result is validated as a whole
+	_, err = client.PatchUser(ctx, &userclient.PatchUserReq{
+		Id:         created.Id,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+	})
+	if e := errors.FromGRPCError(err); e == nil || e.StatusCode != http.StatusBadRequest ||
+		len(e.Violations) != 1 || e.Violations[0].Field != "name" {
+		t.Fatalf("expected a name violation when clearing the name, got %v", err)
+	}
+
+	_, err = client.PatchUser(ctx, &userclient.PatchUserReq{
+		Id:         created.Id,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"created_at"}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for an unknown path, got %v", err)
+	}
+	_, err = client.PatchUser(ctx, &userclient.PatchUserReq{Id: created.Id})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument without an update_mask, got %v", err)
+	}
+
+	_, err = client.PatchUser(ctx, &userclient.PatchUserReq{
+		Id:         created.Id,
+		User:       &userclient.UserFields{Email: "taken@example.com"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}},
+	})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("expected AlreadyExists for a taken email, got %v", err)
+	}
+
+	_, err = client.PatchUser(ctx, &userclient.PatchUserReq{
+		Id:              created.Id,
+		User:            &userclient.UserFields{Name: "Pat Stale"},
+		UpdateMask:      &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+		ExpectedVersion: 1,
+	})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition for a stale version, got %v", err)
+	}
+}
+
+func TestGetUserRepositoryFailure(t *testing.T) {
+	repo := newMemoryUserRepo()
+	client := newTestClientWithRepo(t, repo)
+	ctx := context.Background()
+
+	created, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: "kim@example.com", Name: "Kim"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	_, err = client.GetUser(ctx, &userclient.GetUserReq{Id: created.Id + 1})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound for a missing user, got %v", err)
+	}
+
+	repo.mu.Lock()
+	repo.readErr = fmt.Errorf("failed to get user %d: %w", created.Id, sql.ErrConnDone)
+	repo.mu.Unlock()
+
+	_, err = client.GetUser(ctx, &userclient.GetUserReq{Id: created.Id})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("expected Internal when the database fails, got %v", err)
+	}
+	if e := errors.FromGRPCError(err); e.Code != "INTERNAL_ERROR" || e.Details != "" {
+		t.Fatalf("expected an internal error without driver details, got %+v", e)
+	}
+}
+
+func TestUserCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	if err != nil {
+		t.Fatalf("invalid miniredis port: %v", err)
+	}
+	redis, err := cache.NewRedisClient(cache.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatalf("failed to connect to miniredis: %v", err)
+	}
+	t.Cleanup(func() { _ = redis.Close() })
+
+	repo := newMemoryUserRepo()
+	// Without a database the manager never starts transactions, so
+	// invalidations run right after each write
+	tx := database.NewTxManager(nil, database.TxOptions{})
+	client := newTestClientWithRepo(t, userrepo.NewCachedUserRepo(repo, tx, redis, time.Minute, time.Minute))
+	ctx := context.Background()
+
+	created, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: "alex@example.com", Name: "Alex"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := client.GetUser(ctx, &userclient.GetUserReq{Id: created.Id}); err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if !mr.Exists("user:id:" + strconv.FormatInt(created.Id, 10)) {
+		t.Fatal("expected the user to be cached after a lookup")
+	}
+
+	repo.mu.Lock()
+	repo.readErr = sql.ErrConnDone
+	repo.mu.Unlock()
+	got, err := client.GetUser(ctx, &userclient.GetUserReq{Id: created.Id})
+	if err != nil || got.Email != "alex@example.com" {
+		t.Fatalf("expected a cache hit while the database is down, got %+v, %v", got, err)
+	}
+	repo.mu.Lock()
+	repo.readErr = nil
+	repo.mu.Unlock()
+
+	// A cached miss is cleared when a user with that ID is created
+	missingID := created.Id + 1
+	_, err = client.GetUser(ctx, &userclient.GetUserReq{Id: missingID})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound for a missing user, got %v", err)
+	}
+	if !mr.Exists("user:id:" + strconv.FormatInt(missingID, 10)) {
+		t.Fatal("expected the miss to be cached")
+	}
+	second, err := client.CreateUser(ctx, &userclient.CreateUserReq{Email: "blair@example.com", Name: "Blair"})
+	if err != nil || second.Id != missingID {
+		t.Fatalf("CreateUser: %+v, %v", second, err)
+	}
+	if _, err := client.GetUser(ctx, &userclient.GetUserReq{Id: missingID}); err != nil {
+		t.Fatalf("expected the new user after a cached miss, got %v", err)
+	}
+
+	if _, err := client.UpdateUser(ctx, &userclient.UpdateUserReq{Id: created.Id, Name: "Alex Updated"}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	got, err = client.GetUser(ctx, &userclient.GetUserReq{Id: created.Id})
+	if err != nil || got.Name != "Alex Updated" || got.Version != 2 {
+		t.Fatalf("expected the update to invalidate the cache, got %+v, %v", got, err)
+	}
+
+	if _, err := client.DeleteUser(ctx, &userclient.DeleteUserReq{Id: created.Id}); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	_, err = client.GetUser(ctx, &userclient.GetUserReq{Id: created.Id})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound after delete, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"github.com/Nha1410/go-zero-template/common/validator"
+	"github.com/Nha1410/go-zero-template/service/user/userclient"
+)
+
+// Generated messages cannot carry validate tags, so their rules are
+// registered here and enforced by interceptor.UnaryValidationInterceptor
+func init() {
+	validator.RegisterRules(map[string]string{
+		"Email": "required,email",
+		"Name":  "required",
+	}, userclient.CreateUserReq{})
+	validator.RegisterRules(map[string]string{
+		"Id": "required,gt=0",
+	}, userclient.GetUserReq{}, userclient.RestoreUserReq{})
+	validator.RegisterRules(map[string]string{
+		"Id":              "required,gt=0",
+		"ExpectedVersion": "gte=0",
+	}, userclient.DeleteUserReq{})
+	validator.RegisterRules(map[string]string{
+		"PageSize":      "gte=0",
+		"Cursor":        "max=512",
+		"Email":         "max=255",
+		"NameContains":  "max=255",
+		"CreatedAfter":  "omitempty,datetime=2006-01-02T15:04:05Z07:00",
+		"CreatedBefore": "omitempty,datetime=2006-01-02T15:04:05Z07:00",
+		"Q":             "max=255",
+		"Sort":          "omitempty,oneof=created_at updated_at name email id",
+	}, userclient.GetUsersReq{})
+	validator.RegisterRules(map[string]string{
+		"Query": "required,max=255",
+		"Limit": "gte=0",
+	}, userclient.SearchUsersReq{})
+	validator.RegisterRules(map[string]string{
+		"Id":              "required,gt=0",
+		"Email":           "omitempty,email",
+		"ExpectedVersion": "gte=0",
+	}, userclient.UpdateUserReq{})
+	validator.RegisterRules(map[string]string{
+		"Id":              "required,gt=0",
+		"UpdateMask":      "required",
+		"ExpectedVersion": "gte=0",
+	}, userclient.PatchUserReq{})
+}
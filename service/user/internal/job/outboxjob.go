@@ -0,0 +1,114 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/common/lifecycle"
+	"github.com/Nha1410/go-zero-template/common/outbox"
+	"github.com/Nha1410/go-zero-template/service/user/internal/svc"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	defaultOutboxBatch = 100
+	// pruneInterval is how often sent outbox events are pruned
+	pruneInterval = time.Hour
+)
+
+// OutboxJob relays user events from the outbox table to RabbitMQ and prunes
+// the ones already published
+type OutboxJob struct {
+	svcCtx *svc.ServiceContext
+	relay  *outbox.Relay
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// failing is set after a failed relay so the error is logged once
+	failing   bool
+	nextPrune time.Time
+}
+
+func NewOutboxJob(svcCtx *svc.ServiceContext) *OutboxJob {
+	return &OutboxJob{
+		svcCtx: svcCtx,
+		relay:  outbox.NewRelay(svcCtx.DB, svcCtx.RabbitMQ, svcCtx.Config.Outbox.Exchange),
+	}
+}
+
+// Start relays pending events every PollInterval. It does nothing when
+// RabbitMQ is disabled; events then stay in the outbox
+func (j *OutboxJob) Start() {
+	if j.svcCtx.Config.Dependencies.RabbitMQ == lifecycle.Disabled || j.svcCtx.Config.Outbox.PollInterval <= 0 {
+		logx.Info("Outbox relay is disabled, user events will not be published")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		ticker := time.NewTicker(j.svcCtx.Config.Outbox.PollInterval)
+		defer ticker.Stop()
+		for {
+			j.relayPending(ctx)
+			j.prune(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop waits for the batch being published and stops the job
+func (j *OutboxJob) Stop() {
+	if j.cancel == nil {
+		return
+	}
+	j.cancel()
+	j.wg.Wait()
+}
+
+// relayPending publishes batches until the outbox is drained or a publish fails
+func (j *OutboxJob) relayPending(ctx context.Context) {
+	batchSize := j.svcCtx.Config.Outbox.BatchSize
+	if batchSize < 1 {
+		batchSize = defaultOutboxBatch
+	}
+	for ctx.Err() == nil {
+		n, err := j.relay.PublishPending(ctx, batchSize)
+		if err != nil {
+			if !j.failing && ctx.Err() == nil {
+				logx.Errorf("Failed to relay user events, retrying every %s: %v", j.svcCtx.Config.Outbox.PollInterval, err)
+			}
+			j.failing = true
+			return
+		}
+		if j.failing {
+			logx.Info("Relaying user events again")
+			j.failing = false
+		}
+		if int64(n) < batchSize {
+			return
+		}
+	}
+}
+
+func (j *OutboxJob) prune(ctx context.Context) {
+	retention := j.svcCtx.Config.Outbox.Retention
+	if retention <= 0 || time.Now().Before(j.nextPrune) {
+		return
+	}
+	j.nextPrune = time.Now().Add(pruneInterval)
+
+	n, err := j.relay.Prune(ctx, time.Now().Add(-retention))
+	if err != nil && ctx.Err() == nil {
+		logx.Errorf("Failed to prune sent user events: %v", err)
+	}
+	if n > 0 {
+		logx.Infof("Pruned %d sent user events", n)
+	}
+}
@@ -0,0 +1,70 @@
+package job
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/service/user/internal/svc"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// PurgeJob periodically hard-deletes users that have been soft-deleted for
+// longer than the configured retention
+type PurgeJob struct {
+	svcCtx *svc.ServiceContext
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewPurgeJob(svcCtx *svc.ServiceContext) *PurgeJob {
+	return &PurgeJob{svcCtx: svcCtx}
+}
+
+// Start runs a purge immediately and then every PurgeInterval. It does
+// nothing when the retention is zero
+func (j *PurgeJob) Start() {
+	conf := j.svcCtx.Config.SoftDelete
+	if conf.Retention <= 0 || conf.PurgeInterval <= 0 {
+		logx.Info("Purging of deleted users is disabled")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		ticker := time.NewTicker(conf.PurgeInterval)
+		defer ticker.Stop()
+		for {
+			j.purge(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels a running purge and waits for the job to exit
+func (j *PurgeJob) Stop() {
+	if j.cancel == nil {
+		return
+	}
+	j.cancel()
+	j.wg.Wait()
+}
+
+func (j *PurgeJob) purge(ctx context.Context) {
+	conf := j.svcCtx.Config.SoftDelete
+	before := time.Now().Add(-conf.Retention)
+	n, err := j.svcCtx.UserUsecase.PurgeDeletedUsers(ctx, before, conf.PurgeBatchSize)
+	if err != nil && ctx.Err() == nil {
+		logx.Errorf("Failed to purge deleted users: %v", err)
+	}
+	if n > 0 {
+		logx.Infof("Purged %d users deleted before %s", n, before.Format(time.RFC3339))
+	}
+}
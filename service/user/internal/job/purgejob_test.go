@@ -0,0 +1,106 @@
+package job
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/repository"
+	"github.com/Nha1410/go-zero-template/service/user/internal/svc"
+	"github.com/Nha1410/go-zero-template/service/user/internal/usecase"
+	"github.com/zeromicro/go-zero/core/logx/logtest"
+)
+
+type purgeCall struct {
+	before time.Time
+	limit  int64
+}
+
+// purgeUserRepo reports each Purge on calls. When block is set, Purge waits
+// for its context to end and returns its error
+type purgeUserRepo struct {
+	repository.UserRepository
+
+	block bool
+	calls chan purgeCall
+}
+
+func (r *purgeUserRepo) Purge(ctx context.Context, before time.Time, limit int64) (int64, error) {
+	r.calls <- purgeCall{before: before, limit: limit}
+	if r.block {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	return 0, nil
+}
+
+func newTestPurgeJob(repo *purgeUserRepo, retention, interval time.Duration) *PurgeJob {
+	svcCtx := &svc.ServiceContext{UserUsecase: usecase.NewUserUsecase(repo, nil, nil)}
+	svcCtx.Config.SoftDelete.Retention = retention
+	svcCtx.Config.SoftDelete.PurgeInterval = interval
+	svcCtx.Config.SoftDelete.PurgeBatchSize = 50
+	return NewPurgeJob(svcCtx)
+}
+
+func TestPurgeJobDisabled(t *testing.T) {
+	logtest.Discard(t)
+	repo := &purgeUserRepo{calls: make(chan purgeCall, 1)}
+	j := newTestPurgeJob(repo, 0, time.Millisecond)
+
+	j.Start()
+	time.Sleep(10 * time.Millisecond)
+	j.Stop()
+	if len(repo.calls) != 0 {
+		t.Fatal("purged with purging disabled")
+	}
+}
+
+func TestPurgeJobRetention(t *testing.T) {
+	logtest.Discard(t)
+	repo := &purgeUserRepo{calls: make(chan purgeCall, 10)}
+	j := newTestPurgeJob(repo, time.Hour, 5*time.Millisecond)
+
+	start := time.Now()
+	j.Start()
+	defer j.Stop()
+
+	// Purges right away and then on every tick, each time up to the retention
+	for i := 0; i < 2; i++ {
+		select {
+		case call := <-repo.calls:
+			if cutoff := start.Add(-time.Hour); call.before.Before(cutoff) || call.before.After(time.Now().Add(-time.Hour)) {
+				t.Fatalf("purged before %s, want an hour before the purge", call.before)
+			}
+			if call.limit != 50 {
+				t.Fatalf("purged in batches of %d, want 50", call.limit)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("purge %d did not run", i+1)
+		}
+	}
+}
+
+func TestPurgeJobStopCancelsPurge(t *testing.T) {
+	logs := logtest.NewCollector(t)
+	repo := &purgeUserRepo{block: true, calls: make(chan purgeCall, 1)}
+	j := newTestPurgeJob(repo, time.Hour, time.Hour)
+
+	j.Start()
+	<-repo.calls
+
+	stopped := make(chan struct{})
+	go func() {
+		j.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop() did not cancel the running purge")
+	}
+
+	if strings.Contains(logs.String(), "Failed to purge") {
+		t.Fatalf("cancellation logged as a failure: %s", logs.String())
+	}
+}
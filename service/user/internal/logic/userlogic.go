@@ -2,9 +2,14 @@ package logic
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/entity"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/repository"
 	"github.com/Nha1410/go-zero-template/service/user/internal/svc"
+	"github.com/Nha1410/go-zero-template/service/user/internal/usecase"
 	"github.com/Nha1410/go-zero-template/service/user/userclient"
 	"github.com/zeromicro/go-zero/core/logx"
 )
@@ -34,6 +39,7 @@ func (l *CreateUserLogic) CreateUser(req *userclient.CreateUserReq) (*userclient
 		Email:     user.Email,
 		Name:      user.Name,
 		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+		Version:   user.Version,
 	}, nil
 }
 
@@ -52,18 +58,12 @@ func NewGetUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetUserLo
 }
 
 func (l *GetUserLogic) GetUser(req *userclient.GetUserReq) (*userclient.GetUserResp, error) {
-	user, err := l.svcCtx.UserUsecase.GetUser(l.ctx, req.Id)
+	user, err := l.svcCtx.UserUsecase.GetUser(l.ctx, req.Id, req.IncludeDeleted)
 	if err != nil {
 		return nil, err
 	}
 
-	return &userclient.GetUserResp{
-		Id:        user.ID,
-		Email:     user.Email,
-		Name:      user.Name,
-		CreatedAt: user.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
-	}, nil
+	return toUserResp(user), nil
 }
 
 type GetUsersLogic struct {
@@ -81,30 +81,106 @@ func NewGetUsersLogic(ctx context.Context, svcCtx *svc.ServiceContext) *GetUsers
 }
 
 func (l *GetUsersLogic) GetUsers(req *userclient.GetUsersReq) (*userclient.GetUsersResp, error) {
-	users, total, err := l.svcCtx.UserUsecase.GetUsers(l.ctx, req.Page, req.PageSize)
+	filter := repository.ListFilter{
+		Email:          req.Email,
+		NameContains:   req.NameContains,
+		Query:          req.Q,
+		IncludeDeleted: req.IncludeDeleted,
+	}
+	var err error
+	if filter.CreatedAfter, err = parseTime(req.CreatedAfter); err != nil {
+		return nil, errors.ErrBadRequest.WithDetails("Invalid created_after")
+	}
+	if filter.CreatedBefore, err = parseTime(req.CreatedBefore); err != nil {
+		return nil, errors.ErrBadRequest.WithDetails("Invalid created_before")
+	}
+
+	page, err := l.svcCtx.UserUsecase.GetUsers(l.ctx, usecase.ListUsersParams{
+		Filter: filter,
+		Sort: repository.Sort{
+			Field: repository.SortField(req.Sort),
+			Desc:  req.Order == userclient.SortOrder_SORT_ORDER_DESC,
+		},
+		PageSize:  req.PageSize,
+		Cursor:    req.Cursor,
+		TotalMode: toTotalMode(req.TotalMode),
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	var respUsers []*userclient.GetUserResp
-	for _, user := range users {
-		respUsers = append(respUsers, &userclient.GetUserResp{
-			Id:        user.ID,
-			Email:     user.Email,
-			Name:      user.Name,
-			CreatedAt: user.CreatedAt.Format(time.RFC3339),
-			UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
-		})
+	for _, user := range page.Users {
+		respUsers = append(respUsers, toUserResp(user))
 	}
 
 	return &userclient.GetUsersResp{
-		Users:    respUsers,
-		Total:    total,
-		Page:     req.Page,
-		PageSize: req.PageSize,
+		Users:      respUsers,
+		Total:      page.Total,
+		TotalExact: page.TotalExact,
+		PageSize:   page.PageSize,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
 	}, nil
 }
 
+// parseTime parses an optional RFC 3339 timestamp and converts it to UTC
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
+func toTotalMode(mode userclient.TotalMode) usecase.TotalMode {
+	switch mode {
+	case userclient.TotalMode_TOTAL_MODE_EXACT:
+		return usecase.TotalExact
+	case userclient.TotalMode_TOTAL_MODE_NONE:
+		return usecase.TotalNone
+	default:
+		return usecase.TotalEstimated
+	}
+}
+
+type SearchUsersLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSearchUsersLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SearchUsersLogic {
+	return &SearchUsersLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *SearchUsersLogic) SearchUsers(req *userclient.SearchUsersReq) (*userclient.SearchUsersResp, error) {
+	hits, err := l.svcCtx.UserUsecase.SearchUsers(l.ctx, req.Query, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	respHits := make([]*userclient.UserSearchHit, 0, len(hits))
+	for _, hit := range hits {
+		respHits = append(respHits, &userclient.UserSearchHit{
+			User:           toUserResp(hit.User),
+			Score:          hit.Score,
+			NameHighlight:  hit.NameHighlight,
+			EmailHighlight: hit.EmailHighlight,
+		})
+	}
+
+	return &userclient.SearchUsersResp{Hits: respHits}, nil
+}
+
 type UpdateUserLogic struct {
 	logx.Logger
 	ctx    context.Context
@@ -119,18 +195,55 @@ func NewUpdateUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *Update
 	}
 }
 
-func (l *UpdateUserLogic) UpdateUser(req *userclient.UpdateUserReq) (*userclient.UpdateUserResp, error) {
-	user, err := l.svcCtx.UserUsecase.UpdateUser(l.ctx, req.Id, req.Email, req.Name)
+func (l *UpdateUserLogic) UpdateUser(req *userclient.UpdateUserReq) (*userclient.GetUserResp, error) {
+	user, err := l.svcCtx.UserUsecase.UpdateUser(l.ctx, req.Id, req.Email, req.Name, req.ExpectedVersion)
 	if err != nil {
 		return nil, err
 	}
 
-	return &userclient.UpdateUserResp{
-		Id:        user.ID,
-		Email:     user.Email,
-		Name:      user.Name,
-		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
-	}, nil
+	return toUserResp(user), nil
+}
+
+type PatchUserLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewPatchUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *PatchUserLogic {
+	return &PatchUserLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *PatchUserLogic) PatchUser(req *userclient.PatchUserReq) (*userclient.GetUserResp, error) {
+	if len(req.UpdateMask.GetPaths()) == 0 {
+		return nil, errors.ErrBadRequest.WithDetails("update_mask must name at least one field")
+	}
+
+	var patch usecase.UserPatch
+	fields := req.GetUser()
+	for _, path := range req.UpdateMask.GetPaths() {
+		switch path {
+		case "email":
+			email := fields.GetEmail()
+			patch.Email = &email
+		case "name":
+			name := fields.GetName()
+			patch.Name = &name
+		default:
+			return nil, errors.ErrBadRequest.WithDetails(fmt.Sprintf("Unknown update_mask path %q", path))
+		}
+	}
+
+	user, err := l.svcCtx.UserUsecase.PatchUser(l.ctx, req.Id, patch, req.ExpectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return toUserResp(user), nil
 }
 
 type DeleteUserLogic struct {
@@ -148,7 +261,7 @@ func NewDeleteUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *Delete
 }
 
 func (l *DeleteUserLogic) DeleteUser(req *userclient.DeleteUserReq) (*userclient.DeleteUserResp, error) {
-	err := l.svcCtx.UserUsecase.DeleteUser(l.ctx, req.Id)
+	err := l.svcCtx.UserUsecase.DeleteUser(l.ctx, req.Id, req.ExpectedVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -157,3 +270,41 @@ func (l *DeleteUserLogic) DeleteUser(req *userclient.DeleteUserReq) (*userclient
 		Success: true,
 	}, nil
 }
+
+type RestoreUserLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRestoreUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RestoreUserLogic {
+	return &RestoreUserLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *RestoreUserLogic) RestoreUser(req *userclient.RestoreUserReq) (*userclient.GetUserResp, error) {
+	user, err := l.svcCtx.UserUsecase.RestoreUser(l.ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toUserResp(user), nil
+}
+
+func toUserResp(user *entity.User) *userclient.GetUserResp {
+	resp := &userclient.GetUserResp{
+		Id:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
+		Version:   user.Version,
+	}
+	if user.DeletedAt != nil {
+		resp.DeletedAt = user.DeletedAt.Format(time.RFC3339)
+	}
+	return resp
+}
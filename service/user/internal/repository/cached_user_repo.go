@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Nha1410/go-zero-template/common/cache"
+	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/entity"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/repository"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/metric"
+	"github.com/zeromicro/go-zero/core/syncx"
+)
+
+// cacheRequests counts lookups by key kind ("id" or "email") and result
+// ("hit", "miss" or "error"). It is served on the dev server's /metrics
+var cacheRequests = metric.NewCounterVec(&metric.CounterVecOpts{
+	Namespace: "user",
+	Subsystem: "cache",
+	Name:      "requests_total",
+	Help:      "User cache lookups by key kind and result.",
+	Labels:    []string{"kind", "result"},
+})
+
+// fetchTimeout bounds a fetch shared by concurrent misses. It runs without
+// the caller's cancellation, since the other callers still wait for it, and
+// it is how long the lease of the fetch lasts
+const fetchTimeout = 5 * time.Second
+
+// cacheEntry is stored under both key kinds: the user under its ID, and the
+// ID under the email. An empty entry records that nothing matched
+type cacheEntry struct {
+	User *entity.User `json:"user,omitempty"`
+	ID   int64        `json:"id,omitempty"`
+}
+
+var _ repository.UserRepository = (*cachedUserRepo)(nil)
+
+// cachedUserRepo serves lookups of active users outside transactions from
+// Redis. Methods it does not override go straight to the wrapped repository
+type cachedUserRepo struct {
+	repository.UserRepository
+	tx          *database.TxManager
+	redis       *cache.RedisClient
+	ttl         time.Duration
+	negativeTTL time.Duration
+	flight      syncx.SingleFlight
+}
+
+// NewCachedUserRepo wraps next with a read-through cache. Users are cached
+// for ttl and missing users for negativeTTL; a zero TTL disables that kind of
+// entry. Writes invalidate the keys they affect once their transaction
+// commits. While Redis is unavailable every lookup goes to next
+func NewCachedUserRepo(next repository.UserRepository, tx *database.TxManager, redis *cache.RedisClient,
+	ttl, negativeTTL time.Duration) repository.UserRepository {
+	return &cachedUserRepo{
+		UserRepository: next,
+		tx:             tx,
+		redis:          redis,
+		ttl:            ttl,
+		negativeTTL:    negativeTTL,
+		flight:         syncx.NewSingleFlight(),
+	}
+}
+
+func userIDKey(id int64) string {
+	return fmt.Sprintf("user:id:%d", id)
+}
+
+func userEmailKey(email string) string {
+	return "user:email:" + email
+}
+
+// GetByID bypasses the cache for deleted users and inside transactions,
+// which must read the rows they are about to write
+func (r *cachedUserRepo) GetByID(ctx context.Context, id int64, includeDeleted bool) (*entity.User, error) {
+	if includeDeleted || r.tx.InTx(ctx) {
+		return r.UserRepository.GetByID(ctx, id, includeDeleted)
+	}
+
+	entry, err := r.load(ctx, "id", userIDKey(id), func(ctx context.Context) (cacheEntry, error) {
+		user, err := r.UserRepository.GetByID(ctx, id, false)
+		return cacheEntry{User: user}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entry.User == nil {
+		return nil, repository.ErrUserNotFound
+	}
+	// Callers may modify the user, and concurrent misses share the entry
+	user := *entry.User
+	return &user, nil
+}
+
+// GetByEmail caches the ID of the user with the email and reads the user
+// through GetByID, so an update is invalidated under a single key and the
+// user is cached under its lease. The ID is checked against the user, since
+// the email may have changed hands since
+func (r *cachedUserRepo) GetByEmail(ctx context.Context, email string, includeDeleted bool) (*entity.User, error) {
+	if includeDeleted || r.tx.InTx(ctx) {
+		return r.UserRepository.GetByEmail(ctx, email, includeDeleted)
+	}
+
+	entry, err := r.load(ctx, "email", userEmailKey(email), func(ctx context.Context) (cacheEntry, error) {
+		user, err := r.UserRepository.GetByEmail(ctx, email, false)
+		if err != nil {
+			return cacheEntry{}, err
+		}
+		return cacheEntry{ID: user.ID}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entry.ID == 0 {
+		return nil, repository.ErrUserNotFound
+	}
+
+	user, err := r.GetByID(ctx, entry.ID, false)
+	if errors.Is(err, repository.ErrUserNotFound) || err == nil && user.Email != email {
+		r.delete(ctx, userEmailKey(email))
+		return r.UserRepository.GetByEmail(ctx, email, false)
+	}
+	return user, err
+}
+
+// Create also clears cached misses for the new ID and email
+func (r *cachedUserRepo) Create(ctx context.Context, user *entity.User) error {
+	if err := r.UserRepository.Create(ctx, user); err != nil {
+		return err
+	}
+	r.invalidate(ctx, userIDKey(user.ID), userEmailKey(user.Email))
+	return nil
+}
+
+// Update leaves the entry of a previous email in place; GetByEmail notices
+// that it no longer matches
+func (r *cachedUserRepo) Update(ctx context.Context, user *entity.User) error {
+	if err := r.UserRepository.Update(ctx, user); err != nil {
+		return err
+	}
+	r.invalidate(ctx, userIDKey(user.ID), userEmailKey(user.Email))
+	return nil
+}
+
+func (r *cachedUserRepo) Delete(ctx context.Context, id, version int64, deletedAt time.Time) error {
+	if err := r.UserRepository.Delete(ctx, id, version, deletedAt); err != nil {
+		return err
+	}
+	r.invalidate(ctx, userIDKey(id))
+	return nil
+}
+
+// Restore reads the restored user back to clear a cached miss for its email
+func (r *cachedUserRepo) Restore(ctx context.Context, id int64, restoredAt time.Time) error {
+	if err := r.UserRepository.Restore(ctx, id, restoredAt); err != nil {
+		return err
+	}
+	keys := []string{userIDKey(id)}
+	if user, err := r.UserRepository.GetByID(ctx, id, false); err == nil {
+		keys = append(keys, userEmailKey(user.Email))
+	}
+	r.invalidate(ctx, keys...)
+	return nil
+}
+
+// load returns the entry under key. On a miss, fetch is called once for all
+// concurrent callers and its result is cached; ErrUserNotFound is cached as
+// an empty entry. The fetch takes a lease on key first and only fills it
+// while the lease holds, so a row read before a write commits is not cached
+// after the write invalidated key
+func (r *cachedUserRepo) load(ctx context.Context, kind, key string,
+	fetch func(ctx context.Context) (cacheEntry, error)) (cacheEntry, error) {
+	var entry cacheEntry
+	err := r.redis.GetJSON(key, &entry)
+	switch {
+	case err == nil:
+		cacheRequests.Inc(kind, "hit")
+		return entry, nil
+	case errors.Is(err, cache.ErrKeyNotFound):
+		cacheRequests.Inc(kind, "miss")
+	case !errors.Is(err, cache.ErrUnavailable):
+		cacheRequests.Inc(kind, "error")
+		logx.WithContext(ctx).Errorf("Failed to read %s from the user cache: %v", key, err)
+	}
+
+	v, err := r.flight.Do(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+
+		token := r.lease(ctx, key)
+		entry, err := fetch(ctx)
+		ttl := r.ttl
+		if errors.Is(err, repository.ErrUserNotFound) {
+			entry, err, ttl = cacheEntry{}, nil, r.negativeTTL
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.fill(ctx, key, token, entry, ttl)
+		return entry, nil
+	})
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	return v.(cacheEntry), nil
+}
+
+func leaseKey(key string) string {
+	return key + ":lease"
+}
+
+// lease takes the lease a fill of key must hold, replacing any other. It
+// returns "" when no lease could be taken, and then nothing is cached
+func (r *cachedUserRepo) lease(ctx context.Context, key string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		logx.WithContext(ctx).Errorf("Failed to generate a user cache lease: %v", err)
+		return ""
+	}
+	token := hex.EncodeToString(b)
+	if err := r.redis.Set(leaseKey(key), token, fetchTimeout); err != nil {
+		if !errors.Is(err, cache.ErrUnavailable) {
+			logx.WithContext(ctx).Errorf("Failed to lease %s in the user cache: %v", key, err)
+		}
+		return ""
+	}
+	return token
+}
+
+// fill caches entry under key if the lease token is still held
+func (r *cachedUserRepo) fill(ctx context.Context, key, token string, entry cacheEntry, ttl time.Duration) {
+	if ttl <= 0 || token == "" {
+		return
+	}
+	_, err := r.redis.SetJSONWithLease(key, leaseKey(key), token, entry, ttl)
+	if err != nil && !errors.Is(err, cache.ErrUnavailable) {
+		logx.WithContext(ctx).Errorf("Failed to write %s to the user cache: %v", key, err)
+	}
+}
+
+// invalidate deletes keys and revokes their leases once the transaction in
+// ctx commits, so a reader that fetched the old row cannot cache it again.
+// Entries that cannot be deleted while Redis is down expire after their TTL
+func (r *cachedUserRepo) invalidate(ctx context.Context, keys ...string) {
+	r.tx.AfterCommit(ctx, func() {
+		for _, key := range keys {
+			r.delete(ctx, key, leaseKey(key))
+		}
+	})
+}
+
+func (r *cachedUserRepo) delete(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := r.redis.Delete(key); err != nil && !errors.Is(err, cache.ErrUnavailable) {
+			logx.WithContext(ctx).Errorf("Failed to invalidate %s in the user cache: %v", key, err)
+		}
+	}
+}
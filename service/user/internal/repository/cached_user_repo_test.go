@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Nha1410/go-zero-template/common/cache"
+	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/entity"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/repository"
+	"github.com/alicebob/miniredis/v2"
+)
+
+// blockingUserRepo holds one user. When blocked, GetByID reports the row it
+// read on fetched, waits for release and records the error of its context
+type blockingUserRepo struct {
+	repository.UserRepository
+
+	mu      sync.Mutex
+	user    entity.User
+	fetched chan entity.User
+	release chan struct{}
+	ctxErr  error
+}
+
+func (f *blockingUserRepo) GetByID(ctx context.Context, id int64, includeDeleted bool) (*entity.User, error) {
+	f.mu.Lock()
+	user, fetched, release := f.user, f.fetched, f.release
+	f.fetched, f.release = nil, nil
+	f.mu.Unlock()
+	if user.ID != id {
+		return nil, repository.ErrUserNotFound
+	}
+
+	if fetched != nil {
+		fetched <- user
+		<-release
+		f.mu.Lock()
+		f.ctxErr = ctx.Err()
+		f.mu.Unlock()
+	}
+	return &user, nil
+}
+
+func (f *blockingUserRepo) Update(ctx context.Context, user *entity.User) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.user = *user
+	return nil
+}
+
+// block makes the next GetByID wait after reading the row
+func (f *blockingUserRepo) block() (fetched chan entity.User, release chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetched, f.release = make(chan entity.User, 1), make(chan struct{})
+	return f.fetched, f.release
+}
+
+// newTestCachedRepo caches a blockingUserRepo holding user. Without a
+// database the manager never starts transactions, so invalidations run right
+// after each write
+func newTestCachedRepo(t *testing.T, db *sql.DB, user entity.User) (*blockingUserRepo, repository.UserRepository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	redis, err := cache.NewRedisClient(cache.RedisConfig{Host: mr.Host(), Port: port})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = redis.Close() })
+
+	inner := &blockingUserRepo{user: user}
+	tx := database.NewTxManager(db, database.TxOptions{})
+	return inner, NewCachedUserRepo(inner, tx, redis, time.Minute, time.Minute), mr
+}
+
+func TestCachedUserRepoSkipsStaleFill(t *testing.T) {
+	inner, repo, mr := newTestCachedRepo(t, nil, entity.User{ID: 1, Email: "jane@example.com", Name: "Jane", Version: 1})
+	ctx := context.Background()
+
+	// A reader misses and reads version 1
+	fetched, release := inner.block()
+	done := make(chan *entity.User)
+	go func() {
+		user, err := repo.GetByID(ctx, 1, false)
+		if err != nil {
+			t.Errorf("GetByID() error = %v", err)
+		}
+		done <- user
+	}()
+	if got := <-fetched; got.Version != 1 {
+		t.Fatalf("reader fetched version %d, want 1", got.Version)
+	}
+
+	// A writer commits version 2 and invalidates before the reader fills
+	if err := repo.Update(ctx, &entity.User{ID: 1, Email: "jane@example.com", Name: "Jane Doe", Version: 2}); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if user := <-done; user == nil || user.Version != 1 {
+		t.Fatalf("reader got %+v, want the version 1 it read", user)
+	}
+
+	if mr.Exists(userIDKey(1)) {
+		got, _ := mr.Get(userIDKey(1))
+		t.Fatalf("stale entry cached after the invalidation: %s", got)
+	}
+	user, err := repo.GetByID(ctx, 1, false)
+	if err != nil || user.Version != 2 {
+		t.Fatalf("GetByID() = %+v, %v, want version 2", user, err)
+	}
+	if !mr.Exists(userIDKey(1)) {
+		t.Fatal("version 2 was not cached")
+	}
+}
+
+func TestCachedUserRepoFetchOutlivesCaller(t *testing.T) {
+	inner, repo, mr := newTestCachedRepo(t, nil, entity.User{ID: 1, Email: "jane@example.com", Name: "Jane", Version: 1})
+
+	fetched, release := inner.block()
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := repo.GetByID(ctx, 1, false)
+		first <- err
+	}()
+	<-fetched
+
+	// A second caller joins the fetch, then the caller that started it goes away
+	second := make(chan error)
+	go func() {
+		_, err := repo.GetByID(context.Background(), 1, false)
+		second <- err
+	}()
+	cancel()
+	close(release)
+
+	if err := <-first; err != nil {
+		t.Fatalf("first GetByID() error = %v", err)
+	}
+	if err := <-second; err != nil {
+		t.Fatalf("second GetByID() error = %v, want the shared result", err)
+	}
+	inner.mu.Lock()
+	ctxErr := inner.ctxErr
+	inner.mu.Unlock()
+	if ctxErr != nil {
+		t.Fatalf("fetch context error = %v, want it to outlive the caller", ctxErr)
+	}
+	if !mr.Exists(userIDKey(1)) {
+		t.Fatal("user was not cached")
+	}
+}
+
+func TestCachedUserRepoInvalidatesOnCommitOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, repo, mr := newTestCachedRepo(t, db, entity.User{ID: 1, Email: "jane@example.com", Name: "Jane", Version: 1})
+	tx := database.NewTxManager(db, database.TxOptions{})
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+
+	if _, err := repo.GetByID(ctx, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	update := func(ctx context.Context) error {
+		return repo.Update(ctx, &entity.User{ID: 1, Email: "jane@example.com", Name: "Jane Doe", Version: 2})
+	}
+
+	// A rolled-back transaction leaves the entry in place
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	err = tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := update(ctx); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("WithinTx() error = %v, want %v", err, errFailed)
+	}
+	if !mr.Exists(userIDKey(1)) {
+		t.Fatal("entry invalidated by a rolled-back transaction")
+	}
+
+	// So does a rolled-back savepoint in a committed transaction
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	err = tx.WithinTx(ctx, func(ctx context.Context) error {
+		err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			if err := update(ctx); err != nil {
+				return err
+			}
+			return errFailed
+		})
+		if !errors.Is(err, errFailed) {
+			t.Fatalf("savepoint error = %v, want %v", err, errFailed)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	if !mr.Exists(userIDKey(1)) {
+		t.Fatal("entry invalidated by a rolled-back savepoint")
+	}
+
+	// A committed write invalidates it
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	if err := tx.WithinTx(ctx, update); err != nil {
+		t.Fatalf("WithinTx() error = %v", err)
+	}
+	if mr.Exists(userIDKey(1)) {
+		t.Fatal("entry not invalidated after commit")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,9 +3,15 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/common/outbox"
 	"github.com/Nha1410/go-zero-template/service/user/internal/domain/entity"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/event"
 	"github.com/Nha1410/go-zero-template/service/user/internal/domain/repository"
 	"github.com/zeromicro/go-zero/core/logx"
 )
@@ -13,104 +19,150 @@ import (
 var _ repository.UserRepository = (*userRepo)(nil)
 
 type userRepo struct {
-	db *sql.DB
+	tx *database.TxManager
 }
 
-func NewUserRepo(db *sql.DB) repository.UserRepository {
+// NewUserRepo returns a repository whose queries join the transaction of
+// the ctx they are given, if any
+func NewUserRepo(tx *database.TxManager) repository.UserRepository {
 	return &userRepo{
-		db: db,
+		tx: tx,
 	}
 }
 
+// Writes record their event in the outbox in the same transaction, so an
+// event is published if and only if the change is committed. Constraint
+// violations are returned classified by database.Classify.
+//
+// The timestamp columns hold UTC without a time zone, and Postgres drops the
+// offset of a value bound to them, so times are always bound in UTC
 func (r *userRepo) Create(ctx context.Context, user *entity.User) error {
 	query := `
 		INSERT INTO users (email, name, created_at, updated_at)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id
+		RETURNING id, version
 	`
 
-	err := r.db.QueryRowContext(ctx, query, user.Email, user.Name, user.CreatedAt, user.UpdatedAt).Scan(&user.ID)
-	if err != nil {
-		logx.Errorf("Failed to create user: %v", err)
-		return err
-	}
-
-	return nil
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		tx := r.tx.Conn(ctx)
+		err := tx.QueryRowContext(ctx, query, user.Email, user.Name, user.CreatedAt.UTC(), user.UpdatedAt.UTC()).Scan(&user.ID, &user.Version)
+		if err != nil {
+			logx.Errorf("Failed to create user: %v", err)
+			return database.Classify(err)
+		}
+		return addEvent(ctx, tx, event.UserCreated, user)
+	})
 }
 
-func (r *userRepo) GetByID(ctx context.Context, id int64) (*entity.User, error) {
+func (r *userRepo) GetByID(ctx context.Context, id int64, includeDeleted bool) (*entity.User, error) {
 	query := `
-		SELECT id, email, name, created_at, updated_at
+		SELECT id, email, name, created_at, updated_at, deleted_at, version
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND ($2 OR deleted_at IS NULL)
 	`
 
 	user := &entity.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.tx.Conn(ctx).QueryRowContext(ctx, query, id, includeDeleted).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Name,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletedAt,
+		&user.Version,
 	)
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrUserNotFound
 	}
 	if err != nil {
 		logx.Errorf("Failed to get user by ID: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("failed to get user %d: %w", id, err)
 	}
 
 	return user, nil
 }
 
-func (r *userRepo) GetByEmail(ctx context.Context, email string) (*entity.User, error) {
+// GetByEmail prefers the active user when deleted ones share the address
+func (r *userRepo) GetByEmail(ctx context.Context, email string, includeDeleted bool) (*entity.User, error) {
 	query := `
-		SELECT id, email, name, created_at, updated_at
+		SELECT id, email, name, created_at, updated_at, deleted_at, version
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND ($2 OR deleted_at IS NULL)
+		ORDER BY deleted_at DESC NULLS FIRST
+		LIMIT 1
 	`
 
 	user := &entity.User{}
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
+	err := r.tx.Conn(ctx).QueryRowContext(ctx, query, email, includeDeleted).Scan(
 		&user.ID,
 		&user.Email,
 		&user.Name,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeletedAt,
+		&user.Version,
 	)
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrUserNotFound
 	}
 	if err != nil {
 		logx.Errorf("Failed to get user by email: %v", err)
-		return nil, err
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
 
 	return user, nil
 }
 
-func (r *userRepo) List(ctx context.Context, page, pageSize int64) ([]*entity.User, int64, error) {
-	var total int64
-	countQuery := `SELECT COUNT(*) FROM users`
-	err := r.db.QueryRowContext(ctx, countQuery).Scan(&total)
-	if err != nil {
-		logx.Errorf("Failed to count users: %v", err)
-		return nil, 0, err
+// sortColumns maps each allowed sort field to its column and the placeholder
+// its key is bound to. Only these strings are ever written into ORDER BY
+var sortColumns = map[repository.SortField]struct{ column, placeholder string }{
+	repository.SortByCreatedAt: {"created_at", "?::timestamp"},
+	repository.SortByUpdatedAt: {"updated_at", "?::timestamp"},
+	repository.SortByName:      {"name", "?"},
+	repository.SortByEmail:     {"email", "?"},
+	repository.SortByID:        {"id", "?"},
+}
+
+func (r *userRepo) List(ctx context.Context, q repository.ListQuery) ([]*entity.User, error) {
+	sort, ok := sortColumns[q.Sort.Field]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort field %q", q.Sort.Field)
 	}
 
-	offset := (page - 1) * pageSize
-	query := `
-		SELECT id, email, name, created_at, updated_at
-		FROM users
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+	b := database.Select("id", "email", "name", "created_at", "updated_at", "deleted_at", "version").From("users")
+	applyFilter(b, q.Filter)
 
-	rows, err := r.db.QueryContext(ctx, query, pageSize, offset)
+	// Walking backward reads the opposite direction from the key
+	desc := q.Sort.Desc
+	key := q.After
+	if q.Before != nil {
+		desc, key = !desc, q.Before
+	}
+	op, dir := ">", "ASC"
+	if desc {
+		op, dir = "<", "DESC"
+	}
+	// Row-value comparisons let Postgres seek on a (column, id) index
+	if key != nil {
+		value := key.Value
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC()
+		}
+		if sort.column == "id" {
+			b.Where("id "+op+" ?", key.ID)
+		} else {
+			b.Where("("+sort.column+", id) "+op+" ("+sort.placeholder+", ?)", value, key.ID)
+		}
+	}
+	if sort.column != "id" {
+		b.OrderBy(sort.column + " " + dir)
+	}
+	query, args := b.OrderBy("id " + dir).Limit(q.Limit).Build()
+
+	rows, err := r.tx.Conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		logx.Errorf("Failed to list users: %v", err)
-		return nil, 0, err
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -123,57 +175,236 @@ func (r *userRepo) List(ctx context.Context, page, pageSize int64) ([]*entity.Us
 			&user.Name,
 			&user.CreatedAt,
 			&user.UpdatedAt,
+			&user.DeletedAt,
+			&user.Version,
 		)
 		if err != nil {
 			logx.Errorf("Failed to scan user: %v", err)
-			continue
+			return nil, err
 		}
 		users = append(users, user)
 	}
+	if err := rows.Err(); err != nil {
+		logx.Errorf("Failed to iterate users: %v", err)
+		return nil, err
+	}
 
-	return users, total, nil
+	return users, nil
 }
 
-func (r *userRepo) Update(ctx context.Context, user *entity.User) error {
+func (r *userRepo) Search(ctx context.Context, text string, limit int64) ([]*repository.SearchResult, error) {
+	// Full-text matches use idx_users_search_vector; word_similarity (<%)
+	// tolerates typos and partial words through the trigram indexes
 	query := `
-		UPDATE users
-		SET email = $1, name = $2, updated_at = $3
-		WHERE id = $4
+		SELECT id, email, name, created_at, updated_at, version,
+			ts_rank(search_vector, websearch_to_tsquery('simple', $1))
+				+ GREATEST(word_similarity($1, name), word_similarity($1, email)) AS rank
+		FROM users
+		WHERE deleted_at IS NULL
+			AND (search_vector @@ websearch_to_tsquery('simple', $1)
+				OR $1 <% name
+				OR $1 <% email)
+		ORDER BY rank DESC, id DESC
+		LIMIT $2
 	`
 
-	result, err := r.db.ExecContext(ctx, query, user.Email, user.Name, user.UpdatedAt, user.ID)
+	rows, err := r.tx.Conn(ctx).QueryContext(ctx, query, text, limit)
 	if err != nil {
-		logx.Errorf("Failed to update user: %v", err)
-		return err
+		logx.Errorf("Failed to search users: %v", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
+	var results []*repository.SearchResult
+	for rows.Next() {
+		result := &repository.SearchResult{User: &entity.User{}}
+		err := rows.Scan(
+			&result.User.ID,
+			&result.User.Email,
+			&result.User.Name,
+			&result.User.CreatedAt,
+			&result.User.UpdatedAt,
+			&result.User.Version,
+			&result.Rank,
+		)
+		if err != nil {
+			logx.Errorf("Failed to scan user: %v", err)
+			return nil, err
+		}
+		results = append(results, result)
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("user not found")
+	if err := rows.Err(); err != nil {
+		logx.Errorf("Failed to iterate users: %v", err)
+		return nil, err
 	}
 
-	return nil
+	return results, nil
 }
 
-func (r *userRepo) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM users WHERE id = $1`
+func (r *userRepo) Count(ctx context.Context, filter repository.ListFilter) (int64, error) {
+	b := database.Select("COUNT(*)").From("users")
+	applyFilter(b, filter)
+	query, args := b.Build()
+
+	var total int64
+	if err := r.tx.Conn(ctx).QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+		logx.Errorf("Failed to count users: %v", err)
+		return 0, err
+	}
+	return total, nil
+}
+
+// applyFilter adds the conditions for f. Filter values are only bound as args
+func applyFilter(b *database.SelectBuilder, f repository.ListFilter) {
+	if !f.IncludeDeleted {
+		b.Where("deleted_at IS NULL")
+	}
+	if f.Email != "" {
+		b.Where("email = ?", f.Email)
+	}
+	if f.NameContains != "" {
+		b.Where("name ILIKE ?", "%"+database.EscapeLike(f.NameContains)+"%")
+	}
+	if f.CreatedAfter != nil {
+		b.Where("created_at > ?::timestamp", f.CreatedAfter.UTC())
+	}
+	if f.CreatedBefore != nil {
+		b.Where("created_at < ?::timestamp", f.CreatedBefore.UTC())
+	}
+	if f.Query != "" {
+		pattern := "%" + database.EscapeLike(f.Query) + "%"
+		b.Where("email ILIKE ? OR name ILIKE ?", pattern, pattern)
+	}
+}
 
-	result, err := r.db.ExecContext(ctx, query, id)
+func (r *userRepo) EstimateCount(ctx context.Context) (int64, error) {
+	query := `SELECT reltuples::bigint FROM pg_class WHERE oid = 'users'::regclass`
+
+	var estimate int64
+	if err := r.tx.Conn(ctx).QueryRowContext(ctx, query).Scan(&estimate); err != nil {
+		logx.Errorf("Failed to estimate user count: %v", err)
+		return 0, err
+	}
+
+	// reltuples is -1 until the table has been vacuumed or analyzed
+	if estimate < 0 {
+		return r.Count(ctx, repository.ListFilter{})
+	}
+	return estimate, nil
+}
+
+// Update and Delete also report a missing or deleted row as a version
+// conflict: callers read the user first, so it changed after that read
+func (r *userRepo) Update(ctx context.Context, user *entity.User) error {
+	query := `
+		UPDATE users
+		SET email = $1, name = $2, updated_at = $3, version = version + 1
+		WHERE id = $4 AND version = $5 AND deleted_at IS NULL
+		RETURNING version
+	`
+
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		tx := r.tx.Conn(ctx)
+		err := tx.QueryRowContext(ctx, query, user.Email, user.Name, user.UpdatedAt.UTC(), user.ID, user.Version).
+			Scan(&user.Version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrVersionConflict
+		}
+		if err != nil {
+			logx.Errorf("Failed to update user: %v", err)
+			return database.Classify(err)
+		}
+		return addEvent(ctx, tx, event.UserUpdated, user)
+	})
+}
+
+func (r *userRepo) Delete(ctx context.Context, id, version int64, deletedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET deleted_at = $1, updated_at = $1, version = version + 1
+		WHERE id = $2 AND version = $3 AND deleted_at IS NULL
+		RETURNING id, email, name, created_at, updated_at, deleted_at, version
+	`
+
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		tx := r.tx.Conn(ctx)
+		user, err := scanUser(tx.QueryRowContext(ctx, query, deletedAt.UTC(), id, version))
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrVersionConflict
+		}
+		if err != nil {
+			logx.Errorf("Failed to delete user: %v", err)
+			return database.Classify(err)
+		}
+		return addEvent(ctx, tx, event.UserDeleted, user)
+	})
+}
+
+func (r *userRepo) Restore(ctx context.Context, id int64, restoredAt time.Time) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, updated_at = $1, version = version + 1
+		WHERE id = $2 AND deleted_at IS NOT NULL
+		RETURNING id, email, name, created_at, updated_at, deleted_at, version
+	`
+
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		tx := r.tx.Conn(ctx)
+		user, err := scanUser(tx.QueryRowContext(ctx, query, restoredAt.UTC(), id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrUserNotFound
+		}
+		if err != nil {
+			logx.Errorf("Failed to restore user: %v", err)
+			return database.Classify(err)
+		}
+		return addEvent(ctx, tx, event.UserRestored, user)
+	})
+}
+
+func scanUser(row *sql.Row) (*entity.User, error) {
+	user := &entity.User{}
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Name,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeletedAt,
+		&user.Version,
+	)
 	if err != nil {
-		logx.Errorf("Failed to delete user: %v", err)
-		return err
+		return nil, err
 	}
+	return user, nil
+}
 
-	rowsAffected, err := result.RowsAffected()
+// addEvent records a user event in the outbox with tx
+func addEvent(ctx context.Context, tx database.DBTX, eventType string, user *entity.User) error {
+	e, err := outbox.NewEvent(eventType, event.AggregateUser, strconv.FormatInt(user.ID, 10), event.NewUserData(user))
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("user not found")
-	}
+	return outbox.Add(ctx, tx, e)
+}
 
-	return nil
+func (r *userRepo) Purge(ctx context.Context, before time.Time, limit int64) (int64, error) {
+	// SKIP LOCKED lets several replicas purge without waiting on each other
+	query := `
+		DELETE FROM users
+		WHERE id IN (
+			SELECT id FROM users
+			WHERE deleted_at < $1::timestamp
+			ORDER BY deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	result, err := r.tx.Conn(ctx).ExecContext(ctx, query, before.UTC(), limit)
+	if err != nil {
+		logx.Errorf("Failed to purge users: %v", err)
+		return 0, err
+	}
+	return result.RowsAffected()
 }
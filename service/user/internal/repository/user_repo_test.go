@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/repository"
+)
+
+// utcTime matches a time bound in UTC at the same instant as want
+type utcTime struct {
+	want time.Time
+}
+
+func (m utcTime) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && t.Location() == time.UTC && t.Equal(m.want)
+}
+
+func TestListBindsCreatedFilterInUTC(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := NewUserRepo(database.NewTxManager(db, database.TxOptions{}))
+
+	// 09:00 at +07:00 is 02:00 UTC, which the UTC column must be compared with
+	bangkok := time.FixedZone("ICT", 7*60*60)
+	after := time.Date(2024, 5, 1, 9, 0, 0, 0, bangkok)
+	before := time.Date(2024, 5, 2, 9, 0, 0, 0, bangkok)
+	key := time.Date(2024, 5, 1, 18, 0, 0, 0, bangkok)
+
+	mock.ExpectQuery(`created_at > \$1::timestamp\) AND \(created_at < \$2::timestamp\) AND \(\(created_at, id\) < \(\$3::timestamp, \$4\)\)`).
+		WithArgs(utcTime{after}, utcTime{before}, utcTime{key}, int64(7), int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "created_at", "updated_at", "deleted_at", "version"}))
+
+	_, err = repo.List(context.Background(), repository.ListQuery{
+		Filter: repository.ListFilter{CreatedAfter: &after, CreatedBefore: &before},
+		Sort:   repository.Sort{Field: repository.SortByCreatedAt, Desc: true},
+		Limit:  10,
+		After:  &repository.ListKey{Value: key, ID: 7},
+	})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPurgeBindsCutoffInUTC(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := NewUserRepo(database.NewTxManager(db, database.TxOptions{}))
+
+	bangkok := time.FixedZone("ICT", 7*60*60)
+	before := time.Date(2024, 5, 1, 9, 0, 0, 0, bangkok)
+
+	mock.ExpectExec(`DELETE FROM users\s+WHERE id IN \(\s+SELECT id FROM users\s+WHERE deleted_at < \$1::timestamp\s+ORDER BY deleted_at\s+LIMIT \$2\s+FOR UPDATE SKIP LOCKED`).
+		WithArgs(utcTime{before}, int64(50)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := repo.Purge(context.Background(), before, 50)
+	if err != nil || n != 3 {
+		t.Fatalf("Purge() = %d, %v, want 3", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,68 +1,155 @@
 package svc
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/Nha1410/go-zero-template/common/cache"
 	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/common/health"
+	"github.com/Nha1410/go-zero-template/common/lifecycle"
+	"github.com/Nha1410/go-zero-template/common/pagination"
 	"github.com/Nha1410/go-zero-template/common/queue"
 	"github.com/Nha1410/go-zero-template/service/user/internal/config"
 	domainRepo "github.com/Nha1410/go-zero-template/service/user/internal/domain/repository"
 	"github.com/Nha1410/go-zero-template/service/user/internal/repository"
 	"github.com/Nha1410/go-zero-template/service/user/internal/usecase"
-
+	"github.com/Nha1410/go-zero-template/service/user/migrations"
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
 type ServiceContext struct {
-	Config      config.Config
-	DB          *sql.DB
+	Config config.Config
+	DB     *sql.DB
+	// TxManager runs transactions on DB that repositories join through the context
+	TxManager *database.TxManager
+	// Redis and RabbitMQ return ErrUnavailable while disconnected or disabled
 	Redis       *cache.RedisClient
 	RabbitMQ    *queue.RabbitMQClient
 	UserRepo    domainRepo.UserRepository
 	UserUsecase *usecase.UserUsecase
+	Health      *health.Checker
+
+	connector *lifecycle.Connector
+	closers   lifecycle.Closers
 }
 
-func NewServiceContext(c config.Config) *ServiceContext {
-	db, err := database.NewPostgresConnection(c.Database.Postgres)
+// NewServiceContext connects to every enabled dependency. The database is
+// retried with backoff until c.Startup.Timeout; optional dependencies that are
+// down leave the service degraded and reconnect in the background. On failure
+// the connections already opened are closed
+func NewServiceContext(c config.Config) (*ServiceContext, error) {
+	ctx, cancel := c.Startup.Context()
+	defer cancel()
+
+	svcCtx := &ServiceContext{
+		Config:    c,
+		Redis:     &cache.RedisClient{},
+		RabbitMQ:  &queue.RabbitMQClient{},
+		Health:    health.NewChecker(c.HealthCheck.Timeout, c.HealthCheck.CacheTTL),
+		connector: lifecycle.NewConnector(c.Startup),
+	}
+
+	err := lifecycle.Retry(ctx, c.Startup, "PostgreSQL", func() (err error) {
+		svcCtx.DB, err = database.NewPostgresConnection(c.Database.Postgres)
+		return err
+	})
 	if err != nil {
-		logx.Errorf("Failed to connect to database: %v", err)
-		panic(err)
+		return nil, svcCtx.closeOnError(err)
 	}
+	svcCtx.closers.Add("PostgreSQL", func() error {
+		return database.ClosePostgresConnection(svcCtx.DB)
+	})
+	svcCtx.Health.Register("database", health.DBCheck(svcCtx.DB))
 
-	redisClient, err := cache.NewRedisClient(cache.RedisConfig{
-		Host:     c.AppRedis.Host,
-		Port:     c.AppRedis.Port,
-		Password: c.AppRedis.Password,
-		DB:       c.AppRedis.DB,
-		PoolSize: c.AppRedis.PoolSize,
+	if c.Migrations.AutoMigrate {
+		if err := migrate(svcCtx.DB); err != nil {
+			return nil, svcCtx.closeOnError(err)
+		}
+	}
+
+	enabled, err := svcCtx.connector.Connect(ctx, c.Dependencies.Redis, "Redis", func() error {
+		return svcCtx.Redis.Connect(cache.RedisConfig{
+			Host:     c.AppRedis.Host,
+			Port:     c.AppRedis.Port,
+			Password: c.AppRedis.Password,
+			DB:       c.AppRedis.DB,
+			PoolSize: c.AppRedis.PoolSize,
+		})
 	})
 	if err != nil {
-		logx.Errorf("Failed to connect to Redis: %v", err)
-		panic(err)
+		return nil, svcCtx.closeOnError(err)
+	}
+	if enabled {
+		svcCtx.closers.Add("Redis", svcCtx.Redis.Close)
+		lifecycle.RegisterCheck(svcCtx.Health, c.Dependencies.Redis, "redis", health.PingCheck(svcCtx.Redis))
 	}
 
-	rabbitmqClient, err := queue.NewRabbitMQClient(queue.RabbitMQConfig{
-		Host:     c.RabbitMQ.Host,
-		Port:     c.RabbitMQ.Port,
-		User:     c.RabbitMQ.User,
-		Password: c.RabbitMQ.Password,
-		VHost:    c.RabbitMQ.VHost,
+	enabled, err = svcCtx.connector.Connect(ctx, c.Dependencies.RabbitMQ, "RabbitMQ", func() error {
+		return svcCtx.RabbitMQ.Connect(queue.RabbitMQConfig{
+			Host:     c.RabbitMQ.Host,
+			Port:     c.RabbitMQ.Port,
+			User:     c.RabbitMQ.User,
+			Password: c.RabbitMQ.Password,
+			VHost:    c.RabbitMQ.VHost,
+		})
 	})
 	if err != nil {
-		logx.Errorf("Failed to connect to RabbitMQ: %v", err)
-		panic(err)
+		return nil, svcCtx.closeOnError(err)
+	}
+	if enabled {
+		svcCtx.closers.Add("RabbitMQ", svcCtx.RabbitMQ.Close)
+		lifecycle.RegisterCheck(svcCtx.Health, c.Dependencies.RabbitMQ, "rabbitmq", health.PingCheck(svcCtx.RabbitMQ))
 	}
 
-	userRepo := repository.NewUserRepo(db)
-	userUsecase := usecase.NewUserUsecase(userRepo)
+	if c.Pagination.CursorSecret == "" {
+		logx.Error("USER_CURSOR_SECRET is not set, list cursors will not work across replicas or restarts")
+	}
+	cursors, err := pagination.NewCursorCodec(c.Pagination.CursorSecret)
+	if err != nil {
+		return nil, svcCtx.closeOnError(err)
+	}
+
+	svcCtx.TxManager = database.NewTxManager(svcCtx.DB, c.Database.Tx)
+	svcCtx.UserRepo = repository.NewUserRepo(svcCtx.TxManager)
+	if c.Cache.Enabled {
+		if c.Dependencies.Redis == lifecycle.Disabled {
+			logx.Error("USER_CACHE_ENABLED is set but Redis is disabled, users will not be cached")
+		} else {
+			svcCtx.UserRepo = repository.NewCachedUserRepo(svcCtx.UserRepo, svcCtx.TxManager, svcCtx.Redis,
+				c.Cache.TTL, c.Cache.NegativeTTL)
+		}
+	}
+	svcCtx.UserUsecase = usecase.NewUserUsecase(svcCtx.UserRepo, svcCtx.TxManager, cursors)
 
-	return &ServiceContext{
-		Config:      c,
-		DB:          db,
-		Redis:       redisClient,
-		RabbitMQ:    rabbitmqClient,
-		UserRepo:    userRepo,
-		UserUsecase: userUsecase,
+	return svcCtx, nil
+}
+
+// migrate applies pending migrations. It is not bounded by the startup
+// timeout: each migration runs in a transaction and a long index build must
+// not be cancelled on every boot
+func migrate(db *sql.DB) error {
+	m, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
 	}
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		return err
+	}
+	logx.Infof("Database schema is up to date, applied %d migration(s)", len(applied))
+	return nil
+}
+
+// Close stops background reconnects, then releases all connections in the
+// reverse order they were opened. Call it only after the server has stopped
+// accepting requests
+func (s *ServiceContext) Close() error {
+	s.connector.Stop()
+	return s.closers.Close()
+}
+
+func (s *ServiceContext) closeOnError(err error) error {
+	_ = s.Close()
+	return err
 }
@@ -2,29 +2,104 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Nha1410/go-zero-template/common/database"
 	common_errors "github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/common/pagination"
+	"github.com/Nha1410/go-zero-template/common/validator"
 	"github.com/Nha1410/go-zero-template/service/user/internal/domain/entity"
 	"github.com/Nha1410/go-zero-template/service/user/internal/domain/repository"
 )
 
+const (
+	defaultPageSize    = 10
+	maxPageSize        = 100
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	defaultPurgeBatch  = 500
+)
+
+// Rules every stored user must satisfy, checked after a patch is applied
+func init() {
+	validator.RegisterRules(map[string]string{
+		"Email": "required,email,max=255",
+		"Name":  "required,max=255",
+	}, entity.User{})
+}
+
+// TotalMode selects how GetUsers computes the total
+type TotalMode int
+
+const (
+	TotalEstimated TotalMode = iota
+	TotalExact
+	TotalNone
+)
+
+// ListUsersParams selects a page of users
+type ListUsersParams struct {
+	Filter repository.ListFilter
+	// Sort.Field defaults to created_at
+	Sort     repository.Sort
+	PageSize int64
+	// Cursor is a NextCursor or PrevCursor from a previous page, or empty for the first page
+	Cursor    string
+	TotalMode TotalMode
+}
+
+// UserPage is one page of users in the requested order
+type UserPage struct {
+	Users      []*entity.User
+	PageSize   int64
+	NextCursor string
+	PrevCursor string
+	// Total is nil when TotalNone was requested
+	Total      *int64
+	TotalExact bool
+}
+
+// UserPatch names the fields PatchUser changes. Nil fields are left as they are
+type UserPatch struct {
+	Email *string
+	Name  *string
+}
+
+// UserSearchHit is a search match. The highlights are HTML-escaped with the
+// matched query terms wrapped in <mark>
+type UserSearchHit struct {
+	User           *entity.User
+	Score          float64
+	NameHighlight  string
+	EmailHighlight string
+}
+
+// UserUsecase runs each write in one transaction, so the checks it makes
+// before writing still hold when the write commits
 type UserUsecase struct {
 	userRepo repository.UserRepository
+	tx       repository.Transactor
+	cursors  *pagination.CursorCodec
 }
 
-func NewUserUsecase(userRepo repository.UserRepository) *UserUsecase {
+func NewUserUsecase(userRepo repository.UserRepository, tx repository.Transactor, cursors *pagination.CursorCodec) *UserUsecase {
 	return &UserUsecase{
 		userRepo: userRepo,
+		tx:       tx,
+		cursors:  cursors,
 	}
 }
 
+// CreateUser relies on the unique email index, so concurrent creates with
+// the same email cannot both succeed
 func (uc *UserUsecase) CreateUser(ctx context.Context, email, name string) (*entity.User, error) {
-	existing, _ := uc.userRepo.GetByEmail(ctx, email)
-	if existing != nil {
-		return nil, common_errors.ErrConflict.WithDetails("User with this email already exists")
-	}
-
 	user := &entity.User{
 		Email:     email,
 		Name:      name,
@@ -33,73 +108,394 @@ func (uc *UserUsecase) CreateUser(ctx context.Context, email, name string) (*ent
 	}
 
 	if err := uc.userRepo.Create(ctx, user); err != nil {
-		return nil, common_errors.ErrInternalError.WithDetails(err.Error())
+		return nil, appError(err)
 	}
 
 	return user, nil
 }
 
-func (uc *UserUsecase) GetUser(ctx context.Context, id int64) (*entity.User, error) {
-	user, err := uc.userRepo.GetByID(ctx, id)
+// GetUser returns an active user, or a soft-deleted one too when includeDeleted is set
+func (uc *UserUsecase) GetUser(ctx context.Context, id int64, includeDeleted bool) (*entity.User, error) {
+	user, err := uc.userRepo.GetByID(ctx, id, includeDeleted)
 	if err != nil {
-		return nil, common_errors.ErrNotFound.WithDetails("User not found")
+		return nil, appError(lookupError(err))
 	}
 	return user, nil
 }
 
-func (uc *UserUsecase) GetUsers(ctx context.Context, page, pageSize int64) ([]*entity.User, int64, error) {
-	if page < 1 {
-		page = 1
-	}
+func (uc *UserUsecase) GetUsers(ctx context.Context, params ListUsersParams) (*UserPage, error) {
+	pageSize := params.PageSize
 	if pageSize < 1 {
-		pageSize = 10
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	sort := params.Sort
+	if sort.Field == "" {
+		sort.Field = repository.SortByCreatedAt
+	}
+	if !sort.Field.Valid() {
+		return nil, common_errors.ErrBadRequest.WithDetails("Unsupported sort field")
 	}
-	if pageSize > 100 {
-		pageSize = 100
+	scope := listScope(params.Filter, sort)
+
+	// Fetch one extra row to learn whether another page follows
+	query := repository.ListQuery{
+		Filter: params.Filter,
+		Sort:   sort,
+		Limit:  pageSize + 1,
+	}
+	var cursor pagination.Cursor
+	if params.Cursor != "" {
+		var err error
+		cursor, err = uc.cursors.Decode(params.Cursor)
+		if err != nil {
+			return nil, common_errors.ErrBadRequest.WithDetails("Invalid cursor")
+		}
+		if cursor.Scope != scope {
+			return nil, common_errors.ErrBadRequest.WithDetails("Cursor does not match the filters or sort")
+		}
+		value, err := decodeSortKey(sort.Field, cursor.Key)
+		if err != nil {
+			return nil, common_errors.ErrBadRequest.WithDetails("Invalid cursor")
+		}
+		key := &repository.ListKey{Value: value, ID: cursor.ID}
+		if cursor.Backward {
+			query.Before = key
+		} else {
+			query.After = key
+		}
 	}
 
-	users, total, err := uc.userRepo.List(ctx, page, pageSize)
+	users, err := uc.userRepo.List(ctx, query)
 	if err != nil {
-		return nil, 0, common_errors.ErrInternalError.WithDetails(err.Error())
+		return nil, appError(err)
 	}
 
-	return users, total, nil
+	hasMore := int64(len(users)) > pageSize
+	if hasMore {
+		users = users[:pageSize]
+	}
+	if cursor.Backward {
+		slices.Reverse(users)
+	}
+
+	page := &UserPage{
+		Users:    users,
+		PageSize: pageSize,
+	}
+
+	// Walking forward there are earlier rows before any cursor; walking
+	// backward there are later rows after it
+	hasNext, hasPrev := hasMore, params.Cursor != ""
+	if cursor.Backward {
+		hasNext, hasPrev = true, hasMore
+	}
+	if len(users) > 0 {
+		if hasNext {
+			page.NextCursor = uc.encodeCursor(sort.Field, scope, users[len(users)-1], false)
+		}
+		if hasPrev {
+			page.PrevCursor = uc.encodeCursor(sort.Field, scope, users[0], true)
+		}
+	}
+
+	switch {
+	case params.TotalMode == TotalNone:
+	// Planner statistics only cover the whole table, so filtered totals are
+	// always counted
+	case params.TotalMode == TotalExact || !params.Filter.IsZero():
+		total, err := uc.userRepo.Count(ctx, params.Filter)
+		if err != nil {
+			return nil, appError(err)
+		}
+		page.Total, page.TotalExact = &total, true
+	default:
+		total, err := uc.userRepo.EstimateCount(ctx)
+		if err != nil {
+			return nil, appError(err)
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+func (uc *UserUsecase) encodeCursor(field repository.SortField, scope string, user *entity.User, backward bool) string {
+	return uc.cursors.Encode(pagination.Cursor{
+		Key:      encodeSortKey(field.Key(user)),
+		ID:       user.ID,
+		Backward: backward,
+		Scope:    scope,
+	})
+}
+
+// listScope fingerprints the filter and sort a cursor belongs to
+func listScope(f repository.ListFilter, sort repository.Sort) string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		string(sort.Field), strconv.FormatBool(sort.Desc),
+		f.Email, f.NameContains, formatTime(f.CreatedAfter), formatTime(f.CreatedBefore), f.Query,
+		strconv.FormatBool(f.IncludeDeleted),
+	}, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func encodeSortKey(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return v.(string)
+	}
+}
+
+func decodeSortKey(field repository.SortField, key string) (interface{}, error) {
+	switch field {
+	case repository.SortByCreatedAt, repository.SortByUpdatedAt:
+		return time.Parse(time.RFC3339Nano, key)
+	case repository.SortByID:
+		return strconv.ParseInt(key, 10, 64)
+	default:
+		return key, nil
+	}
 }
 
-func (uc *UserUsecase) UpdateUser(ctx context.Context, id int64, email, name string) (*entity.User, error) {
-	user, err := uc.userRepo.GetByID(ctx, id)
+func (uc *UserUsecase) SearchUsers(ctx context.Context, query string, limit int64) ([]*UserSearchHit, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, common_errors.ErrBadRequest.WithDetails("Search query is required")
+	}
+	if limit < 1 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	results, err := uc.userRepo.Search(ctx, query, limit)
 	if err != nil {
-		return nil, common_errors.ErrNotFound.WithDetails("User not found")
+		return nil, appError(err)
 	}
 
-	if email != "" {
-		existing, _ := uc.userRepo.GetByEmail(ctx, email)
-		if existing != nil && existing.ID != id {
-			return nil, common_errors.ErrConflict.WithDetails("Email already taken")
+	// Drop web search syntax so only the words themselves are marked
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		if term := strings.Trim(field, `"-`); term != "" && !strings.EqualFold(term, "or") {
+			terms = append(terms, term)
+		}
+	}
+
+	hits := make([]*UserSearchHit, 0, len(results))
+	for _, r := range results {
+		hits = append(hits, &UserSearchHit{
+			User:           r.User,
+			Score:          r.Rank,
+			NameHighlight:  highlight(r.User.Name, terms),
+			EmailHighlight: highlight(r.User.Email, terms),
+		})
+	}
+	return hits, nil
+}
+
+// highlight HTML-escapes text and wraps case-insensitive occurrences of terms
+// in <mark>. Fuzzy matches without a literal occurrence are left unmarked
+func highlight(text string, terms []string) string {
+	marked := make([]bool, len(text))
+	for i := range text {
+		for _, term := range terms {
+			if end := i + len(term); end <= len(text) && strings.EqualFold(text[i:end], term) {
+				for j := i; j < end; j++ {
+					marked[j] = true
+				}
+			}
 		}
-		user.Email = email
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(text[i:j]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(text[i:j]))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// UpdateUser changes the non-empty fields. When expectedVersion is not zero
+// the user must still be at that version
+func (uc *UserUsecase) UpdateUser(ctx context.Context, id int64, email, name string, expectedVersion int64) (*entity.User, error) {
+	var patch UserPatch
+	if email != "" {
+		patch.Email = &email
 	}
 	if name != "" {
-		user.Name = name
+		patch.Name = &name
 	}
-	user.UpdatedAt = time.Now()
+	return uc.PatchUser(ctx, id, patch, expectedVersion)
+}
 
-	if err := uc.userRepo.Update(ctx, user); err != nil {
-		return nil, common_errors.ErrInternalError.WithDetails(err.Error())
+// PatchUser sets the fields present in patch, including to empty values, and
+// validates the resulting user as a whole. When expectedVersion is not zero
+// the user must still be at that version
+func (uc *UserUsecase) PatchUser(ctx context.Context, id int64, patch UserPatch, expectedVersion int64) (*entity.User, error) {
+	var user *entity.User
+	err := uc.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = uc.userRepo.GetByID(ctx, id, false)
+		if err != nil {
+			return lookupError(err)
+		}
+		if err := checkVersion(user, expectedVersion); err != nil {
+			return err
+		}
+
+		if patch.Email != nil {
+			user.Email = *patch.Email
+		}
+		if patch.Name != nil {
+			user.Name = *patch.Name
+		}
+		if err := validator.Validate(user); err != nil {
+			return common_errors.FromValidationError(err)
+		}
+		user.UpdatedAt = time.Now()
+		return uc.userRepo.Update(ctx, user)
+	})
+	if err != nil {
+		return nil, writeError(err, expectedVersion)
 	}
 
 	return user, nil
 }
 
-func (uc *UserUsecase) DeleteUser(ctx context.Context, id int64) error {
-	_, err := uc.userRepo.GetByID(ctx, id)
+// DeleteUser soft-deletes a user. It can be restored until the purge job
+// removes it after the retention period. When expectedVersion is not zero
+// the user must still be at that version
+func (uc *UserUsecase) DeleteUser(ctx context.Context, id, expectedVersion int64) error {
+	err := uc.tx.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := uc.userRepo.GetByID(ctx, id, false)
+		if err != nil {
+			return lookupError(err)
+		}
+		if err := checkVersion(user, expectedVersion); err != nil {
+			return err
+		}
+		return uc.userRepo.Delete(ctx, id, user.Version, time.Now())
+	})
 	if err != nil {
+		return writeError(err, expectedVersion)
+	}
+
+	return nil
+}
+
+// checkVersion fails with ErrPreconditionFailed when the client expected
+// another version of the user
+func checkVersion(user *entity.User, expectedVersion int64) error {
+	if expectedVersion != 0 && user.Version != expectedVersion {
+		return common_errors.ErrPreconditionFailed.WithDetails("User has been modified")
+	}
+	return nil
+}
+
+// writeError maps a failed versioned write. A user changed between our read
+// and write is a conflict, or a failed precondition if the client named the
+// version it expected
+func writeError(err error, expectedVersion int64) error {
+	if !errors.Is(err, repository.ErrVersionConflict) {
+		return appError(err)
+	}
+	if expectedVersion != 0 {
+		return common_errors.ErrPreconditionFailed.WithDetails("User has been modified")
+	}
+	return common_errors.ErrConflict.WithDetails("User was modified concurrently, retry the request")
+}
+
+// RestoreUser undoes a soft delete, unless the email has since been taken by
+// another active user
+func (uc *UserUsecase) RestoreUser(ctx context.Context, id int64) (*entity.User, error) {
+	var user *entity.User
+	err := uc.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = uc.userRepo.GetByID(ctx, id, true)
+		if err != nil {
+			return lookupError(err)
+		}
+		if user.DeletedAt == nil {
+			return common_errors.ErrConflict.WithDetails("User is not deleted")
+		}
+
+		// Fails with a unique violation if the email has been taken since
+		now := time.Now()
+		if err := uc.userRepo.Restore(ctx, id, now); err != nil {
+			return lookupError(err)
+		}
+		user.DeletedAt, user.UpdatedAt = nil, now
+		user.Version++
+		return nil
+	})
+	if err != nil {
+		return nil, appError(err)
+	}
+
+	return user, nil
+}
+
+// lookupError reports a missing user as not found and returns any other
+// error, an infrastructure failure, unchanged so transactions can retry it
+func lookupError(err error) error {
+	if errors.Is(err, repository.ErrUserNotFound) {
 		return common_errors.ErrNotFound.WithDetails("User not found")
 	}
+	return err
+}
 
-	if err := uc.userRepo.Delete(ctx, id); err != nil {
-		return common_errors.ErrInternalError.WithDetails(err.Error())
+// appError passes through errors the usecase raised inside a transaction,
+// maps constraint violations and retryable failures that were not resolved
+// by retrying, and reports anything else as an internal error. Its cause is
+// kept for the server logs and not sent to clients
+func appError(err error) error {
+	var appErr *common_errors.Error
+	if errors.As(err, &appErr) {
+		return appErr
 	}
+	if appErr := database.AppError(err, "User"); appErr != nil {
+		return appErr
+	}
+	return common_errors.ErrInternalError.WithCause(err)
+}
 
-	return nil
+// PurgeDeletedUsers hard-deletes users soft-deleted before the cutoff in
+// batches of batchSize and returns how many were removed
+func (uc *UserUsecase) PurgeDeletedUsers(ctx context.Context, before time.Time, batchSize int64) (int64, error) {
+	if batchSize < 1 {
+		batchSize = defaultPurgeBatch
+	}
+	var total int64
+	for {
+		n, err := uc.userRepo.Purge(ctx, before, batchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < batchSize || ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+	}
 }
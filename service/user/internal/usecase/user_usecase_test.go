@@ -0,0 +1,275 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	common_errors "github.com/Nha1410/go-zero-template/common/errors"
+	"github.com/Nha1410/go-zero-template/common/pagination"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/entity"
+	"github.com/Nha1410/go-zero-template/service/user/internal/domain/repository"
+)
+
+// listUserRepo lists users by ID and records which total it was asked for
+type listUserRepo struct {
+	repository.UserRepository
+
+	users     []*entity.User
+	counted   bool
+	estimated bool
+}
+
+func newListUserRepo(n int64) *listUserRepo {
+	r := &listUserRepo{}
+	for id := int64(1); id <= n; id++ {
+		r.users = append(r.users, &entity.User{ID: id})
+	}
+	return r
+}
+
+func (r *listUserRepo) List(ctx context.Context, query repository.ListQuery) ([]*entity.User, error) {
+	var out []*entity.User
+	if query.Before != nil {
+		for i := len(r.users) - 1; i >= 0 && int64(len(out)) < query.Limit; i-- {
+			if r.users[i].ID < query.Before.ID {
+				out = append(out, r.users[i])
+			}
+		}
+		return out, nil
+	}
+	for _, u := range r.users {
+		if int64(len(out)) == query.Limit {
+			break
+		}
+		if query.After == nil || u.ID > query.After.ID {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func (r *listUserRepo) Count(ctx context.Context, filter repository.ListFilter) (int64, error) {
+	r.counted = true
+	return int64(len(r.users)), nil
+}
+
+func (r *listUserRepo) EstimateCount(ctx context.Context) (int64, error) {
+	r.estimated = true
+	return 100, nil
+}
+
+func newTestUsecase(t *testing.T, repo repository.UserRepository) *UserUsecase {
+	t.Helper()
+	cursors, err := pagination.NewCursorCodec("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewUserUsecase(repo, nil, cursors)
+}
+
+func pageIDs(page *UserPage) []int64 {
+	var ids []int64
+	for _, u := range page.Users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func TestGetUsersPages(t *testing.T) {
+	uc := newTestUsecase(t, newListUserRepo(5))
+	ctx := context.Background()
+	byID := repository.Sort{Field: repository.SortByID}
+
+	get := func(cursor string, wantIDs []int64, wantPrev, wantNext bool) *UserPage {
+		t.Helper()
+		page, err := uc.GetUsers(ctx, ListUsersParams{Sort: byID, PageSize: 2, Cursor: cursor, TotalMode: TotalNone})
+		if err != nil {
+			t.Fatalf("GetUsers() error = %v", err)
+		}
+		if ids := pageIDs(page); !slices.Equal(ids, wantIDs) {
+			t.Fatalf("page = %v, want %v", ids, wantIDs)
+		}
+		if (page.PrevCursor != "") != wantPrev || (page.NextCursor != "") != wantNext {
+			t.Fatalf("page %v has prev %t, next %t, want %t, %t", wantIDs,
+				page.PrevCursor != "", page.NextCursor != "", wantPrev, wantNext)
+		}
+		return page
+	}
+
+	first := get("", []int64{1, 2}, false, true)
+	second := get(first.NextCursor, []int64{3, 4}, true, true)
+	last := get(second.NextCursor, []int64{5}, true, false)
+
+	// Walking back returns the same pages, and none before the first
+	back := get(last.PrevCursor, []int64{3, 4}, true, true)
+	get(back.PrevCursor, []int64{1, 2}, false, true)
+}
+
+func TestGetUsersFullLastPage(t *testing.T) {
+	uc := newTestUsecase(t, newListUserRepo(4))
+	ctx := context.Background()
+	params := ListUsersParams{Sort: repository.Sort{Field: repository.SortByID}, PageSize: 2, TotalMode: TotalNone}
+
+	first, err := uc.GetUsers(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Cursor = first.NextCursor
+	last, err := uc.GetUsers(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := pageIDs(last); !slices.Equal(ids, []int64{3, 4}) || last.NextCursor != "" {
+		t.Fatalf("last page = %v with next cursor %q, want [3 4] without one", ids, last.NextCursor)
+	}
+}
+
+func TestGetUsersRejectsCursor(t *testing.T) {
+	uc := newTestUsecase(t, newListUserRepo(5))
+	ctx := context.Background()
+	byID := repository.Sort{Field: repository.SortByID}
+
+	first, err := uc.GetUsers(ctx, ListUsersParams{Sort: byID, PageSize: 2, TotalMode: TotalNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		params ListUsersParams
+	}{
+		{name: "tampered", params: ListUsersParams{Sort: byID, Cursor: first.NextCursor + "x"}},
+		{name: "other sort", params: ListUsersParams{Sort: repository.Sort{Field: repository.SortByID, Desc: true}, Cursor: first.NextCursor}},
+		{name: "other filter", params: ListUsersParams{Sort: byID, Filter: repository.ListFilter{Query: "jane"}, Cursor: first.NextCursor}},
+		{name: "unsupported sort", params: ListUsersParams{Sort: repository.Sort{Field: "password"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := uc.GetUsers(ctx, tt.params)
+			if appErr, ok := err.(*common_errors.Error); !ok || appErr.Code != common_errors.ErrBadRequest.Code {
+				t.Fatalf("GetUsers() error = %v, want %s", err, common_errors.ErrBadRequest.Code)
+			}
+		})
+	}
+}
+
+func TestGetUsersTotal(t *testing.T) {
+	after := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		params        ListUsersParams
+		wantTotal     int64
+		wantExact     bool
+		wantCounted   bool
+		wantEstimated bool
+	}{
+		{name: "estimated", params: ListUsersParams{}, wantTotal: 100, wantEstimated: true},
+		{name: "exact", params: ListUsersParams{TotalMode: TotalExact}, wantTotal: 3, wantExact: true, wantCounted: true},
+		{
+			name:      "filtered is always exact",
+			params:    ListUsersParams{Filter: repository.ListFilter{CreatedAfter: &after}},
+			wantTotal: 3, wantExact: true, wantCounted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newListUserRepo(3)
+			page, err := newTestUsecase(t, repo).GetUsers(context.Background(), tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.Total == nil || *page.Total != tt.wantTotal || page.TotalExact != tt.wantExact {
+				t.Fatalf("total = %v, exact %t, want %d, exact %t", page.Total, page.TotalExact, tt.wantTotal, tt.wantExact)
+			}
+			if repo.counted != tt.wantCounted || repo.estimated != tt.wantEstimated {
+				t.Fatalf("counted %t, estimated %t, want %t, %t", repo.counted, repo.estimated, tt.wantCounted, tt.wantEstimated)
+			}
+		})
+	}
+
+	repo := newListUserRepo(3)
+	page, err := newTestUsecase(t, repo).GetUsers(context.Background(), ListUsersParams{TotalMode: TotalNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != nil || repo.counted || repo.estimated {
+		t.Fatalf("total = %v, counted %t, estimated %t, want no total", page.Total, repo.counted, repo.estimated)
+	}
+}
+
+// purgeUserRepo holds deleted users to purge and records each batch
+type purgeUserRepo struct {
+	repository.UserRepository
+
+	deleted int64
+	err     error
+	// cancel, when set, is called after the first batch
+	cancel  context.CancelFunc
+	batches []int64
+	before  time.Time
+}
+
+func (r *purgeUserRepo) Purge(ctx context.Context, before time.Time, limit int64) (int64, error) {
+	r.batches = append(r.batches, limit)
+	r.before = before
+	if r.cancel != nil {
+		r.cancel()
+	}
+	n := min(limit, r.deleted)
+	r.deleted -= n
+	if r.deleted == 0 && r.err != nil {
+		return n, r.err
+	}
+	return n, nil
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	cutoff := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name        string
+		deleted     int64
+		batchSize   int64
+		err         error
+		wantTotal   int64
+		wantBatches int
+	}{
+		{name: "batches until a short one", deleted: 5, batchSize: 2, wantTotal: 5, wantBatches: 3},
+		{name: "ends with an empty batch", deleted: 4, batchSize: 2, wantTotal: 4, wantBatches: 3},
+		{name: "nothing to purge", deleted: 0, batchSize: 2, wantTotal: 0, wantBatches: 1},
+		{name: "default batch size", deleted: defaultPurgeBatch + 1, wantTotal: defaultPurgeBatch + 1, wantBatches: 2},
+		{name: "failure keeps the count so far", deleted: 3, batchSize: 2, err: errFailed, wantTotal: 3, wantBatches: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &purgeUserRepo{deleted: tt.deleted, err: tt.err}
+			total, err := newTestUsecase(t, repo).PurgeDeletedUsers(context.Background(), cutoff, tt.batchSize)
+			if !errors.Is(err, tt.err) || total != tt.wantTotal {
+				t.Fatalf("PurgeDeletedUsers() = %d, %v, want %d, %v", total, err, tt.wantTotal, tt.err)
+			}
+			if len(repo.batches) != tt.wantBatches || !repo.before.Equal(cutoff) {
+				t.Fatalf("purged %v before %s, want %d batches before %s", repo.batches, repo.before, tt.wantBatches, cutoff)
+			}
+		})
+	}
+}
+
+func TestPurgeDeletedUsersStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := &purgeUserRepo{deleted: 10, cancel: cancel}
+
+	total, err := newTestUsecase(t, repo).PurgeDeletedUsers(ctx, time.Now(), 2)
+	if !errors.Is(err, context.Canceled) || total != 2 {
+		t.Fatalf("PurgeDeletedUsers() = %d, %v, want 2, %v", total, err, context.Canceled)
+	}
+	if len(repo.batches) != 1 {
+		t.Fatalf("purged %d batches, want 1 before stopping", len(repo.batches))
+	}
+}
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
 
 	envConfig "github.com/Nha1410/go-zero-template/common/config"
+	"github.com/Nha1410/go-zero-template/common/database"
+	"github.com/Nha1410/go-zero-template/common/health"
+	"github.com/Nha1410/go-zero-template/common/interceptor"
 	"github.com/Nha1410/go-zero-template/service/user/internal/config"
+	"github.com/Nha1410/go-zero-template/service/user/internal/handler"
+	"github.com/Nha1410/go-zero-template/service/user/internal/job"
 	"github.com/Nha1410/go-zero-template/service/user/internal/svc"
+	"github.com/Nha1410/go-zero-template/service/user/migrations"
+	"github.com/Nha1410/go-zero-template/service/user/userclient"
 
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/proc"
 	"github.com/zeromicro/go-zero/zrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -16,17 +28,58 @@ func main() {
 	_ = envConfig.LoadEnv()
 	c := config.LoadFromEnv()
 
-	svcCtx := svc.NewServiceContext(c)
-	_ = svcCtx
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(c, os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	svcCtx, err := svc.NewServiceContext(c)
+	logx.Must(err)
+	// Deferred first so it runs last, once the server has drained
+	defer svcCtx.Close()
+
+	purgeJob := job.NewPurgeJob(svcCtx)
+	purgeJob.Start()
+	defer purgeJob.Stop()
+
+	outboxJob := job.NewOutboxJob(svcCtx)
+	outboxJob.Start()
+	defer outboxJob.Stop()
 
 	s := zrpc.MustNewServer(c.RpcServerConf, func(grpcServer *grpc.Server) {
+		userclient.RegisterUserServer(grpcServer, handler.NewUserHandler(svcCtx))
+		grpc_health_v1.RegisterHealthServer(grpcServer,
+			health.NewGRPCServer(svcCtx.Health, userclient.User_ServiceDesc.ServiceName))
 
 		if c.Mode == "dev" {
 			reflection.Register(grpcServer)
 		}
 	})
+	s.AddUnaryInterceptors(interceptor.UnaryServerInterceptors()...)
 	defer s.Stop()
 
+	// On SIGTERM report NOT_SERVING before the server stops; Start returns
+	// after in-flight calls finish
+	proc.AddWrapUpListener(svcCtx.Health.MarkShuttingDown)
+
 	fmt.Printf("Starting rpc server at %s...\n", c.ListenOn)
 	s.Start()
 }
+
+// runMigrate runs "migrate <command>" against the configured database
+func runMigrate(c config.Config, args []string) error {
+	db, err := database.NewPostgresConnection(c.Database.Postgres)
+	if err != nil {
+		return err
+	}
+	defer database.ClosePostgresConnection(db)
+
+	m, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	return database.RunMigrateCommand(context.Background(), m, args, os.Stdout)
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_search_vector;
+ALTER TABLE users DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS idx_users_email_trgm;
+DROP INDEX IF EXISTS idx_users_name_trgm;
@@ -0,0 +1,12 @@
+-- Trigram indexes serve typo-tolerant matching on partial names and emails
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING gin (email gin_trgm_ops);
+
+-- Full-text document over the name and the words of the email, so "alice"
+-- matches alice@example.com
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', name || ' ' || translate(email, '@._-', '    '))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING gin (search_vector);
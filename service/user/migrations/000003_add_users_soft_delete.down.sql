@@ -0,0 +1,14 @@
+-- Dropping deleted_at would make soft-deleted users active again, and their
+-- emails may clash with the restored unique constraint. Refuse to roll back
+-- until they are purged or restored
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE deleted_at IS NOT NULL) THEN
+        RAISE EXCEPTION 'users has soft-deleted rows; purge or restore them before rolling back';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_users_email_active;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
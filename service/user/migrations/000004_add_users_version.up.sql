@@ -0,0 +1,2 @@
+-- Incremented on every write and checked by updates for optimistic locking
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
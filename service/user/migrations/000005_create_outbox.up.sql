@@ -0,0 +1,19 @@
+-- Domain events written in the same transaction as the change they describe
+-- and published to RabbitMQ by the outbox relay
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL UNIQUE,
+    event_type VARCHAR(100) NOT NULL,
+    aggregate_type VARCHAR(100) NOT NULL,
+    aggregate_id VARCHAR(100) NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    data JSONB NOT NULL,
+    sent_at TIMESTAMPTZ,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+-- Serves the relay, which reads unsent events oldest first
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(id) WHERE sent_at IS NULL;
+-- Serves pruning of sent events
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_at ON outbox(sent_at) WHERE sent_at IS NOT NULL;
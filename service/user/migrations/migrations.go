@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+
+	"github.com/Nha1410/go-zero-template/common/database"
+)
+
+// Service names the user service's rows in schema_migrations
+const Service = "user"
+
+//go:embed *.sql
+var files embed.FS
+
+// NewMigrator returns a migrator for the user service schema
+func NewMigrator(db *sql.DB) (*database.Migrator, error) {
+	return database.NewMigrator(db, files, Service)
+}